@@ -6,6 +6,8 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	// the image ships without a timezone database, channels may use any zone
+	_ "time/tzdata"
 )
 
 func main() {
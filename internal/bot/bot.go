@@ -8,6 +8,7 @@ import (
 	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
 	"github.com/Enthreeka/tg-bot-quiz/internal/handler/view"
 	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	"github.com/Enthreeka/tg-bot-quiz/internal/scheduler"
 	service "github.com/Enthreeka/tg-bot-quiz/internal/usecase"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/excel"
 	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
@@ -21,6 +22,9 @@ import (
 
 const (
 	PostgresMaxAttempts = 5
+	UndoTTL             = 5 * time.Minute
+	SchedulerTick       = 10 * time.Second
+	SchedulerJobTimeout = 5 * time.Minute
 )
 
 type Bot struct {
@@ -32,14 +36,27 @@ type Bot struct {
 	excel         *excel.Excel
 	tgMsg         *customMsg.TelegramMsg
 	callbackStore *store.CallbackStorage
-
-	userService    service.UserService
-	channelService service.ChannelService
-	quizService    service.QuizService
-
-	userRepo    repo.UserRepo
-	channelRepo repo.ChannelRepo
-	quizRepo    repo.QuizRepo
+	undoStore     *store.UndoStorage
+	scheduler     *scheduler.Scheduler
+
+	userService      service.UserService
+	channelService   service.ChannelService
+	quizService      service.QuizService
+	publishService   service.PublishService
+	seasonService    service.SeasonService
+	seriesService    service.SeriesService
+	autopilotService service.AutopilotService
+	cronJobService   service.CronJobService
+	settingsService  service.ChannelSettingsService
+
+	userRepo      repo.UserRepo
+	channelRepo   repo.ChannelRepo
+	quizRepo      repo.QuizRepo
+	seasonRepo    repo.SeasonRepo
+	seriesRepo    repo.SeriesRepo
+	autopilotRepo repo.AutopilotRepo
+	cronJobRepo   repo.CronJobRepo
+	settingsRepo  repo.ChannelSettingsRepo
 
 	callbackQuiz callback.CallbackQuiz
 	callbackUser callback.CallbackUser
@@ -59,7 +76,7 @@ func (b *Bot) initHandler() {
 	}
 	b.callbackUser = callbackUser
 
-	callbackQuiz, err := callback.NewCallbackQuiz(b.quizService, b.channelService, b.log, b.store, b.tgMsg, b.excel)
+	callbackQuiz, err := callback.NewCallbackQuiz(b.quizService, b.channelService, b.publishService, b.seasonService, b.seriesService, b.autopilotService, b.cronJobService, b.settingsService, b.log, b.store, b.undoStore, b.tgMsg, b.excel)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -75,18 +92,54 @@ func (b *Bot) initUsecase() {
 	}
 	b.userService = userService
 
-	channelService, err := service.NewChannelService(b.channelRepo, b.log)
-	if err != nil {
-		b.log.Fatal("NewChannelService:", err)
-	}
-	b.channelService = channelService
-
 	quizService, err := service.NewQuizService(b.quizRepo, b.log)
 	if err != nil {
 		b.log.Fatal("NewQuizService:", err)
 	}
 	b.quizService = quizService
 
+	settingsService, err := service.NewChannelSettingsService(b.settingsRepo, b.log)
+	if err != nil {
+		b.log.Fatal("NewChannelSettingsService:", err)
+	}
+	b.settingsService = settingsService
+
+	publishService, err := service.NewPublishService(b.quizRepo, b.channelRepo, b.settingsRepo, b.tgMsg, b.log)
+	if err != nil {
+		b.log.Fatal("NewPublishService:", err)
+	}
+	b.publishService = publishService
+
+	seasonService, err := service.NewSeasonService(b.seasonRepo, b.log)
+	if err != nil {
+		b.log.Fatal("NewSeasonService:", err)
+	}
+	b.seasonService = seasonService
+
+	seriesService, err := service.NewSeriesService(b.seriesRepo, b.publishService, b.log)
+	if err != nil {
+		b.log.Fatal("NewSeriesService:", err)
+	}
+	b.seriesService = seriesService
+
+	autopilotService, err := service.NewAutopilotService(b.autopilotRepo, b.channelRepo, b.userRepo, b.publishService, b.tgMsg, b.log)
+	if err != nil {
+		b.log.Fatal("NewAutopilotService:", err)
+	}
+	b.autopilotService = autopilotService
+
+	cronJobService, err := service.NewCronJobService(b.cronJobRepo, b.channelRepo, b.userRepo, b.quizRepo, b.publishService, b.excel, b.tgMsg, b.log)
+	if err != nil {
+		b.log.Fatal("NewCronJobService:", err)
+	}
+	b.cronJobService = cronJobService
+
+	channelService, err := service.NewChannelService(b.channelRepo, b.autopilotService, b.cronJobService, b.log)
+	if err != nil {
+		b.log.Fatal("NewChannelService:", err)
+	}
+	b.channelService = channelService
+
 	b.log.Info("Initializing usecase")
 }
 
@@ -109,6 +162,36 @@ func (b *Bot) initRepo() {
 	}
 	b.quizRepo = quizRepo
 
+	seasonRepo, err := repo.NewSeasonRepo(b.psql)
+	if err != nil {
+		b.log.Fatal("NewSeasonRepo: ", err)
+	}
+	b.seasonRepo = seasonRepo
+
+	seriesRepo, err := repo.NewSeriesRepo(b.psql)
+	if err != nil {
+		b.log.Fatal("NewSeriesRepo: ", err)
+	}
+	b.seriesRepo = seriesRepo
+
+	autopilotRepo, err := repo.NewAutopilotRepo(b.psql)
+	if err != nil {
+		b.log.Fatal("NewAutopilotRepo: ", err)
+	}
+	b.autopilotRepo = autopilotRepo
+
+	cronJobRepo, err := repo.NewCronJobRepo(b.psql)
+	if err != nil {
+		b.log.Fatal("NewCronJobRepo: ", err)
+	}
+	b.cronJobRepo = cronJobRepo
+
+	settingsRepo, err := repo.NewChannelSettingsRepo(b.psql)
+	if err != nil {
+		b.log.Fatal("NewChannelSettingsRepo: ", err)
+	}
+	b.settingsRepo = settingsRepo
+
 	b.log.Info("Initializing repo")
 }
 
@@ -150,6 +233,12 @@ func (b *Bot) initStore() {
 	b.log.Info("Initializing store")
 }
 
+func (b *Bot) initUndoStorage() {
+	b.undoStore = store.NewUndoStorage(UndoTTL)
+
+	b.log.Info("Initializing undo storage")
+}
+
 func (b *Bot) initCallbackStorage() {
 	b.callbackStore = store.NewCallbackStorage()
 
@@ -168,6 +257,16 @@ func (b *Bot) initTelegramBot() {
 	b.log.Info("Authorized on account %s", bot.Self.UserName)
 }
 
+func (b *Bot) initScheduler() {
+	b.scheduler = scheduler.New(SchedulerTick, SchedulerJobTimeout, b.log)
+	b.scheduler.Add("series", b.seriesService.RunDue)
+	b.scheduler.Add("autopilot", b.autopilotService.RunDue)
+	b.scheduler.Add("cron_jobs", b.cronJobService.RunDue)
+	b.scheduler.Add("deadlines", b.publishService.CloseOverdue)
+
+	b.log.Info("Initializing scheduler")
+}
+
 func (b *Bot) initExcel() {
 	b.excel = excel.NewExcel(b.log)
 }
@@ -179,17 +278,19 @@ func (b *Bot) initialize(ctx context.Context) {
 	b.initTelegramBot()
 	b.initStore()
 	b.initCallbackStorage()
+	b.initUndoStorage()
 	b.initPostgres(ctx)
 	b.initMessage()
 	b.initRepo()
 	b.initUsecase()
 	b.initHandler()
+	b.initScheduler()
 }
 
 func (b *Bot) Run(ctx context.Context) {
 	startBot := time.Now()
 	b.initialize(ctx)
-	newBot, err := tgbot.NewBot(b.bot, b.log, b.store, b.tgMsg, b.userService, b.quizService, b.callbackStore, b.channelService)
+	newBot, err := tgbot.NewBot(b.bot, b.log, b.store, b.tgMsg, b.userService, b.quizService, b.callbackStore, b.channelService, b.publishService, b.autopilotService, b.cronJobService, b.settingsService)
 	if err != nil {
 		b.log.Fatal("failed go create new bot: ", err)
 	}
@@ -215,18 +316,95 @@ func (b *Bot) Run(ctx context.Context) {
 	newBot.RegisterCommandCallback("quiz_answer", b.callbackQuiz.CallbackUserResponse()) // без middleware
 	//todo по хорошему вынести в другую область предметную
 	newBot.RegisterCommandCallback("add_image", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackAddImage()))
+	newBot.RegisterCommandCallback("delete_image", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackDeleteImage()))
 	newBot.RegisterCommandCallback("update_question", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackUpdateQuestion()))
 	newBot.RegisterCommandCallback("cancel_update", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCancelUpdate()))
 	newBot.RegisterCommandCallback("update_answers", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackUpdateAnswers()))
 	newBot.RegisterCommandCallback("downloading_rating", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackGetUserResultExcelFile()))
+	newBot.RegisterCommandCallback("rating", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackDownloadRating()))
+	newBot.RegisterCommandCallback("rfilter", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackRatingFilter()))
+	newBot.RegisterCommandCallback("rseason", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeasonExport()))
+	newBot.RegisterCommandCallback("season_compare", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeasonCompare()))
+	newBot.RegisterCommandCallback("rtoggle", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackRatingToggleQuestion()))
 	newBot.RegisterCommandCallback("reset_rating", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackResetRating()))
+	newBot.RegisterCommandCallback("undo_action", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackUndo()))
+	newBot.RegisterCommandCallback("trash_list", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackTrashList()))
+	newBot.RegisterCommandCallback("trash_get", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackTrashQuestion()))
+	newBot.RegisterCommandCallback("trash_restore", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackTrashRestore()))
+	newBot.RegisterCommandCallback("trash_points", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackTrashPoints()))
+	newBot.RegisterCommandCallback("trash_purge", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackTrashPurge()))
+	newBot.RegisterCommandCallback("qhistory", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionHistory()))
+	newBot.RegisterCommandCallback("qversion", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionVersion()))
+	newBot.RegisterCommandCallback("qrollback", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionRollback()))
+	newBot.RegisterCommandCallback("qmeta", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionMeta()))
+	newBot.RegisterCommandCallback("qdiff", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionDifficulty()))
+	newBot.RegisterCommandCallback("qlayout", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionLayout()))
+	newBot.RegisterCommandCallback("qrow", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionButtonsPerRow()))
+	newBot.RegisterCommandCallback("qshuffle", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionShuffle()))
+	newBot.RegisterCommandCallback("qtags", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackQuestionTags()))
 	newBot.RegisterCommandCallback("send_question", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSendQuizToChannel()))
+	newBot.RegisterCommandCallback("send_anyway", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSendQuizAnyway()))
+	newBot.RegisterCommandCallback("sync_post", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSyncPost()))
+	newBot.RegisterCommandCallback("pubch", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackPublishChannel()))
+	newBot.RegisterCommandCallback("pubmode", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackPublishScoring()))
+	newBot.RegisterCommandCallback("pubsend", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackPublishSend()))
+	newBot.RegisterCommandCallback("qclone", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCloneQuestion()))
+	newBot.RegisterCommandCallback("clone_select", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCloneSelect()))
+	newBot.RegisterCommandCallback("clone_toggle", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCloneToggle()))
+	newBot.RegisterCommandCallback("clone_target", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCloneTarget()))
+	newBot.RegisterCommandCallback("clone_into", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCloneInto()))
+	newBot.RegisterCommandCallback("series_list", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesList()))
+	newBot.RegisterCommandCallback("series_new", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesNew()))
+	newBot.RegisterCommandCallback("series_toggle", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesToggle()))
+	newBot.RegisterCommandCallback("series_interval", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesInterval()))
+	newBot.RegisterCommandCallback("series_window", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesWindow()))
+	newBot.RegisterCommandCallback("series_get", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesGet()))
+	newBot.RegisterCommandCallback("series_start", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesStart()))
+	newBot.RegisterCommandCallback("series_pause", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesPause()))
+	newBot.RegisterCommandCallback("series_resume", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesResume()))
+	newBot.RegisterCommandCallback("series_skip", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesSkip()))
+	newBot.RegisterCommandCallback("series_reveal", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesReveal()))
+	newBot.RegisterCommandCallback("series_delete", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesDelete()))
+	newBot.RegisterCommandCallback("series_rating", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackSeriesRating()))
+	newBot.RegisterCommandCallback("autopilot", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackAutopilot()))
+	newBot.RegisterCommandCallback("apl_toggle", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackAutopilotToggle()))
+	newBot.RegisterCommandCallback("apl_schedule", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackAutopilotSchedule()))
+	newBot.RegisterCommandCallback("apl_tag", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackAutopilotTag()))
+	newBot.RegisterCommandCallback("apl_diff", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackAutopilotDifficulty()))
+	newBot.RegisterCommandCallback("apl_low", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackAutopilotThreshold()))
+	newBot.RegisterCommandCallback("jobs_list", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCronJobList()))
+	newBot.RegisterCommandCallback("jobs_new", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCronJobNew()))
+	newBot.RegisterCommandCallback("jobkind", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCronJobKind()))
+	newBot.RegisterCommandCallback("job_get", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCronJobGet()))
+	newBot.RegisterCommandCallback("job_pause", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCronJobPause()))
+	newBot.RegisterCommandCallback("job_schedule", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCronJobSchedule()))
+	newBot.RegisterCommandCallback("job_delete", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackCronJobDelete()))
+
+	newBot.RegisterCommandCallback("chset", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelSettings()))
+	newBot.RegisterCommandCallback("cset_row", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelButtonsPerRow()))
+	newBot.RegisterCommandCallback("cset_shuffle", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelShuffle()))
+	newBot.RegisterCommandCallback("cset_points", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelPoints()))
+	newBot.RegisterCommandCallback("cset_member", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelMembership()))
+	newBot.RegisterCommandCallback("cset_change", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelAnswerChange()))
+	newBot.RegisterCommandCallback("cset_deadline", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelDeadline()))
+	newBot.RegisterCommandCallback("cset_feedback", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelFeedback()))
+	newBot.RegisterCommandCallback("cset_sign", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelSignature()))
+	newBot.RegisterCommandCallback("cset_tz", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackChannelTimezone()))
+
+	newBot.RegisterForwardView(middleware.AdminForwardMiddleware(b.userService, b.callbackQuiz.ForwardCreateQuestion()))
+	newBot.RegisterCommandCallback("channel_forward", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackForwardChannel()))
+	newBot.RegisterCommandCallback("import_question", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackImportQuestion()))
+	newBot.RegisterCommandCallback("import_confirm", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackImportConfirm()))
+	newBot.RegisterCommandCallback("export_question", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackExportQuestion()))
+	newBot.RegisterCommandCallback("bank_xlsx", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackExportQuestionXLSX()))
+	newBot.RegisterCommandCallback("bank_json", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackExportQuestionJSON()))
 
 	//v2
 	newBot.RegisterCommandCallback("list_channelsv2", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackGetChannelsV2()))
 	newBot.RegisterCommandCallback("channel_get", middleware.AdminMiddleware(b.userService, b.callbackQuiz.CallbackGetChannelSettingV2()))
 
 	b.log.Info("Initialize bot took [%f] seconds", time.Since(startBot).Seconds())
+	go b.scheduler.Run(ctx)
 	if err := newBot.Run(ctx); err != nil {
 		b.log.Fatal("failed to run Telegram Bot: %v", err)
 	}
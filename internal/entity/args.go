@@ -5,6 +5,7 @@ type Args struct {
 }
 
 type AnswerArgs struct {
-	Answer string `json:"ответ"`
-	Cost   int    `json:"цена_ответа"`
+	Answer  string `json:"ответ"`
+	Cost    int    `json:"цена_ответа"`
+	Correct *bool  `json:"верный,omitempty"`
 }
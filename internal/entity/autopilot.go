@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAutopilotSchedule = "0 12 * * 1-5"
+	DefaultLowBankThreshold  = 5
+	MaxQuestionDifficulty    = 5
+	maxQuestionTagLength     = 64
+)
+
+// Autopilot posts an unsent question of the channel on every slot of its cron
+// schedule. Tag and Difficulty narrow the pick, without them any unsent
+// question is taken at random.
+type Autopilot struct {
+	ChannelTgID      int64      `json:"channel_tg_id"`
+	Enabled          bool       `json:"enabled"`
+	Schedule         string     `json:"schedule"`
+	Tag              *string    `json:"tag"`
+	Difficulty       *int       `json:"difficulty"`
+	LowBankThreshold int        `json:"low_bank_threshold"`
+	NextRunAt        *time.Time `json:"next_run_at"`
+	LastRunAt        *time.Time `json:"last_run_at"`
+	LastError        *string    `json:"last_error"`
+	LowBankWarnedAt  *time.Time `json:"low_bank_warned_at"`
+	UpdatedBy        int64      `json:"updated_by"`
+
+	// Timezone is the timezone of the channel, it is read with the settings
+	Timezone string `json:"timezone"`
+}
+
+func NewAutopilot(channelTgID int64, timezone string) *Autopilot {
+	return &Autopilot{
+		ChannelTgID:      channelTgID,
+		Timezone:         timezone,
+		Schedule:         DefaultAutopilotSchedule,
+		LowBankThreshold: DefaultLowBankThreshold,
+	}
+}
+
+// FilterTitle describes which questions the autopilot picks from.
+func (a *Autopilot) FilterTitle() string {
+	var parts []string
+	if a.Tag != nil {
+		parts = append(parts, "тег "+*a.Tag)
+	}
+	if a.Difficulty != nil {
+		parts = append(parts, fmt.Sprintf("сложность %d", *a.Difficulty))
+	}
+	if len(parts) == 0 {
+		return "любой неотправленный вопрос"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NextDifficulty cycles the filter: any, 1, 2, ... MaxQuestionDifficulty, any.
+func NextDifficulty(difficulty *int) *int {
+	if difficulty == nil {
+		next := 1
+		return &next
+	}
+	if *difficulty >= MaxQuestionDifficulty {
+		return nil
+	}
+	next := *difficulty + 1
+	return &next
+}
+
+// ParseTags reads tags separated by commas, e.g. "#history, Science". Tags
+// are lowercased and repeated ones are dropped, "-" clears the tags.
+func ParseTags(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "-" {
+		return []string{}
+	}
+
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(text, ",") {
+		tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(part), "#"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if runes := []rune(tag); len(runes) > maxQuestionTagLength {
+			tag = string(runes[:maxQuestionTagLength])
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// TagsTitle joins the tags for the admin, "нет" when there are none.
+func TagsTitle(tags []string) string {
+	if len(tags) == 0 {
+		return "нет"
+	}
+	return strings.Join(tags, ", ")
+}
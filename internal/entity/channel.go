@@ -2,6 +2,8 @@ package entity
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 type ChannelStatus string
@@ -35,6 +37,7 @@ type Channel struct {
 	ChannelName   string        `json:"channel_name"`
 	ChannelUrl    *string       `json:"channel_url"`
 	ChannelStatus ChannelStatus `json:"channel_status"`
+	Timezone      string        `json:"timezone"`
 }
 
 func (c Channel) String() string {
@@ -48,3 +51,31 @@ func (c Channel) String() string {
 	return fmt.Sprintf("(tg_id: %d | channel_name: %s | ChannelURL: %s | Status: %s)",
 		c.TgID, c.ChannelName, url, c.ChannelStatus)
 }
+
+// ParseLocation loads an IANA timezone typed by an admin. Unlike
+// time.LoadLocation it refuses an empty name and Local, which mean the
+// timezone of the server.
+func ParseLocation(timezone string) (*time.Location, error) {
+	timezone = strings.TrimSpace(timezone)
+	if timezone == "" || timezone == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", timezone)
+	}
+	return time.LoadLocation(timezone)
+}
+
+// Location is the timezone the channel shows and reads times in.
+func (c Channel) Location() *time.Location {
+	return LoadLocation(c.Timezone)
+}
+
+// LoadLocation loads a timezone of a channel, an unknown one falls back to
+// the default timezone.
+func LoadLocation(timezone string) *time.Location {
+	if location, err := ParseLocation(timezone); err == nil {
+		return location
+	}
+	if location, err := time.LoadLocation(DefaultChannelTimezone); err == nil {
+		return location
+	}
+	return time.UTC
+}
@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	MaxButtonsPerRow = 4
+	// ButtonsPerRowAuto chooses the buttons per row by the length of the answers
+	ButtonsPerRowAuto = -1
+	// FeedbackPoints is replaced by the points in the alert after an answer
+	FeedbackPoints = "{points}"
+
+	defaultFeedbackText     = "За выбранный ответ вы получили баллов: " + FeedbackPoints
+	defaultFeedbackNoPoints = "Ваш ответ принят"
+)
+
+// AnswerChangeWindows are the values the admin cycles the answer change
+// window through, 0 forbids changing the answer.
+var AnswerChangeWindows = []time.Duration{0, time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+
+// DefaultDeadlines are the values the admin cycles the default deadline
+// through, 0 leaves questions without a deadline.
+var DefaultDeadlines = []time.Duration{0, time.Hour, 6 * time.Hour, 24 * time.Hour, 72 * time.Hour, 7 * 24 * time.Hour}
+
+// ChannelSettings is how the channel publishes questions and takes answers.
+type ChannelSettings struct {
+	ChannelTgID       int64         `json:"channel_tg_id"`
+	ButtonsPerRow     int           `json:"buttons_per_row"`
+	ShuffleAnswers    bool          `json:"shuffle_answers"`
+	FeedbackText      *string       `json:"feedback_text"`
+	ShowPoints        bool          `json:"show_points"`
+	RequireMembership bool          `json:"require_membership"`
+	AnswerChange      time.Duration `json:"answer_change"`
+	DefaultDeadline   time.Duration `json:"default_deadline"`
+	Signature         *string       `json:"signature"`
+	UpdatedBy         int64         `json:"updated_by"`
+}
+
+func NewChannelSettings(channelTgID int64) *ChannelSettings {
+	return &ChannelSettings{
+		ChannelTgID:   channelTgID,
+		ButtonsPerRow: 1,
+		ShowPoints:    true,
+	}
+}
+
+// Feedback is the alert the user sees after an answer. The points are left
+// out when the channel hides them.
+func (s *ChannelSettings) Feedback(points int) string {
+	text := defaultFeedbackText
+	switch {
+	case s.FeedbackText != nil:
+		text = *s.FeedbackText
+	case !s.ShowPoints:
+		text = defaultFeedbackNoPoints
+	}
+
+	value := ""
+	if s.ShowPoints {
+		value = fmt.Sprint(points)
+	}
+	return strings.Join(strings.Fields(strings.ReplaceAll(text, FeedbackPoints, value)), " ")
+}
+
+// NewLayout is the layout of a new post of the question, the question may
+// override the buttons per row and the shuffling of the channel. The order of
+// the answers is fixed when the post is published.
+func (s *ChannelSettings) NewLayout(question Question, answers []Answer) *PostLayout {
+	layout := &PostLayout{ButtonsPerRow: s.ButtonsPerRow, Signature: s.Signature}
+	if question.ButtonsPerRow != nil {
+		layout.ButtonsPerRow = *question.ButtonsPerRow
+	}
+
+	shuffle := s.ShuffleAnswers
+	if question.ShuffleAnswers != nil {
+		shuffle = *question.ShuffleAnswers
+	}
+	if shuffle {
+		for _, key := range rand.Perm(len(answers)) {
+			layout.AnswerOrder = append(layout.AnswerOrder, answers[key].ID)
+		}
+	}
+	return layout
+}
+
+// NextButtonsPerRow cycles the buttons per row: 1, 2, ... MaxButtonsPerRow,
+// auto, 1.
+func NextButtonsPerRow(current int) int {
+	switch {
+	case current == ButtonsPerRowAuto:
+		return 1
+	case current >= MaxButtonsPerRow:
+		return ButtonsPerRowAuto
+	case current < 1:
+		return 1
+	}
+	return current + 1
+}
+
+// ButtonsPerRowTitle shows the buttons per row for the admin.
+func ButtonsPerRowTitle(buttonsPerRow int) string {
+	if buttonsPerRow == ButtonsPerRowAuto {
+		return "авто"
+	}
+	return fmt.Sprint(buttonsPerRow)
+}
+
+// NextDuration returns the value after current in values, the first one after
+// the last.
+func NextDuration(values []time.Duration, current time.Duration) time.Duration {
+	for key, value := range values {
+		if value == current && key+1 < len(values) {
+			return values[key+1]
+		}
+	}
+	return values[0]
+}
+
+// DurationTitle shows a duration for the admin, off when it is 0.
+func DurationTitle(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "нет"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%d дн.", int(d/(24*time.Hour)))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d ч.", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%d мин.", int(d/time.Minute))
+	}
+}
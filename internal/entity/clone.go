@@ -0,0 +1,26 @@
+package entity
+
+// CloneRequest is the questions an admin is copying to another channel.
+type CloneRequest struct {
+	SourceChannelID int64 `json:"source_channel_id"`
+	QuestionIDs     []int `json:"question_ids"`
+}
+
+func (r *CloneRequest) ToggleQuestion(questionID int) {
+	for key, id := range r.QuestionIDs {
+		if id == questionID {
+			r.QuestionIDs = append(r.QuestionIDs[:key], r.QuestionIDs[key+1:]...)
+			return
+		}
+	}
+	r.QuestionIDs = append(r.QuestionIDs, questionID)
+}
+
+func (r *CloneRequest) HasQuestion(questionID int) bool {
+	for _, id := range r.QuestionIDs {
+		if id == questionID {
+			return true
+		}
+	}
+	return false
+}
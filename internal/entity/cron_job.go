@@ -0,0 +1,56 @@
+package entity
+
+import "time"
+
+const DefaultChannelTimezone = "Europe/Moscow"
+
+type CronJobKind string
+
+const (
+	CronJobPostQuestion   CronJobKind = "post_question"
+	CronJobCloseQuestions CronJobKind = "close_questions"
+	CronJobLeaderboard    CronJobKind = "leaderboard"
+	CronJobWeeklyReport   CronJobKind = "weekly_report"
+)
+
+// CronJobKinds is the order the kinds are offered to the admin in.
+var CronJobKinds = []CronJobKind{CronJobPostQuestion, CronJobCloseQuestions, CronJobLeaderboard, CronJobWeeklyReport}
+
+func (k CronJobKind) Title() string {
+	switch k {
+	case CronJobPostQuestion:
+		return "Публикация следующего вопроса"
+	case CronJobCloseQuestions:
+		return "Закрытие открытых вопросов"
+	case CronJobLeaderboard:
+		return "Публикация рейтинга"
+	case CronJobWeeklyReport:
+		return "Отчёт за неделю администраторам"
+	}
+	return string(k)
+}
+
+// CronJob is a recurring job of a channel. The schedule is read in the
+// timezone of the channel.
+type CronJob struct {
+	ID          int         `json:"id"`
+	ChannelTgID int64       `json:"channel_tg_id"`
+	Kind        CronJobKind `json:"kind"`
+	Schedule    string      `json:"schedule"`
+	Paused      bool        `json:"paused"`
+	NextRunAt   *time.Time  `json:"next_run_at"`
+	LastRunAt   *time.Time  `json:"last_run_at"`
+	LastResult  *string     `json:"last_result"`
+	LastFailed  bool        `json:"last_failed"`
+	CreatedBy   int64       `json:"created_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+
+	// Timezone is the timezone of the channel, it is read with the job
+	Timezone string `json:"timezone"`
+}
+
+// CronJobRun is an upcoming run of a job.
+type CronJobRun struct {
+	Job CronJob   `json:"job"`
+	At  time.Time `json:"at"`
+}
@@ -0,0 +1,113 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type ExportDateField string
+
+const (
+	ExportByAnsweredAt  ExportDateField = "answered_at"
+	ExportByPublishedAt ExportDateField = "published_at"
+)
+
+const exportDateLayout = "02.01.2006"
+
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// ExportFilter narrows the results of an export. The zero value exports the
+// whole history of the channel. To is exclusive.
+type ExportFilter struct {
+	CurrentSeason bool            `json:"current_season"`
+	Season        int             `json:"season"`
+	DateField     ExportDateField `json:"date_field"`
+	From          *time.Time      `json:"from"`
+	To            *time.Time      `json:"to"`
+	QuestionIDs   []int           `json:"question_ids"`
+	// Timezone of the channel, the period is typed and shown in it
+	Timezone string `json:"timezone"`
+}
+
+func (f *ExportFilter) location() *time.Location {
+	return LoadLocation(f.Timezone)
+}
+
+// SetPeriod parses "01.09.2024-30.09.2024" or a single day "01.09.2024".
+// Both days are included.
+func (f *ExportFilter) SetPeriod(text string) error {
+	parts := strings.Split(strings.TrimSpace(text), "-")
+	if len(parts) > 2 {
+		return ErrInvalidPeriod
+	}
+
+	from, err := time.ParseInLocation(exportDateLayout, strings.TrimSpace(parts[0]), f.location())
+	if err != nil {
+		return ErrInvalidPeriod
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = time.ParseInLocation(exportDateLayout, strings.TrimSpace(parts[1]), f.location()); err != nil {
+			return ErrInvalidPeriod
+		}
+	}
+	if to.Before(from) {
+		return ErrInvalidPeriod
+	}
+	to = to.AddDate(0, 0, 1)
+
+	f.From, f.To = &from, &to
+	return nil
+}
+
+func (f *ExportFilter) ToggleQuestion(questionID int) {
+	for key, id := range f.QuestionIDs {
+		if id == questionID {
+			f.QuestionIDs = append(f.QuestionIDs[:key], f.QuestionIDs[key+1:]...)
+			return
+		}
+	}
+	f.QuestionIDs = append(f.QuestionIDs, questionID)
+}
+
+func (f *ExportFilter) HasQuestion(questionID int) bool {
+	for _, id := range f.QuestionIDs {
+		if id == questionID {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ExportFilter) String() string {
+	if f == nil {
+		return "вся история"
+	}
+
+	var parts []string
+	if f.CurrentSeason {
+		parts = append(parts, "текущий сезон")
+	}
+	if f.Season > 0 {
+		parts = append(parts, fmt.Sprintf("сезон %d", f.Season))
+	}
+	if f.From != nil && f.To != nil {
+		field := "по дате ответа"
+		if f.DateField == ExportByPublishedAt {
+			field = "по дате публикации"
+		}
+		parts = append(parts, fmt.Sprintf("%s с %s по %s", field,
+			f.From.In(f.location()).Format(exportDateLayout),
+			f.To.In(f.location()).AddDate(0, 0, -1).Format(exportDateLayout)))
+	}
+	if len(f.QuestionIDs) > 0 {
+		parts = append(parts, fmt.Sprintf("выбрано вопросов: %d", len(f.QuestionIDs)))
+	}
+
+	if len(parts) == 0 {
+		return "вся история"
+	}
+	return strings.Join(parts, ", ")
+}
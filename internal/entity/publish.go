@@ -0,0 +1,217 @@
+package entity
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type CheckLevel string
+
+const (
+	CheckOK      CheckLevel = "ok"
+	CheckWarning CheckLevel = "warning"
+	CheckError   CheckLevel = "error"
+)
+
+type PreflightCheck struct {
+	Name   string     `json:"name"`
+	Level  CheckLevel `json:"level"`
+	Detail string     `json:"detail"`
+}
+
+type Preflight struct {
+	QuestionID   int              `json:"question_id"`
+	ChannelTgIDs []int64          `json:"channel_tg_ids"`
+	Checks       []PreflightCheck `json:"checks"`
+}
+
+func (p *Preflight) Add(name string, level CheckLevel, detail string) {
+	p.Checks = append(p.Checks, PreflightCheck{Name: name, Level: level, Detail: detail})
+}
+
+func (p *Preflight) HasErrors() bool {
+	return p.has(CheckError)
+}
+
+func (p *Preflight) HasWarnings() bool {
+	return p.has(CheckWarning)
+}
+
+// PreflightError is returned instead of posting when a publication that no
+// admin confirms fails the checks.
+type PreflightError struct {
+	Preflight *Preflight
+}
+
+func (e *PreflightError) Error() string {
+	var failed []string
+	for _, check := range e.Preflight.Checks {
+		if check.Level != CheckError {
+			continue
+		}
+		if check.Detail != "" {
+			failed = append(failed, check.Name+": "+check.Detail)
+		} else {
+			failed = append(failed, check.Name)
+		}
+	}
+	return "проверка перед публикацией не пройдена: " + strings.Join(failed, "; ")
+}
+
+func (p *Preflight) has(level CheckLevel) bool {
+	for _, check := range p.Checks {
+		if check.Level == level {
+			return true
+		}
+	}
+	return false
+}
+
+// QuestionPost is a message with the question published to a chat.
+type QuestionPost struct {
+	ID            int        `json:"id"`
+	QuestionID    int        `json:"question_id"`
+	ChatID        int64      `json:"chat_id"`
+	MessageID     int        `json:"message_id"`
+	FileID        *string    `json:"file_id"`
+	PublicationID *int       `json:"publication_id"`
+	PublishedAt   time.Time  `json:"published_at"`
+	Layout        PostLayout `json:"layout"`
+}
+
+// PostLayout is how the answers of a post are laid out. It is fixed when the
+// post is published, so editing the post doesn't move the buttons.
+type PostLayout struct {
+	// ButtonsPerRow is 1 to MaxButtonsPerRow or ButtonsPerRowAuto
+	ButtonsPerRow int `json:"buttons_per_row"`
+	// AnswerOrder is the order of the answer ids, empty keeps the order of the question
+	AnswerOrder []int   `json:"answer_order"`
+	Signature   *string `json:"signature"`
+}
+
+// autoRowWidth is about how many characters fit in a row of buttons on a phone
+const autoRowWidth = 32
+
+// PerRow is how many answers are put in a row of buttons. The automatic layout
+// puts as many as fit by the longest answer and evens out the rows.
+func (l *PostLayout) PerRow(answers []Answer) int {
+	switch {
+	case l == nil || (l.ButtonsPerRow < 1 && l.ButtonsPerRow != ButtonsPerRowAuto):
+		return 1
+	case l.ButtonsPerRow != ButtonsPerRowAuto:
+		return l.ButtonsPerRow
+	case len(answers) == 0:
+		return 1
+	}
+
+	longest := 1
+	for _, answer := range answers {
+		longest = max(longest, utf8.RuneCountInString(answer.Answer))
+	}
+	perRow := min(max(autoRowWidth/longest, 1), MaxButtonsPerRow, len(answers))
+
+	rows := (len(answers) + perRow - 1) / perRow
+	return (len(answers) + rows - 1) / rows
+}
+
+// Arrange puts the answers in the order of the layout. Answers added after the
+// post was published go last.
+func (l *PostLayout) Arrange(answers []Answer) []Answer {
+	if l == nil || len(l.AnswerOrder) == 0 {
+		return answers
+	}
+
+	position := make(map[int]int, len(l.AnswerOrder))
+	for key, id := range l.AnswerOrder {
+		position[id] = key
+	}
+
+	arranged := make([]Answer, 0, len(answers))
+	var added []Answer
+	for _, id := range l.AnswerOrder {
+		for _, answer := range answers {
+			if answer.ID == id {
+				arranged = append(arranged, answer)
+			}
+		}
+	}
+	for _, answer := range answers {
+		if _, ok := position[answer.ID]; !ok {
+			added = append(added, answer)
+		}
+	}
+
+	return append(arranged, added...)
+}
+
+// PostSync is the result of updating the published posts of a question.
+type PostSync struct {
+	Total   int
+	Updated int
+	Errors  []string
+}
+
+type PublicationScoring string
+
+const (
+	ScoringSeparate PublicationScoring = "separate"
+	ScoringPooled   PublicationScoring = "pooled"
+)
+
+func (s PublicationScoring) Title() string {
+	switch s {
+	case ScoringSeparate:
+		return "отдельно в каждом канале"
+	case ScoringPooled:
+		return "общие для всех каналов"
+	}
+	return string(s)
+}
+
+// Publication is one send of a question to one or more channels. With
+// ScoringSeparate every channel rates the answers given in it, with
+// ScoringPooled a user answers once and the answer counts in the channel of
+// the question.
+type Publication struct {
+	ID         int                `json:"id"`
+	QuestionID int                `json:"question_id"`
+	Scoring    PublicationScoring `json:"scoring"`
+	ChannelIDs []int64            `json:"channel_ids"`
+	CreatedBy  int64              `json:"created_by"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+func (p *Publication) HasChannel(channelID int64) bool {
+	for _, id := range p.ChannelIDs {
+		if id == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Publication) ToggleChannel(channelID int64) {
+	for key, id := range p.ChannelIDs {
+		if id == channelID {
+			p.ChannelIDs = append(p.ChannelIDs[:key], p.ChannelIDs[key+1:]...)
+			return
+		}
+	}
+	p.ChannelIDs = append(p.ChannelIDs, channelID)
+}
+
+func (p *Publication) ToggleScoring() {
+	if p.Scoring == ScoringPooled {
+		p.Scoring = ScoringSeparate
+		return
+	}
+	p.Scoring = ScoringPooled
+}
+
+// PublishResult lists the channels the question was posted to and the errors
+// of the channels it wasn't.
+type PublishResult struct {
+	Published []string
+	Errors    []string
+}
@@ -1,35 +1,140 @@
 package entity
 
-import "time"
+import (
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
+	"html"
+	"time"
+)
+
+type MediaType string
+
+const (
+	MediaPhoto     MediaType = "photo"
+	MediaVideo     MediaType = "video"
+	MediaAnimation MediaType = "animation"
+	MediaDocument  MediaType = "document"
+	MediaAudio     MediaType = "audio"
+	MediaVoice     MediaType = "voice"
+)
+
+type TextFormat string
+
+const (
+	// TextFormatEntities - the original text is stored together with its entities
+	TextFormatEntities TextFormat = "entities"
+	// TextFormatMarkdownV2 - legacy questions stored as escaped MarkdownV2
+	TextFormatMarkdownV2 TextFormat = "markdown_v2"
+)
 
 type Question struct {
-	ID            int        `json:"id"`
-	CreatedByUser int64      `json:"created_by_user"`
-	CreatedAt     time.Time  `json:"created_at"`
-	QuestionName  string     `json:"question_name"`
-	Deadline      *time.Time `json:"deadline"`
-	FileID        *string    `json:"file_id"`
-	IsSend        bool       `json:"is_send"`
-	ChannelID     int64      `json:"channel_tg_id"`
+	ID               int                      `json:"id"`
+	CreatedByUser    int64                    `json:"created_by_user"`
+	CreatedAt        time.Time                `json:"created_at"`
+	QuestionName     string                   `json:"question_name"`
+	QuestionEntities []coverter.MessageEntity `json:"question_entities"`
+	TextFormat       TextFormat               `json:"text_format"`
+	Deadline         *time.Time               `json:"deadline"`
+	FileID           *string                  `json:"file_id"`
+	MediaType        MediaType                `json:"media_type"`
+	IsSend           bool                     `json:"is_send"`
+	ChannelID        int64                    `json:"channel_tg_id"`
+	// DeletedAt is set while the question is in the trash. CountPoints tells
+	// whether points earned on it still count in the rating meanwhile.
+	DeletedAt   *time.Time `json:"deleted_at"`
+	CountPoints bool       `json:"count_points"`
+	// ClosedAt is set when the question stops taking answers
+	ClosedAt *time.Time `json:"closed_at"`
+	// Tags and Difficulty (1-5) are set by admins to sort the bank
+	Tags       []string `json:"tags"`
+	Difficulty *int     `json:"difficulty"`
+	// ButtonsPerRow and ShuffleAnswers override the settings of the channel,
+	// nil follows them
+	ButtonsPerRow  *int  `json:"buttons_per_row"`
+	ShuffleAnswers *bool `json:"shuffle_answers"`
+}
+
+func (q Question) PlainText() string {
+	if q.TextFormat == TextFormatMarkdownV2 {
+		return coverter.UnescapeMarkdownV2(q.QuestionName)
+	}
+	return coverter.ConvertToPlain(q.QuestionName, q.QuestionEntities)
+}
+
+func (q Question) HTML() string {
+	if q.TextFormat == TextFormatMarkdownV2 {
+		return html.EscapeString(coverter.UnescapeMarkdownV2(q.QuestionName))
+	}
+	return coverter.ConvertToHTML(q.QuestionName, q.QuestionEntities)
+}
+
+func (q Question) MarkdownV2() string {
+	if q.TextFormat == TextFormatMarkdownV2 {
+		return q.QuestionName
+	}
+	return coverter.ConvertToMarkdownV2(q.QuestionName, q.QuestionEntities)
 }
 
 type Answer struct {
 	ID             int    `json:"id"`
 	Answer         string `json:"answer"`
 	CostOfResponse int    `json:"cost_of_response"`
+	IsCorrect      bool   `json:"is_correct"`
 	QuestionID     int    `json:"question_id"`
 }
 
+// LeaderboardRow is the total of a user in a channel. Rank is unique, ties are
+// broken by the time the total was reached and then by user id.
+type LeaderboardRow struct {
+	Rank           int       `json:"rank"`
+	UserID         int64     `json:"user_id"`
+	TGUsername     string    `json:"tg_username"`
+	TotalPoints    int       `json:"total_points"`
+	Answers        int       `json:"answers"`
+	CorrectAnswers int       `json:"correct_answers"`
+	LastAnswerAt   time.Time `json:"last_answer_at"`
+}
+
+// Name is the username of the user, or the id when the user has no username.
+func (r LeaderboardRow) Name() string {
+	if r.TGUsername == "" {
+		return fmt.Sprintf("id%d", r.UserID)
+	}
+	return r.TGUsername
+}
+
+// QuestionStatistics is the aggregate of all answers given to a question.
+type QuestionStatistics struct {
+	Question       Question           `json:"question"`
+	Participants   int                `json:"participants"`
+	CorrectPercent float64            `json:"correct_percent"`
+	AveragePoints  float64            `json:"average_points"`
+	Answers        []AnswerStatistics `json:"answers"`
+}
+
+type AnswerStatistics struct {
+	AnswerID  int    `json:"answer_id"`
+	Answer    string `json:"answer"`
+	IsCorrect bool   `json:"is_correct"`
+	Picked    int    `json:"picked"`
+}
+
 type QuestionsAnswers struct {
 	QuestionID int `json:"questions_id"`
 	AnswerID   int `json:"answers_id"`
 }
 
 type UserResult struct {
-	ID         int   `json:"id"`
-	UserID     int64 `json:"user_id"`
-	Points     int   `json:"points"`
-	QuestionID int   `json:"questions_id"`
+	ID         int       `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Points     int       `json:"points"`
+	QuestionID int       `json:"questions_id"`
+	AnswerID   int       `json:"answer_id"`
+	AnsweredAt time.Time `json:"answered_at"`
+
+	ChannelTgID int64  `json:"channel_tg_id"`
+	ChatID      int64  `json:"chat_id"`
+	ChatName    string `json:"chat_name"`
 
 	TGUsername string `json:"tg_username"`
 
@@ -44,6 +149,7 @@ type Quiz struct {
 }
 
 type IsUserAnswer struct {
-	UserID   int64 `json:"user_id"`
-	AnswerID int   `json:"answers_id"`
+	UserID      int64 `json:"user_id"`
+	AnswerID    int   `json:"answers_id"`
+	ChannelTgID int64 `json:"channel_tg_id"`
 }
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// Season is a closed season of a channel. The current season of a channel is
+// not stored here until it is closed.
+type Season struct {
+	ID           int        `json:"id"`
+	ChannelTgID  int64      `json:"channel_tg_id"`
+	Number       int        `json:"number"`
+	StartedAt    *time.Time `json:"started_at"`
+	ClosedAt     time.Time  `json:"closed_at"`
+	Participants int        `json:"participants"`
+
+	WinnerUsername string `json:"winner_username"`
+	WinnerPoints   int    `json:"winner_points"`
+}
+
+// SeasonStanding is the archived place of a user in a closed season.
+type SeasonStanding struct {
+	SeasonNumber int `json:"season_number"`
+	LeaderboardRow
+}
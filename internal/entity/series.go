@@ -0,0 +1,107 @@
+package entity
+
+import "time"
+
+type SeriesStatus string
+
+const (
+	SeriesDraft    SeriesStatus = "draft"
+	SeriesRunning  SeriesStatus = "running"
+	SeriesPaused   SeriesStatus = "paused"
+	SeriesFinished SeriesStatus = "finished"
+)
+
+func (s SeriesStatus) Title() string {
+	switch s {
+	case SeriesDraft:
+		return "не запущена"
+	case SeriesRunning:
+		return "идет"
+	case SeriesPaused:
+		return "на паузе"
+	case SeriesFinished:
+		return "завершена"
+	}
+	return string(s)
+}
+
+// Series is a set of questions of a channel posted one by one. Every question
+// is open for Interval, then it is closed and the next one is posted.
+type Series struct {
+	ID              int           `json:"id"`
+	ChannelTgID     int64         `json:"channel_tg_id"`
+	Title           string        `json:"title"`
+	Interval        time.Duration `json:"interval"`
+	RevealResults   bool          `json:"reveal_results"`
+	Status          SeriesStatus  `json:"status"`
+	CurrentPosition int           `json:"current_position"`
+	NextRunAt       *time.Time    `json:"next_run_at"`
+	PausedAt        *time.Time    `json:"paused_at"`
+	LastError       *string       `json:"last_error"`
+	StartedAt       *time.Time    `json:"started_at"`
+	FinishedAt      *time.Time    `json:"finished_at"`
+	CreatedBy       int64         `json:"created_by"`
+	CreatedAt       time.Time     `json:"created_at"`
+
+	// QuestionIDs is the order of the questions while the series is a draft
+	// being built, Questions is the stored order.
+	QuestionIDs []int            `json:"question_ids"`
+	Questions   []SeriesQuestion `json:"questions"`
+}
+
+// SeriesQuestion is a question at its position in a series, positions start
+// from 1.
+type SeriesQuestion struct {
+	Position    int        `json:"position"`
+	Question    Question   `json:"question"`
+	PublishedAt *time.Time `json:"published_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+}
+
+func (s *Series) ToggleQuestion(questionID int) {
+	for key, id := range s.QuestionIDs {
+		if id == questionID {
+			s.QuestionIDs = append(s.QuestionIDs[:key], s.QuestionIDs[key+1:]...)
+			return
+		}
+	}
+	s.QuestionIDs = append(s.QuestionIDs, questionID)
+}
+
+// QuestionPosition is the 1-based place of the question in the draft, 0 when
+// the question isn't in it.
+func (s *Series) QuestionPosition(questionID int) int {
+	for key, id := range s.QuestionIDs {
+		if id == questionID {
+			return key + 1
+		}
+	}
+	return 0
+}
+
+// Current is the last posted question, nil before the start.
+func (s *Series) Current() *SeriesQuestion {
+	if s.CurrentPosition < 1 || s.CurrentPosition > len(s.Questions) {
+		return nil
+	}
+	return &s.Questions[s.CurrentPosition-1]
+}
+
+// Next is the question to post after the current one, nil when the current
+// one is the last.
+func (s *Series) Next() *SeriesQuestion {
+	if s.CurrentPosition >= len(s.Questions) {
+		return nil
+	}
+	return &s.Questions[s.CurrentPosition]
+}
+
+// LeaderboardFilter narrows the rating of the channel to the answers given to
+// the questions of the series since its start.
+func (s *Series) LeaderboardFilter() *ExportFilter {
+	filter := &ExportFilter{From: s.StartedAt}
+	for _, question := range s.Questions {
+		filter.QuestionIDs = append(filter.QuestionIDs, question.Question.ID)
+	}
+	return filter
+}
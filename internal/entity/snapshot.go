@@ -0,0 +1,19 @@
+package entity
+
+import "encoding/json"
+
+// QuestionSnapshot holds the rows of a deleted question as they were stored,
+// so the question can be restored with the same ids, answers and results.
+type QuestionSnapshot struct {
+	QuestionID  int
+	ChannelTgID int64
+
+	Question     json.RawMessage
+	Answers      json.RawMessage
+	Results      json.RawMessage
+	UserAnswers  json.RawMessage
+	Versions     json.RawMessage
+	Posts        json.RawMessage
+	Publications json.RawMessage
+	Series       json.RawMessage
+}
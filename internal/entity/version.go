@@ -0,0 +1,141 @@
+package entity
+
+import (
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
+	"time"
+)
+
+type VersionChange string
+
+const (
+	VersionCreated  VersionChange = "created"
+	VersionText     VersionChange = "text"
+	VersionMedia    VersionChange = "media"
+	VersionAnswers  VersionChange = "answers"
+	VersionRollback VersionChange = "rollback"
+)
+
+func (v VersionChange) Title() string {
+	switch v {
+	case VersionCreated:
+		return "исходная версия"
+	case VersionText:
+		return "текст вопроса"
+	case VersionMedia:
+		return "медиа"
+	case VersionAnswers:
+		return "ответы"
+	case VersionRollback:
+		return "откат"
+	}
+	return string(v)
+}
+
+// QuestionContent is everything an admin can edit in a question. It is stored
+// as JSON in every version.
+type QuestionContent struct {
+	QuestionName     string                   `json:"question_name"`
+	QuestionEntities []coverter.MessageEntity `json:"question_entities"`
+	TextFormat       TextFormat               `json:"text_format"`
+	FileID           *string                  `json:"file_id"`
+	MediaType        MediaType                `json:"media_type"`
+	Answers          []Answer                 `json:"answers"`
+}
+
+// QuestionVersion is the content of a question after a change. Version 1 is
+// the content before the first tracked change.
+type QuestionVersion struct {
+	ID                int             `json:"id"`
+	QuestionID        int             `json:"question_id"`
+	Version           int             `json:"version"`
+	ChangedBy         int64           `json:"changed_by"`
+	ChangedByUsername string          `json:"changed_by_username"`
+	ChangedAt         time.Time       `json:"changed_at"`
+	Change            VersionChange   `json:"change"`
+	RolledBackTo      *int            `json:"rolled_back_to"`
+	Content           QuestionContent `json:"content"`
+}
+
+func (c QuestionContent) Question() Question {
+	return Question{
+		QuestionName:     c.QuestionName,
+		QuestionEntities: c.QuestionEntities,
+		TextFormat:       c.TextFormat,
+		FileID:           c.FileID,
+		MediaType:        c.MediaType,
+	}
+}
+
+// Diff describes what changed from prev to c, one line per change.
+func (c QuestionContent) Diff(prev QuestionContent) []string {
+	var lines []string
+
+	if text, prevText := c.Question().PlainText(), prev.Question().PlainText(); text != prevText {
+		lines = append(lines, fmt.Sprintf("Текст: «%s» → «%s»", shorten(prevText), shorten(text)))
+	}
+
+	switch {
+	case prev.FileID == nil && c.FileID != nil:
+		lines = append(lines, fmt.Sprintf("Медиа: добавлено (%s)", c.MediaType))
+	case prev.FileID != nil && c.FileID == nil:
+		lines = append(lines, "Медиа: удалено")
+	case prev.FileID != nil && *prev.FileID != *c.FileID:
+		lines = append(lines, fmt.Sprintf("Медиа: заменено (%s → %s)", prev.MediaType, c.MediaType))
+	}
+
+	prevAnswers := make(map[string]Answer, len(prev.Answers))
+	for _, answer := range prev.Answers {
+		prevAnswers[answer.Answer] = answer
+	}
+	answers := make(map[string]struct{}, len(c.Answers))
+	for _, answer := range c.Answers {
+		answers[answer.Answer] = struct{}{}
+
+		old, ok := prevAnswers[answer.Answer]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("+ %s (%d%s)", shorten(answer.Answer), answer.CostOfResponse, correctMark(answer.IsCorrect)))
+		case old.CostOfResponse != answer.CostOfResponse || old.IsCorrect != answer.IsCorrect:
+			lines = append(lines, fmt.Sprintf("~ %s: %d%s → %d%s", shorten(answer.Answer),
+				old.CostOfResponse, correctMark(old.IsCorrect), answer.CostOfResponse, correctMark(answer.IsCorrect)))
+		}
+	}
+	for _, answer := range prev.Answers {
+		if _, ok := answers[answer.Answer]; !ok {
+			lines = append(lines, fmt.Sprintf("− %s", shorten(answer.Answer)))
+		}
+	}
+
+	return lines
+}
+
+// SameAnswers reports whether both contents have the same answers in the same
+// order.
+func (c QuestionContent) SameAnswers(other QuestionContent) bool {
+	if len(c.Answers) != len(other.Answers) {
+		return false
+	}
+	for key, answer := range c.Answers {
+		value := other.Answers[key]
+		if answer.Answer != value.Answer || answer.CostOfResponse != value.CostOfResponse || answer.IsCorrect != value.IsCorrect {
+			return false
+		}
+	}
+	return true
+}
+
+func correctMark(correct bool) string {
+	if correct {
+		return ", верный"
+	}
+	return ""
+}
+
+func shorten(text string) string {
+	runes := []rune(text)
+	if len(runes) > 40 {
+		return string(runes[:40]) + "…"
+	}
+	return text
+}
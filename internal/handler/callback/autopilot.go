@@ -0,0 +1,212 @@
+package callback
+
+import (
+	"context"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const autopilotScheduleHelp = "Отправьте расписание в формате cron: минута, час, день месяца, месяц, день недели.\n\n" +
+	"<code>0 12 * * 1-5</code> — по будням в 12:00\n" +
+	"<code>30 9,18 * * *</code> — каждый день в 9:30 и 18:30\n" +
+	"<code>0 */3 * * sat,sun</code> — по выходным каждые 3 часа"
+
+// CallbackAutopilot - autopilot_{channel_id}
+func (c *callbackQuiz) CallbackAutopilot() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetSecondValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		// the button also cancels the input of the schedule or the tag
+		if data, ok := c.store.Read(update.FromChat().ID); ok && data != nil &&
+			(data.OperationType == store.AutopilotSchedule || data.OperationType == store.AutopilotTag) {
+			c.store.Delete(update.FromChat().ID)
+		}
+
+		autopilot, err := c.autopilotService.GetAutopilot(ctx, int64(channelID))
+		if err != nil {
+			c.log.Error("autopilotService.GetAutopilot: %v", err)
+			return err
+		}
+
+		return c.showAutopilot(ctx, update, autopilot)
+	}
+}
+
+// CallbackAutopilotToggle - apl_toggle_{channel_id}
+func (c *callbackQuiz) CallbackAutopilotToggle() tgbot.ViewFunc {
+	return c.autopilotAction(c.autopilotService.Toggle)
+}
+
+// CallbackAutopilotDifficulty - apl_diff_{channel_id}
+func (c *callbackQuiz) CallbackAutopilotDifficulty() tgbot.ViewFunc {
+	return c.autopilotAction(c.autopilotService.CycleDifficulty)
+}
+
+// CallbackAutopilotThreshold - apl_low_{channel_id}
+func (c *callbackQuiz) CallbackAutopilotThreshold() tgbot.ViewFunc {
+	return c.autopilotAction(c.autopilotService.CycleThreshold)
+}
+
+// CallbackAutopilotSchedule - apl_schedule_{channel_id}
+func (c *callbackQuiz) CallbackAutopilotSchedule() tgbot.ViewFunc {
+	return c.autopilotInput(store.AutopilotSchedule, autopilotScheduleHelp)
+}
+
+// CallbackAutopilotTag - apl_tag_{channel_id}
+func (c *callbackQuiz) CallbackAutopilotTag() tgbot.ViewFunc {
+	return c.autopilotInput(store.AutopilotTag, "Отправьте тег, из вопросов с которым автопилот будет выбирать, "+
+		"или «-», чтобы выбирать из всех вопросов")
+}
+
+// autopilotAction changes a setting of the autopilot and shows it after.
+func (c *callbackQuiz) autopilotAction(action func(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.Autopilot, error)) tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		autopilot, err := action(ctx, int64(channelID), update.FromChat().ID)
+		if err != nil {
+			c.log.Error("failed to change autopilot of %d: %v", channelID, err)
+			return err
+		}
+
+		return c.showAutopilot(ctx, update, autopilot)
+	}
+}
+
+// autopilotInput asks the admin for a text setting, the reply is handled by
+// the bot as the operation.
+func (c *callbackQuiz) autopilotInput(operation store.TypeCommand, text string) tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		cancelCommand := markup.CancelCommandAutopilot(int64(channelID))
+		sentMsg, err := c.tgMsg.SendNewMessage(update.FromChat().ID, &cancelCommand, text)
+		if err != nil {
+			return err
+		}
+
+		c.store.Set(&store.Data{
+			ChannelID:     channelID,
+			CurrentMsgID:  sentMsg,
+			PreferMsgID:   update.CallbackQuery.Message.MessageID,
+			OperationType: operation,
+		}, update.FromChat().ID)
+
+		return nil
+	}
+}
+
+func (c *callbackQuiz) showAutopilot(ctx context.Context, update *tgbotapi.Update, autopilot *entity.Autopilot) error {
+	unsent, err := c.autopilotService.CountUnsentQuestions(ctx, autopilot)
+	if err != nil {
+		c.log.Error("autopilotService.CountUnsentQuestions: %v", err)
+		return err
+	}
+
+	m := markup.AutopilotSetting(autopilot)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		markup.AutopilotText(autopilot, unsent)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CallbackQuestionMeta - qmeta_{question_id}
+func (c *callbackQuiz) CallbackQuestionMeta() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		return c.showQuestionMeta(ctx, update, id)
+	}
+}
+
+// CallbackQuestionDifficulty - qdiff_{question_id}_{difficulty}, 0 clears it
+func (c *callbackQuiz) CallbackQuestionDifficulty() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		value := GetThirdValue(update.CallbackData())
+		if id == 0 || value < 0 || value > entity.MaxQuestionDifficulty {
+			c.log.Error("failed to get difficulty from  button: %s", update.CallbackData())
+			return customErr.ErrNotFound
+		}
+
+		var difficulty *int
+		if value > 0 {
+			difficulty = &value
+		}
+		if err := c.quizService.UpdateQuestionDifficulty(ctx, id, difficulty); err != nil {
+			c.log.Error("quizService.UpdateQuestionDifficulty: %v", err)
+			return err
+		}
+
+		return c.showQuestionMeta(ctx, update, id)
+	}
+}
+
+// CallbackQuestionTags - qtags_{question_id}
+func (c *callbackQuiz) CallbackQuestionTags() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		text := "Отправьте теги через запятую, например: история, наука. «-» удалит все теги"
+		cancelCommand := markup.CancelCommandQuestion(id)
+		sentMsg, err := c.tgMsg.SendNewMessage(update.FromChat().ID, &cancelCommand, text)
+		if err != nil {
+			return err
+		}
+
+		c.store.Set(&store.Data{
+			QuestionID:    id,
+			CurrentMsgID:  sentMsg,
+			PreferMsgID:   update.CallbackQuery.Message.MessageID,
+			OperationType: store.QuizUpdateTags,
+		}, update.FromChat().ID)
+
+		return nil
+	}
+}
+
+func (c *callbackQuiz) showQuestionMeta(ctx context.Context, update *tgbotapi.Update, id int) error {
+	question, err := c.quizService.GetQuestionByID(ctx, id)
+	if err != nil {
+		c.log.Error("quizService.GetQuestionByID: %v", err)
+		return err
+	}
+
+	m := markup.QuestionMeta(question)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		markup.QuestionMetaText(question)); err != nil {
+		return err
+	}
+
+	return nil
+}
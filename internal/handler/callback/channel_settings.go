@@ -0,0 +1,150 @@
+package callback
+
+import (
+	"context"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CallbackChannelSettings - chset_{channel_id}
+func (c *callbackQuiz) CallbackChannelSettings() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetSecondValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		// the button also cancels the input of a text setting
+		if data, ok := c.store.Read(update.FromChat().ID); ok && data != nil &&
+			(data.OperationType == store.ChannelFeedback || data.OperationType == store.ChannelSignature ||
+				data.OperationType == store.ChannelTimezone) {
+			c.store.Delete(update.FromChat().ID)
+		}
+
+		settings, err := c.settingsService.GetSettings(ctx, int64(channelID))
+		if err != nil {
+			c.log.Error("settingsService.GetSettings: %v", err)
+			return err
+		}
+
+		return c.showChannelSettings(ctx, update, settings)
+	}
+}
+
+// CallbackChannelButtonsPerRow - cset_row_{channel_id}
+func (c *callbackQuiz) CallbackChannelButtonsPerRow() tgbot.ViewFunc {
+	return c.channelSettingsAction(c.settingsService.CycleButtonsPerRow)
+}
+
+// CallbackChannelShuffle - cset_shuffle_{channel_id}
+func (c *callbackQuiz) CallbackChannelShuffle() tgbot.ViewFunc {
+	return c.channelSettingsAction(c.settingsService.ToggleShuffle)
+}
+
+// CallbackChannelPoints - cset_points_{channel_id}
+func (c *callbackQuiz) CallbackChannelPoints() tgbot.ViewFunc {
+	return c.channelSettingsAction(c.settingsService.TogglePoints)
+}
+
+// CallbackChannelMembership - cset_member_{channel_id}
+func (c *callbackQuiz) CallbackChannelMembership() tgbot.ViewFunc {
+	return c.channelSettingsAction(c.settingsService.ToggleMembership)
+}
+
+// CallbackChannelAnswerChange - cset_change_{channel_id}
+func (c *callbackQuiz) CallbackChannelAnswerChange() tgbot.ViewFunc {
+	return c.channelSettingsAction(c.settingsService.CycleAnswerChange)
+}
+
+// CallbackChannelDeadline - cset_deadline_{channel_id}
+func (c *callbackQuiz) CallbackChannelDeadline() tgbot.ViewFunc {
+	return c.channelSettingsAction(c.settingsService.CycleDefaultDeadline)
+}
+
+// CallbackChannelFeedback - cset_feedback_{channel_id}
+func (c *callbackQuiz) CallbackChannelFeedback() tgbot.ViewFunc {
+	return c.channelSettingsInput(store.ChannelFeedback, "Отправьте текст, который пользователь увидит после ответа. "+
+		"<code>"+entity.FeedbackPoints+"</code> заменится на полученные баллы. «-» вернёт текст по умолчанию")
+}
+
+// CallbackChannelSignature - cset_sign_{channel_id}
+func (c *callbackQuiz) CallbackChannelSignature() tgbot.ViewFunc {
+	return c.channelSettingsInput(store.ChannelSignature, "Отправьте подпись, которая будет добавлена в конец новых постов, "+
+		"или «-», чтобы убрать её")
+}
+
+// CallbackChannelTimezone - cset_tz_{channel_id}
+func (c *callbackQuiz) CallbackChannelTimezone() tgbot.ViewFunc {
+	return c.channelSettingsInput(store.ChannelTimezone, "Отправьте часовой пояс канала из базы IANA, например "+
+		"<code>Europe/Moscow</code>, <code>Asia/Yekaterinburg</code> или <code>UTC</code>. В этом поясе считаются "+
+		"расписания задач и автопилота, периоды выгрузок и даты на экранах администратора")
+}
+
+// channelSettingsAction changes a setting of the channel and shows them after.
+func (c *callbackQuiz) channelSettingsAction(action func(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error)) tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		settings, err := action(ctx, int64(channelID), update.FromChat().ID)
+		if err != nil {
+			c.log.Error("failed to change settings of %d: %v", channelID, err)
+			return err
+		}
+
+		return c.showChannelSettings(ctx, update, settings)
+	}
+}
+
+// channelSettingsInput asks the admin for a text setting, the reply is
+// handled by the bot as the operation.
+func (c *callbackQuiz) channelSettingsInput(operation store.TypeCommand, text string) tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		cancelCommand := markup.CancelCommandChannelSettings(int64(channelID))
+		sentMsg, err := c.tgMsg.SendNewMessage(update.FromChat().ID, &cancelCommand, text)
+		if err != nil {
+			return err
+		}
+
+		c.store.Set(&store.Data{
+			ChannelID:     channelID,
+			CurrentMsgID:  sentMsg,
+			PreferMsgID:   update.CallbackQuery.Message.MessageID,
+			OperationType: operation,
+		}, update.FromChat().ID)
+
+		return nil
+	}
+}
+
+func (c *callbackQuiz) showChannelSettings(ctx context.Context, update *tgbotapi.Update, settings *entity.ChannelSettings) error {
+	channel, err := c.channelService.GetByChannelID(ctx, settings.ChannelTgID)
+	if err != nil {
+		c.log.Error("channelService.GetByChannelID: %v", err)
+		return err
+	}
+
+	m := markup.ChannelSettings(settings, channel.Timezone)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		markup.ChannelSettingsText(settings, channel.Timezone)); err != nil {
+		return err
+	}
+
+	return nil
+}
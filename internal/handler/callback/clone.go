@@ -0,0 +1,202 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+)
+
+// CallbackCloneQuestion - qclone_{question_id}
+func (c *callbackQuiz) CallbackCloneQuestion() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		question, err := c.quizService.GetQuestionByID(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question by id: %v", err)
+			return err
+		}
+
+		request := &entity.CloneRequest{SourceChannelID: question.ChannelID, QuestionIDs: []int{id}}
+		c.store.Set(&store.Data{
+			Data:          request,
+			OperationType: store.QuizClone,
+			ChannelID:     int(question.ChannelID),
+			QuestionID:    id,
+		}, update.FromChat().ID)
+
+		return c.showCloneTargets(ctx, update, request, fmt.Sprintf("question_get_%d", id))
+	}
+}
+
+// CallbackCloneSelect - clone_select_{channel_id}
+func (c *callbackQuiz) CallbackCloneSelect() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		request := &entity.CloneRequest{SourceChannelID: int64(channelID)}
+		c.store.Set(&store.Data{
+			Data:          request,
+			OperationType: store.QuizClone,
+			ChannelID:     channelID,
+		}, update.FromChat().ID)
+
+		return c.showCloneQuestions(ctx, update, request, "")
+	}
+}
+
+// CallbackCloneToggle - clone_toggle_{question_id}
+func (c *callbackQuiz) CallbackCloneToggle() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		request, ok := c.storedCloneRequest(update)
+		if !ok {
+			return c.cloneExpired(update)
+		}
+		request.ToggleQuestion(id)
+
+		return c.showCloneQuestions(ctx, update, request, "")
+	}
+}
+
+// CallbackCloneTarget - clone_target_{channel_id}
+func (c *callbackQuiz) CallbackCloneTarget() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		request, ok := c.storedCloneRequest(update)
+		if !ok || request.SourceChannelID != int64(channelID) {
+			return c.cloneExpired(update)
+		}
+		if len(request.QuestionIDs) == 0 {
+			return c.showCloneQuestions(ctx, update, request, "Отметьте хотя бы один вопрос\n\n")
+		}
+
+		return c.showCloneTargets(ctx, update, request, fmt.Sprintf("channel_get_%d", channelID))
+	}
+}
+
+// CallbackCloneInto - clone_into_{channel_id}
+func (c *callbackQuiz) CallbackCloneInto() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		request, ok := c.storedCloneRequest(update)
+		if !ok || len(request.QuestionIDs) == 0 {
+			return c.cloneExpired(update)
+		}
+
+		channel, err := c.channelService.GetByChannelID(ctx, int64(channelID))
+		if err != nil {
+			c.log.Error("channelService.GetByChannelID: %v", err)
+			return err
+		}
+
+		ids, err := c.quizService.CloneQuestions(ctx, request.QuestionIDs, channel.TgID, update.FromChat().ID)
+		if err != nil {
+			c.log.Error("quizService.CloneQuestions: %v", err)
+			return err
+		}
+		c.store.Delete(update.FromChat().ID)
+
+		m := markup.QuizSettingV2(channel.TgID)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			fmt.Sprintf("Скопировано вопросов: %d в канал %s\nКопии сохранены как неотправленные черновики",
+				len(ids), html.EscapeString(channel.ChannelName))); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (c *callbackQuiz) storedCloneRequest(update *tgbotapi.Update) (*entity.CloneRequest, bool) {
+	storeData, ok := c.store.Read(update.FromChat().ID)
+	if !ok || storeData.OperationType != store.QuizClone {
+		return nil, false
+	}
+
+	request, ok := storeData.Data.(*entity.CloneRequest)
+	if !ok {
+		c.log.Error("unexpected clone request type: %T", storeData.Data)
+	}
+	return request, ok
+}
+
+func (c *callbackQuiz) showCloneQuestions(ctx context.Context, update *tgbotapi.Update, request *entity.CloneRequest, prefix string) error {
+	questions, err := c.quizService.GetAllQuestionsByChannelID(ctx, request.SourceChannelID)
+	if err != nil {
+		c.log.Error("quizService.GetAllQuestionsByChannelID: %v", err)
+		return err
+	}
+
+	m := markup.CloneQuestionSelect(questions, request)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		fmt.Sprintf("%sОтметьте вопросы для копирования. Выбрано: %d", prefix, len(request.QuestionIDs))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) showCloneTargets(ctx context.Context, update *tgbotapi.Update, request *entity.CloneRequest, back string) error {
+	channels, err := c.channelService.GetAdminChannels(ctx)
+	if err != nil {
+		c.log.Error("channelService.GetAdminChannels: %v", err)
+		return err
+	}
+
+	m := markup.CloneTargets(channels, back)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		fmt.Sprintf("Выберите канал, в который скопировать вопросов: %d. Копии будут неотправленными черновиками",
+			len(request.QuestionIDs))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) cloneExpired(update *tgbotapi.Update) error {
+	m := markup.Back("main_menu")
+	if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		"Выбор вопросов для копирования не найден, начните заново"); err != nil {
+		return err
+	}
+
+	return nil
+}
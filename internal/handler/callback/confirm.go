@@ -0,0 +1,97 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+)
+
+// confirmSuffix is appended to the callback data of the "Да" button.
+const confirmSuffix = "_y"
+
+// confirmation describes what a destructive action is going to affect. Cancel
+// is the callback data of the "Нет" button.
+type confirmation struct {
+	text   string
+	cancel string
+}
+
+// confirmationFunc builds the summary of the action. A nil confirmation runs
+// the action right away, e.g. when there is nothing to lose.
+type confirmationFunc func(ctx context.Context, update *tgbotapi.Update) (*confirmation, error)
+
+// withConfirmation asks "Вы уверены?" before running next. Next gets the
+// original callback data, without confirmSuffix.
+func (c *callbackQuiz) withConfirmation(summary confirmationFunc, next tgbot.ViewFunc) tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		data := update.CallbackData()
+		if strings.HasSuffix(data, confirmSuffix) {
+			update.CallbackQuery.Data = strings.TrimSuffix(data, confirmSuffix)
+			return next(ctx, bot, update)
+		}
+
+		confirm, err := summary(ctx, update)
+		if err != nil {
+			return err
+		}
+		if confirm == nil {
+			return next(ctx, bot, update)
+		}
+
+		m := markup.Confirm(data+confirmSuffix, confirm.cancel)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			confirm.text+"\n\nВы уверены?"); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// offerUndo saves restore for the admin and returns the menu with the undo
+// button and a note about how long the undo is available.
+func (c *callbackQuiz) offerUndo(update *tgbotapi.Update, menu tgbotapi.InlineKeyboardMarkup, back string,
+	restore func(ctx context.Context) error) (tgbotapi.InlineKeyboardMarkup, string) {
+	token := c.undo.Set(&store.UndoAction{
+		UserID:  update.FromChat().ID,
+		Back:    back,
+		Restore: restore,
+	})
+
+	note := fmt.Sprintf("\n\nОтменить действие можно в течение %d мин.", int(c.undo.TTL().Minutes()))
+	return markup.WithUndo(menu, token), note
+}
+
+// CallbackUndo - undo_action_{token}
+func (c *callbackQuiz) CallbackUndo() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		token := GetThirdValueString(update.CallbackData())
+
+		action, ok := c.undo.Take(token, update.FromChat().ID)
+		if !ok {
+			return customErr.ErrUndoUnavailable
+		}
+
+		if err := action.Restore(ctx); err != nil {
+			c.log.Error("failed to undo action: %v", err)
+			return err
+		}
+
+		m := markup.Back(action.Back)
+		if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			"Действие отменено, прежнее состояние восстановлено"); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,253 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"time"
+)
+
+const cronScheduleHelp = "Отправьте расписание в формате cron: минута, час, день месяца, месяц, день недели.\n\n" +
+	"<code>0 12 * * 1-5</code> — по будням в 12:00\n" +
+	"<code>0 20 * * sun</code> — по воскресеньям в 20:00\n" +
+	"<code>0 9 1 * *</code> — первого числа каждого месяца в 9:00"
+
+// CallbackCronJobList - jobs_list_{channel_id}
+func (c *callbackQuiz) CallbackCronJobList() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		// the button also cancels the input of a new job or the timezone
+		c.cancelCronInput(update)
+
+		return c.showCronJobList(ctx, update, int64(channelID))
+	}
+}
+
+// CallbackCronJobNew - jobs_new_{channel_id}
+func (c *callbackQuiz) CallbackCronJobNew() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		m := markup.CronJobKinds(int64(channelID))
+		if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			"Что задача будет делать?\n\n"+
+				"Публикация следующего вопроса берет самый старый неотправленный вопрос канала. "+
+				"Закрытие убирает кнопки у опубликованных вопросов, кроме вопросов серий. "+
+				"Рейтинг текущего сезона публикуется в канал, отчёт за неделю приходит администраторам файлом Excel"); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackCronJobKind - jobkind_{channel_id}_{kind}
+func (c *callbackQuiz) CallbackCronJobKind() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetSecondValue(update.CallbackData())
+		kind := GetThirdValue(update.CallbackData())
+		if channelID == 0 || kind < 0 || kind >= len(entity.CronJobKinds) {
+			c.log.Error("failed to get job kind from  button: %s", update.CallbackData())
+			return customErr.ErrNotFound
+		}
+
+		return c.cronInput(update, &store.Data{
+			Data: &entity.CronJob{
+				ChannelTgID: int64(channelID),
+				Kind:        entity.CronJobKinds[kind],
+				CreatedBy:   update.FromChat().ID,
+			},
+			ChannelID:     channelID,
+			OperationType: store.CronJobCreate,
+		}, fmt.Sprintf("jobs_list_%d", channelID), cronScheduleHelp)
+	}
+}
+
+// CallbackCronJobGet - job_get_{job_id}
+func (c *callbackQuiz) CallbackCronJobGet() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		// the button also cancels the input of the schedule
+		c.cancelCronInput(update)
+
+		job, err := c.cronJobService.GetCronJobByID(ctx, id)
+		if err != nil {
+			c.log.Error("cronJobService.GetCronJobByID: %v", err)
+			return err
+		}
+
+		return c.showCronJob(update, job)
+	}
+}
+
+// CallbackCronJobPause - job_pause_{job_id}
+func (c *callbackQuiz) CallbackCronJobPause() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		job, err := c.cronJobService.TogglePause(ctx, id)
+		if err != nil {
+			c.log.Error("cronJobService.TogglePause: %v", err)
+			return err
+		}
+
+		return c.showCronJob(update, job)
+	}
+}
+
+// CallbackCronJobSchedule - job_schedule_{job_id}
+func (c *callbackQuiz) CallbackCronJobSchedule() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		job, err := c.cronJobService.GetCronJobByID(ctx, id)
+		if err != nil {
+			c.log.Error("cronJobService.GetCronJobByID: %v", err)
+			return err
+		}
+
+		return c.cronInput(update, &store.Data{
+			Data:          job,
+			ChannelID:     int(job.ChannelTgID),
+			OperationType: store.CronJobSchedule,
+		}, fmt.Sprintf("job_get_%d", id), cronScheduleHelp)
+	}
+}
+
+// CallbackCronJobDelete - job_delete_{job_id}
+func (c *callbackQuiz) CallbackCronJobDelete() tgbot.ViewFunc {
+	return c.withConfirmation(c.deleteCronJobConfirmation, func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		job, err := c.cronJobService.GetCronJobByID(ctx, id)
+		if err != nil {
+			c.log.Error("cronJobService.GetCronJobByID: %v", err)
+			return err
+		}
+
+		if err = c.cronJobService.DeleteCronJob(ctx, id); err != nil {
+			c.log.Error("cronJobService.DeleteCronJob: %v", err)
+			return err
+		}
+
+		return c.showCronJobList(ctx, update, job.ChannelTgID)
+	})
+}
+
+func (c *callbackQuiz) deleteCronJobConfirmation(ctx context.Context, update *tgbotapi.Update) (*confirmation, error) {
+	id := GetThirdValue(update.CallbackData())
+	if id == 0 {
+		c.log.Error("GetThirdValue: failed to get id from  button")
+		return nil, customErr.ErrNotFound
+	}
+
+	job, err := c.cronJobService.GetCronJobByID(ctx, id)
+	if err != nil {
+		c.log.Error("cronJobService.GetCronJobByID: %v", err)
+		return nil, err
+	}
+
+	return &confirmation{
+		text:   fmt.Sprintf("Задача «%s» по расписанию %s будет удалена", job.Kind.Title(), job.Schedule),
+		cancel: fmt.Sprintf("job_get_%d", id),
+	}, nil
+}
+
+// cronInput asks the admin for a text, the reply is handled by the bot as the
+// operation of data. cancel is the screen the cancel button opens.
+func (c *callbackQuiz) cronInput(update *tgbotapi.Update, data *store.Data, cancel string, text string) error {
+	cancelCommand := markup.CancelCommand(cancel)
+	sentMsg, err := c.tgMsg.SendNewMessage(update.FromChat().ID, &cancelCommand, text)
+	if err != nil {
+		return err
+	}
+
+	data.CurrentMsgID = sentMsg
+	data.PreferMsgID = update.CallbackQuery.Message.MessageID
+	c.store.Set(data, update.FromChat().ID)
+
+	return nil
+}
+
+func (c *callbackQuiz) cancelCronInput(update *tgbotapi.Update) {
+	data, ok := c.store.Read(update.FromChat().ID)
+	if !ok || data == nil {
+		return
+	}
+	switch data.OperationType {
+	case store.CronJobCreate, store.CronJobSchedule:
+		c.store.Delete(update.FromChat().ID)
+	}
+}
+
+func (c *callbackQuiz) showCronJobList(ctx context.Context, update *tgbotapi.Update, channelID int64) error {
+	channel, err := c.channelService.GetByChannelID(ctx, channelID)
+	if err != nil {
+		c.log.Error("channelService.GetByChannelID: %v", err)
+		return err
+	}
+
+	jobs, err := c.cronJobService.GetCronJobsByChannelID(ctx, channelID)
+	if err != nil {
+		c.log.Error("cronJobService.GetCronJobsByChannelID: %v", err)
+		return err
+	}
+	runs := c.cronJobService.UpcomingRuns(jobs, time.Now(), markup.CronListRuns)
+
+	m := markup.CronJobList(jobs, channelID)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		markup.CronJobListText(channel.Timezone, jobs, runs)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) showCronJob(update *tgbotapi.Update, job *entity.CronJob) error {
+	runs := c.cronJobService.UpcomingRuns([]entity.CronJob{*job}, time.Now(), markup.CronJobRuns)
+
+	m := markup.CronJobSetting(job)
+	if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		markup.CronJobText(job, runs)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,9 +1,14 @@
 package callback
 
 import (
+	"context"
+	"fmt"
 	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	customMsg "github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api"
+	"html"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func GetThirdValue(data string) int {
@@ -20,6 +25,24 @@ func GetThirdValue(data string) int {
 	return id
 }
 
+func GetSecondValue(data string) int {
+	id, err := strconv.Atoi(GetSecondValueString(data))
+	if err != nil {
+		return 0
+	}
+
+	return id
+}
+
+func GetSecondValueString(data string) string {
+	parts := strings.Split(data, "_")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ""
+	}
+
+	return parts[1]
+}
+
 func GetThirdValueString(data string) string {
 	parts := strings.Split(data, "_")
 	if len(parts) > 3 {
@@ -36,11 +59,189 @@ func AnswerToArgsModel(answer []entity.Answer) *entity.Args {
 	args := new(entity.Args)
 	args.Answers = make([]entity.AnswerArgs, len(answer))
 	for key, value := range answer {
+		isCorrect := value.IsCorrect
 		args.Answers[key] = entity.AnswerArgs{
-			Answer: value.Answer,
-			Cost:   value.CostOfResponse,
+			Answer:  value.Answer,
+			Cost:    value.CostOfResponse,
+			Correct: &isCorrect,
 		}
 	}
 
 	return args
 }
+
+func PreflightToText(preflight *entity.Preflight) string {
+	var sb strings.Builder
+	sb.WriteString("Проверка перед публикацией:\n")
+	for _, check := range preflight.Checks {
+		switch check.Level {
+		case entity.CheckOK:
+			sb.WriteString("✅ ")
+		case entity.CheckWarning:
+			sb.WriteString("⚠️ ")
+		case entity.CheckError:
+			sb.WriteString("❌ ")
+		}
+		sb.WriteString(check.Name)
+		if check.Detail != "" {
+			sb.WriteString(" — " + html.EscapeString(check.Detail))
+		}
+		sb.WriteString("\n")
+	}
+
+	switch {
+	case preflight.HasErrors():
+		sb.WriteString("\nПубликация невозможна, исправьте ошибки")
+	case preflight.HasWarnings():
+		sb.WriteString("\nЕсть предупреждения, вопрос можно опубликовать всё равно")
+	}
+
+	return sb.String()
+}
+
+func PublishResultToText(result *entity.PublishResult) string {
+	var sb strings.Builder
+	if len(result.Published) == 1 && len(result.Errors) == 0 {
+		sb.WriteString("Вопрос опубликован в канале")
+	} else {
+		sb.WriteString("Вопрос опубликован в каналах: " + html.EscapeString(strings.Join(result.Published, ", ")))
+	}
+	for _, text := range result.Errors {
+		sb.WriteString("\n❌ " + html.EscapeString(text))
+	}
+
+	return sb.String()
+}
+
+func PostSyncToText(sync *entity.PostSync) string {
+	if sync.Total == 0 {
+		return "Опубликованные посты вопроса не найдены, обновить можно только посты, отправленные ботом"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Посты в канале обновлены: %d из %d", sync.Updated, sync.Total))
+	for _, text := range sync.Errors {
+		sb.WriteString("\n❌ " + html.EscapeString(text))
+	}
+
+	return sb.String()
+}
+
+// SeriesToText shows the series, times are in location.
+func SeriesToText(series *entity.Series, location *time.Location) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>%s</b>\n", html.EscapeString(series.Title)))
+	sb.WriteString(fmt.Sprintf("Статус: %s\n", series.Status.Title()))
+	sb.WriteString(fmt.Sprintf("Каждый вопрос открыт: %d мин.\n", int(series.Interval.Minutes())))
+	if series.Status == entity.SeriesRunning && series.NextRunAt != nil {
+		sb.WriteString(fmt.Sprintf("Следующий вопрос: %s\n", series.NextRunAt.In(location).Format("15:04")))
+	}
+	if series.LastError != nil {
+		sb.WriteString("❌ Не удалось опубликовать вопрос: " + html.EscapeString(*series.LastError) + "\n")
+	}
+
+	sb.WriteString("\n")
+	for _, question := range series.Questions {
+		var mark string
+		switch {
+		case question.ClosedAt != nil:
+			mark = "✅"
+		case question.PublishedAt != nil:
+			mark = "▶️"
+		case question.Question.DeletedAt != nil:
+			mark = "🗑"
+		default:
+			mark = "⏳"
+		}
+
+		name := []rune(question.Question.PlainText())
+		if len(name) > 40 {
+			name = append(name[:40], '…')
+		}
+		sb.WriteString(fmt.Sprintf("%s %d. %s\n", mark, question.Position, html.EscapeString(string(name))))
+	}
+
+	return sb.String()
+}
+
+const pollCorrectCost = 1
+
+// ForwardToQuizModel builds a question draft from a forwarded post or a poll.
+// ok is false when the message has nothing to build a question from.
+func ForwardToQuizModel(update *customMsg.Update) (*entity.Quiz, bool) {
+	message := update.Message
+	quiz := new(entity.Quiz)
+
+	if message.Poll != nil {
+		quiz.Question.QuestionName = message.Poll.Question
+		quiz.Question.QuestionEntities = update.PollEntities()
+		quiz.Answer = make([]entity.Answer, len(message.Poll.Options))
+		for key, option := range message.Poll.Options {
+			quiz.Answer[key] = entity.Answer{Answer: option.Text}
+			if message.Poll.Type == "quiz" && message.Poll.CorrectOptionID == key {
+				quiz.Answer[key].CostOfResponse = pollCorrectCost
+				quiz.Answer[key].IsCorrect = true
+			}
+		}
+		return quiz, quiz.Question.QuestionName != ""
+	}
+
+	if message.Text != "" {
+		quiz.Question.QuestionName = message.Text
+	} else {
+		quiz.Question.QuestionName = message.Caption
+	}
+	quiz.Question.QuestionEntities = update.TextEntities()
+
+	if fileID, mediaType, ok := customMsg.MediaFromMessage(message); ok {
+		quiz.Question.FileID = &fileID
+		quiz.Question.MediaType = mediaType
+	}
+
+	return quiz, quiz.Question.QuestionName != "" || quiz.Question.FileID != nil
+}
+
+func QuizToText(quiz *entity.Quiz) string {
+	var sb strings.Builder
+	sb.WriteString("Вопрос: " + quiz.Question.HTML() + "\n")
+	if quiz.Question.FileID != nil {
+		sb.WriteString("Медиа: " + string(quiz.Question.MediaType) + "\n")
+	}
+
+	if len(quiz.Answer) == 0 {
+		sb.WriteString("Ответы: нет")
+		return sb.String()
+	}
+
+	sb.WriteString("Ответы:\n")
+	for _, answer := range quiz.Answer {
+		sb.WriteString(fmt.Sprintf("• %s — %d", html.EscapeString(answer.Answer), answer.CostOfResponse))
+		if answer.IsCorrect {
+			sb.WriteString(" ✅")
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// channelLocation returns the timezone of the channel, the default one when
+// the channel can't be read.
+func (c *callbackQuiz) channelLocation(ctx context.Context, channelID int64) *time.Location {
+	channel, err := c.channelService.GetByChannelID(ctx, channelID)
+	if err != nil {
+		c.log.Error("channelService.GetByChannelID: %v", err)
+		return entity.LoadLocation(entity.DefaultChannelTimezone)
+	}
+	return channel.Location()
+}
+
+// questionLocation returns the timezone of the channel of the question.
+func (c *callbackQuiz) questionLocation(ctx context.Context, questionID int) *time.Location {
+	question, err := c.quizService.GetQuestionByID(ctx, questionID)
+	if err != nil {
+		c.log.Error("quizService.GetQuestionByID: %v", err)
+		return entity.LoadLocation(entity.DefaultChannelTimezone)
+	}
+	return c.channelLocation(ctx, question.ChannelID)
+}
@@ -0,0 +1,130 @@
+package callback
+
+import (
+	"context"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CallbackPublishChannel - pubch_{question_id}_{channel_tg_id}
+func (c *callbackQuiz) CallbackPublishChannel() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		questionID, channelID := GetSecondValue(update.CallbackData()), GetThirdValue(update.CallbackData())
+		if questionID == 0 || channelID == 0 {
+			c.log.Error("failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		publication, ok := c.storedPublication(update, questionID)
+		if !ok {
+			return c.publicationExpired(update, questionID)
+		}
+		publication.ToggleChannel(int64(channelID))
+
+		return c.showPublishTargets(ctx, update, publication)
+	}
+}
+
+// CallbackPublishScoring - pubmode_{question_id}
+func (c *callbackQuiz) CallbackPublishScoring() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		questionID := GetSecondValue(update.CallbackData())
+		if questionID == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		publication, ok := c.storedPublication(update, questionID)
+		if !ok {
+			return c.publicationExpired(update, questionID)
+		}
+		publication.ToggleScoring()
+
+		return c.showPublishTargets(ctx, update, publication)
+	}
+}
+
+// CallbackPublishSend - pubsend_{question_id}
+func (c *callbackQuiz) CallbackPublishSend() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		questionID := GetSecondValue(update.CallbackData())
+		if questionID == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		publication, ok := c.storedPublication(update, questionID)
+		if !ok {
+			return c.publicationExpired(update, questionID)
+		}
+
+		return c.publishWithPreflight(ctx, update, publication, false)
+	}
+}
+
+// newPublication returns a publication of the question to its own channel.
+func (c *callbackQuiz) newPublication(ctx context.Context, update *tgbotapi.Update, questionID int) (*entity.Publication, error) {
+	question, err := c.quizService.GetQuestionByID(ctx, questionID)
+	if err != nil {
+		c.log.Error("failed to get question by id: %v", err)
+		return nil, err
+	}
+
+	return &entity.Publication{
+		QuestionID: questionID,
+		Scoring:    entity.ScoringSeparate,
+		ChannelIDs: []int64{question.ChannelID},
+		CreatedBy:  update.FromChat().ID,
+	}, nil
+}
+
+// storedPublication returns the publication the admin is setting up for the
+// question.
+func (c *callbackQuiz) storedPublication(update *tgbotapi.Update, questionID int) (*entity.Publication, bool) {
+	storeData, ok := c.store.Read(update.FromChat().ID)
+	if !ok || storeData.OperationType != store.QuizPublishTargets || storeData.QuestionID != questionID {
+		return nil, false
+	}
+
+	publication, ok := storeData.Data.(*entity.Publication)
+	if !ok {
+		c.log.Error("unexpected publication type: %T", storeData.Data)
+	}
+	return publication, ok
+}
+
+func (c *callbackQuiz) showPublishTargets(ctx context.Context, update *tgbotapi.Update, publication *entity.Publication) error {
+	channels, err := c.channelService.GetAdminChannels(ctx)
+	if err != nil {
+		c.log.Error("channelService.GetAdminChannels: %v", err)
+		return err
+	}
+
+	m := markup.PublishTargets(channels, publication)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		"Выберите каналы для публикации.\n\n"+
+			"Очки отдельно в каждом канале — участник может ответить в каждом канале, ответ идет в рейтинг того канала, где он дан.\n"+
+			"Общие очки — участник отвечает один раз в любом из каналов, ответ идет в рейтинг канала вопроса."); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) publicationExpired(update *tgbotapi.Update, questionID int) error {
+	questionSetting := markup.QuestionSetting(questionID)
+	if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&questionSetting,
+		"Выбор каналов не найден, нажмите «Отправить вопрос в канал» ещё раз"); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package callback
+
+import (
+	"context"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CallbackQuestionLayout - qlayout_{question_id}
+func (c *callbackQuiz) CallbackQuestionLayout() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		return c.showQuestionLayout(ctx, update, id)
+	}
+}
+
+// CallbackQuestionButtonsPerRow - qrow_{question_id}_{buttons_per_row}, 0 follows the channel
+func (c *callbackQuiz) CallbackQuestionButtonsPerRow() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		value := GetThirdValue(update.CallbackData())
+		if id == 0 || value < entity.ButtonsPerRowAuto || value > entity.MaxButtonsPerRow {
+			c.log.Error("failed to get buttons per row from  button: %s", update.CallbackData())
+			return customErr.ErrNotFound
+		}
+
+		var buttonsPerRow *int
+		if value != 0 {
+			buttonsPerRow = &value
+		}
+		if err := c.quizService.UpdateQuestionButtonsPerRow(ctx, id, buttonsPerRow); err != nil {
+			c.log.Error("quizService.UpdateQuestionButtonsPerRow: %v", err)
+			return err
+		}
+
+		return c.showQuestionLayout(ctx, update, id)
+	}
+}
+
+// CallbackQuestionShuffle - qshuffle_{question_id}_{0|1|2}: the channel, shuffle, keep the order
+func (c *callbackQuiz) CallbackQuestionShuffle() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		value := GetThirdValue(update.CallbackData())
+		if id == 0 || value < 0 || value > 2 {
+			c.log.Error("failed to get shuffle from  button: %s", update.CallbackData())
+			return customErr.ErrNotFound
+		}
+
+		var shuffle *bool
+		if value != 0 {
+			on := value == 1
+			shuffle = &on
+		}
+		if err := c.quizService.UpdateQuestionShuffle(ctx, id, shuffle); err != nil {
+			c.log.Error("quizService.UpdateQuestionShuffle: %v", err)
+			return err
+		}
+
+		return c.showQuestionLayout(ctx, update, id)
+	}
+}
+
+func (c *callbackQuiz) showQuestionLayout(ctx context.Context, update *tgbotapi.Update, id int) error {
+	question, err := c.quizService.GetQuestionByID(ctx, id)
+	if err != nil {
+		c.log.Error("quizService.GetQuestionByID: %v", err)
+		return err
+	}
+
+	m := markup.QuestionLayout(question)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		markup.QuestionLayoutText(question)); err != nil {
+		return err
+	}
+
+	return nil
+}
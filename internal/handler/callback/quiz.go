@@ -1,6 +1,7 @@
 package callback
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,12 +11,14 @@ import (
 	service "github.com/Enthreeka/tg-bot-quiz/internal/usecase"
 	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/excel"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/export"
 	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/question_bank"
 	customMsg "github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"sync"
+	"io"
 	"time"
 )
 
@@ -24,10 +27,12 @@ const (
 	QuestionDELETE = "delete"
 )
 
-const jsonExample = "{\n \"варианты_ответы\": [\n  {\n   \"ответ\": \"Answer 123124\",\n   \"цена_ответа\": 10\n  },\n  {\n   \"ответ\": \"Answer e23fsdf\",\n   \"цена_ответа\": 20\n  },\n  {\n   \"ответ\": \"Answer 33249w8ueryfsd\",\n   \"цена_ответа\": 30\n  }\n ]\n}"
+const jsonExample = "{\n \"варианты_ответы\": [\n  {\n   \"ответ\": \"Answer 123124\",\n   \"цена_ответа\": 10\n  },\n  {\n   \"ответ\": \"Answer e23fsdf\",\n   \"цена_ответа\": 20\n  },\n  {\n   \"ответ\": \"Answer 33249w8ueryfsd\",\n   \"цена_ответа\": 30,\n   \"верный\": true\n  }\n ]\n}"
 
 const contextTimeout = 2 * time.Minute
 
+const success = "Операция выполнена успешно. "
+
 type CallbackQuiz interface {
 	CallbackCreateQuizQuestion() tgbot.ViewFunc
 	CallbackListQuestion() tgbot.ViewFunc
@@ -38,12 +43,87 @@ type CallbackQuiz interface {
 	CallbackCreateAnswer() tgbot.ViewFunc
 	CallbackUserResponse() tgbot.ViewFunc
 	CallbackSendQuizToChannel() tgbot.ViewFunc
+	CallbackSendQuizAnyway() tgbot.ViewFunc
+	CallbackSyncPost() tgbot.ViewFunc
+	CallbackPublishChannel() tgbot.ViewFunc
+	CallbackPublishScoring() tgbot.ViewFunc
+	CallbackPublishSend() tgbot.ViewFunc
+	CallbackCloneQuestion() tgbot.ViewFunc
+	CallbackCloneSelect() tgbot.ViewFunc
+	CallbackCloneToggle() tgbot.ViewFunc
+	CallbackCloneTarget() tgbot.ViewFunc
+	CallbackCloneInto() tgbot.ViewFunc
+	CallbackSeriesList() tgbot.ViewFunc
+	CallbackSeriesNew() tgbot.ViewFunc
+	CallbackSeriesToggle() tgbot.ViewFunc
+	CallbackSeriesInterval() tgbot.ViewFunc
+	CallbackSeriesWindow() tgbot.ViewFunc
+	CallbackSeriesGet() tgbot.ViewFunc
+	CallbackSeriesStart() tgbot.ViewFunc
+	CallbackSeriesPause() tgbot.ViewFunc
+	CallbackSeriesResume() tgbot.ViewFunc
+	CallbackSeriesSkip() tgbot.ViewFunc
+	CallbackSeriesReveal() tgbot.ViewFunc
+	CallbackSeriesDelete() tgbot.ViewFunc
+	CallbackSeriesRating() tgbot.ViewFunc
+	CallbackAutopilot() tgbot.ViewFunc
+	CallbackAutopilotToggle() tgbot.ViewFunc
+	CallbackAutopilotSchedule() tgbot.ViewFunc
+	CallbackAutopilotTag() tgbot.ViewFunc
+	CallbackAutopilotDifficulty() tgbot.ViewFunc
+	CallbackAutopilotThreshold() tgbot.ViewFunc
+	CallbackCronJobList() tgbot.ViewFunc
+	CallbackCronJobNew() tgbot.ViewFunc
+	CallbackCronJobKind() tgbot.ViewFunc
+	CallbackCronJobGet() tgbot.ViewFunc
+	CallbackCronJobPause() tgbot.ViewFunc
+	CallbackCronJobSchedule() tgbot.ViewFunc
+	CallbackCronJobDelete() tgbot.ViewFunc
+	CallbackChannelSettings() tgbot.ViewFunc
+	CallbackChannelButtonsPerRow() tgbot.ViewFunc
+	CallbackChannelShuffle() tgbot.ViewFunc
+	CallbackChannelPoints() tgbot.ViewFunc
+	CallbackChannelMembership() tgbot.ViewFunc
+	CallbackChannelAnswerChange() tgbot.ViewFunc
+	CallbackChannelDeadline() tgbot.ViewFunc
+	CallbackChannelFeedback() tgbot.ViewFunc
+	CallbackChannelSignature() tgbot.ViewFunc
+	CallbackChannelTimezone() tgbot.ViewFunc
 	CallbackAddImage() tgbot.ViewFunc
+	CallbackDeleteImage() tgbot.ViewFunc
 	CallbackUpdateQuestion() tgbot.ViewFunc
 	CallbackCancelUpdate() tgbot.ViewFunc
 	CallbackUpdateAnswers() tgbot.ViewFunc
 	CallbackGetUserResultExcelFile() tgbot.ViewFunc
 	CallbackResetRating() tgbot.ViewFunc
+	CallbackUndo() tgbot.ViewFunc
+	CallbackTrashList() tgbot.ViewFunc
+	CallbackTrashQuestion() tgbot.ViewFunc
+	CallbackTrashRestore() tgbot.ViewFunc
+	CallbackTrashPoints() tgbot.ViewFunc
+	CallbackTrashPurge() tgbot.ViewFunc
+	CallbackQuestionHistory() tgbot.ViewFunc
+	CallbackQuestionVersion() tgbot.ViewFunc
+	CallbackQuestionRollback() tgbot.ViewFunc
+	CallbackQuestionMeta() tgbot.ViewFunc
+	CallbackQuestionDifficulty() tgbot.ViewFunc
+	CallbackQuestionLayout() tgbot.ViewFunc
+	CallbackQuestionButtonsPerRow() tgbot.ViewFunc
+	CallbackQuestionShuffle() tgbot.ViewFunc
+	CallbackQuestionTags() tgbot.ViewFunc
+
+	ForwardCreateQuestion() tgbot.ForwardViewFunc
+	CallbackForwardChannel() tgbot.ViewFunc
+	CallbackImportQuestion() tgbot.ViewFunc
+	CallbackImportConfirm() tgbot.ViewFunc
+	CallbackExportQuestion() tgbot.ViewFunc
+	CallbackDownloadRating() tgbot.ViewFunc
+	CallbackRatingFilter() tgbot.ViewFunc
+	CallbackRatingToggleQuestion() tgbot.ViewFunc
+	CallbackSeasonExport() tgbot.ViewFunc
+	CallbackSeasonCompare() tgbot.ViewFunc
+	CallbackExportQuestionXLSX() tgbot.ViewFunc
+	CallbackExportQuestionJSON() tgbot.ViewFunc
 
 	//v2
 	CallbackGetChannelsV2() tgbot.ViewFunc
@@ -51,27 +131,42 @@ type CallbackQuiz interface {
 }
 
 type callbackQuiz struct {
-	quizService    service.QuizService
-	channelService service.ChannelService
-	log            *logger.Logger
-	store          store.LocalStorage
-	excel          *excel.Excel
-	tgMsg          customMsg.Message
-
-	mu sync.RWMutex
+	quizService      service.QuizService
+	channelService   service.ChannelService
+	publishService   service.PublishService
+	seasonService    service.SeasonService
+	seriesService    service.SeriesService
+	autopilotService service.AutopilotService
+	cronJobService   service.CronJobService
+	settingsService  service.ChannelSettingsService
+	log              *logger.Logger
+	store            store.LocalStorage
+	undo             *store.UndoStorage
+	excel            *excel.Excel
+	tgMsg            customMsg.Message
 }
 
 func NewCallbackQuiz(
 	quizService service.QuizService,
 	channelService service.ChannelService,
+	publishService service.PublishService,
+	seasonService service.SeasonService,
+	seriesService service.SeriesService,
+	autopilotService service.AutopilotService,
+	cronJobService service.CronJobService,
+	settingsService service.ChannelSettingsService,
 	log *logger.Logger,
 	store store.LocalStorage,
+	undo *store.UndoStorage,
 	tgMsg customMsg.Message,
 	excel *excel.Excel,
 ) (CallbackQuiz, error) {
 	if store == nil {
 		return nil, errors.New("store is nil")
 	}
+	if undo == nil {
+		return nil, errors.New("undo is nil")
+	}
 	if log == nil {
 		return nil, errors.New("logger is nil")
 	}
@@ -87,14 +182,39 @@ func NewCallbackQuiz(
 	if excel == nil {
 		return nil, errors.New("excel is nil")
 	}
+	if publishService == nil {
+		return nil, errors.New("publishService is nil")
+	}
+	if seasonService == nil {
+		return nil, errors.New("seasonService is nil")
+	}
+	if seriesService == nil {
+		return nil, errors.New("seriesService is nil")
+	}
+	if autopilotService == nil {
+		return nil, errors.New("autopilotService is nil")
+	}
+	if cronJobService == nil {
+		return nil, errors.New("cronJobService is nil")
+	}
+	if settingsService == nil {
+		return nil, errors.New("settingsService is nil")
+	}
 
 	return &callbackQuiz{
-		quizService:    quizService,
-		channelService: channelService,
-		log:            log,
-		store:          store,
-		tgMsg:          tgMsg,
-		excel:          excel,
+		quizService:      quizService,
+		channelService:   channelService,
+		publishService:   publishService,
+		seasonService:    seasonService,
+		seriesService:    seriesService,
+		autopilotService: autopilotService,
+		cronJobService:   cronJobService,
+		settingsService:  settingsService,
+		log:              log,
+		store:            store,
+		undo:             undo,
+		tgMsg:            tgMsg,
+		excel:            excel,
 	}, nil
 }
 
@@ -209,7 +329,7 @@ func (c *callbackQuiz) CallbackGetQuestion() tgbot.ViewFunc {
 		}
 
 		questionSetting := markup.QuestionSetting(id)
-		text := "Вопрос: " + question.QuestionName + "\n" + "Канал: " + channel.ChannelName
+		text := "Вопрос: " + question.HTML() + "\n" + "Канал: " + channel.ChannelName
 		_, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
 			update.CallbackQuery.Message.MessageID,
 			&questionSetting,
@@ -224,7 +344,7 @@ func (c *callbackQuiz) CallbackGetQuestion() tgbot.ViewFunc {
 
 // CallbackDeleteByIDQuestion - question_delete_{question_id}
 func (c *callbackQuiz) CallbackDeleteByIDQuestion() tgbot.ViewFunc {
-	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+	return c.withConfirmation(c.deleteQuestionConfirmation, func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
 		id := GetThirdValue(update.CallbackData())
 		if id == 0 {
 			c.log.Error("GetThirdValue: failed to get id from  button")
@@ -237,15 +357,17 @@ func (c *callbackQuiz) CallbackDeleteByIDQuestion() tgbot.ViewFunc {
 			return err
 		}
 
-		err = c.quizService.DeleteQuestion(ctx, id)
-		if err != nil {
-			c.log.Error("failed to get question by id: %v", err)
+		if err = c.quizService.DeleteQuestion(ctx, id); err != nil {
+			c.log.Error("failed to delete question: %v", err)
 			return err
 		}
 
-		m := markup.QuizSettingV2(int64(channelID))
+		m, note := c.offerUndo(update, markup.QuizSettingV2(int64(channelID)), fmt.Sprintf("question_get_%d", id),
+			func(ctx context.Context) error {
+				return c.quizService.RestoreQuestion(ctx, id)
+			})
 
-		text := "Управление ботом"
+		text := "Вопрос перемещен в корзину" + note
 		if _, err := c.tgMsg.SendEditMessage(
 			update.FromChat().ID,
 			update.CallbackQuery.Message.MessageID,
@@ -256,7 +378,39 @@ func (c *callbackQuiz) CallbackDeleteByIDQuestion() tgbot.ViewFunc {
 		}
 
 		return nil
+	})
+}
+
+func (c *callbackQuiz) deleteQuestionConfirmation(ctx context.Context, update *tgbotapi.Update) (*confirmation, error) {
+	id := GetThirdValue(update.CallbackData())
+	if id == 0 {
+		c.log.Error("GetThirdValue: failed to get id from  button")
+		return nil, customErr.ErrNotFound
+	}
+
+	quiz, err := c.quizService.GetQuizByQuestionID(ctx, id)
+	if err != nil {
+		c.log.Error("failed to get quiz by question id: %v", err)
+		return nil, err
 	}
+
+	participants := 0
+	if err = c.quizService.StreamQuestionStatisticsByChannelID(ctx, int(quiz.Question.ChannelID),
+		&entity.ExportFilter{QuestionIDs: []int{id}},
+		func(stat *entity.QuestionStatistics) error {
+			participants += stat.Participants
+			return nil
+		}); err != nil {
+		c.log.Error("failed to get question statistics: %v", err)
+		return nil, err
+	}
+
+	return &confirmation{
+		text: fmt.Sprintf("Вопрос будет перемещен в корзину: %s\n\nВариантов ответа: %d\nОтветивших участников: %d\n\n"+
+			"Очки за вопрос продолжат учитываться в рейтинге, это можно изменить в корзине",
+			quiz.Question.HTML(), len(quiz.Answer), participants),
+		cancel: fmt.Sprintf("channel_get_%d", quiz.Question.ChannelID),
+	}, nil
 }
 
 // CallbackCheckQuiz - quiz_check_{question_id}
@@ -274,7 +428,16 @@ func (c *callbackQuiz) CallbackCheckQuiz() tgbot.ViewFunc {
 			return err
 		}
 
-		if _, err = c.tgMsg.SendMessageToUser(update.FromChat().ID, quiz); err != nil {
+		// the preview shows the layout of the question, the answers are never shuffled in it
+		settings, err := c.settingsService.GetSettings(ctx, quiz.Question.ChannelID)
+		if err != nil {
+			c.log.Error("settingsService.GetSettings: %v", err)
+			return err
+		}
+		layout := settings.NewLayout(quiz.Question, quiz.Answer)
+		layout.AnswerOrder = nil
+
+		if _, err = c.tgMsg.SendMessageToUser(update.FromChat().ID, quiz, layout); err != nil {
 			return err
 		}
 
@@ -341,7 +504,41 @@ func (c *callbackQuiz) CallbackUserResponse() tgbot.ViewFunc {
 			return nil
 		}
 
-		isUserAnswerDomain := &entity.IsUserAnswer{AnswerID: id, UserID: update.CallbackQuery.From.ID}
+		var (
+			chatID    int64
+			messageID int
+		)
+		if msg := update.CallbackQuery.Message; msg != nil {
+			chatID, messageID = msg.Chat.ID, msg.MessageID
+		}
+
+		channelTgID, err := c.quizService.GetAnswerChannel(ctx, id, chatID, messageID)
+		if errors.Is(err, customErr.ErrNoRows) {
+			return c.answerCallback(bot, update, "Этот вариант ответа больше недоступен")
+		}
+		if err != nil {
+			c.log.Error("failed to get answer channel: %v", err)
+			return nil
+		}
+
+		settings, err := c.settingsService.GetSettings(ctx, channelTgID)
+		if err != nil {
+			c.log.Error("failed to get channel settings: %v", err)
+			return nil
+		}
+
+		if settings.RequireMembership {
+			isMember, err := c.tgMsg.IsChatMember(channelTgID, update.CallbackQuery.From.ID)
+			if err != nil {
+				c.log.Error("failed to check channel membership: %v", err)
+				return nil
+			}
+			if !isMember {
+				return c.answerCallback(bot, update, "Отвечать могут только подписчики канала")
+			}
+		}
+
+		isUserAnswerDomain := &entity.IsUserAnswer{AnswerID: id, UserID: update.CallbackQuery.From.ID, ChannelTgID: channelTgID}
 
 		isAnswerExist, err := c.quizService.IsUserAnswerExists(ctx, isUserAnswerDomain)
 		if err != nil {
@@ -353,8 +550,28 @@ func (c *callbackQuiz) CallbackUserResponse() tgbot.ViewFunc {
 		switch isAnswerExist {
 		case true:
 			text = "На данный вопрос вы уже отвечали!"
+			if settings.AnswerChange <= 0 {
+				break
+			}
+
+			costOfResponse, changed, err := c.quizService.ChangeUserResult(ctx, id, update.CallbackQuery.From.ID, channelTgID, settings.AnswerChange)
+			if errors.Is(err, customErr.ErrNoRows) {
+				text = "Этот вариант ответа больше недоступен"
+				break
+			}
+			if err != nil {
+				c.log.Error("failed to change user result: %v, update.Callback: %s", err, update.CallbackData())
+				return nil
+			}
+			if changed {
+				text = "Ответ изменён. " + settings.Feedback(costOfResponse)
+			}
 		case false:
-			costOfResponse, err := c.quizService.UpdateUserResult(ctx, id, update.CallbackQuery.From.ID)
+			costOfResponse, err := c.quizService.UpdateUserResult(ctx, id, update.CallbackQuery.From.ID, channelTgID, chatID)
+			if errors.Is(err, customErr.ErrNoRows) {
+				text = "Этот вариант ответа больше недоступен"
+				break
+			}
 			if err != nil {
 				c.log.Error("failed to update user result: %v, update.Callback: %s", err, update.CallbackData())
 				return nil
@@ -366,17 +583,20 @@ func (c *callbackQuiz) CallbackUserResponse() tgbot.ViewFunc {
 				}
 			}(ctx, isUserAnswerDomain)
 
-			text = fmt.Sprintf("За выбранный ответ вы получили баллов: %d", costOfResponse)
+			text = settings.Feedback(costOfResponse)
 		}
 
-		callback := tgbotapi.NewCallback(update.CallbackQuery.ID, text)
-		if _, err := bot.Request(callback); err != nil {
-			c.log.Error("failed to send callback message: %v", err)
-			return nil
-		}
+		return c.answerCallback(bot, update, text)
+	}
+}
 
-		return nil
+func (c *callbackQuiz) answerCallback(bot *tgbotapi.BotAPI, update *tgbotapi.Update, text string) error {
+	callback := tgbotapi.NewCallback(update.CallbackQuery.ID, text)
+	if _, err := bot.Request(callback); err != nil {
+		c.log.Error("failed to send callback message: %v", err)
 	}
+
+	return nil
 }
 
 // CallbackSendQuizToChannel - send_question_{question_id}
@@ -388,18 +608,114 @@ func (c *callbackQuiz) CallbackSendQuizToChannel() tgbot.ViewFunc {
 			return customErr.ErrNotFound
 		}
 
-		quiz, err := c.quizService.GetQuizByQuestionID(ctx, questionID)
+		publication, err := c.newPublication(ctx, update, questionID)
 		if err != nil {
-			c.log.Error("failed to get quiz by id: %v", err)
 			return err
 		}
 
-		if _, err = c.tgMsg.SendMessageToUser(quiz.Question.ChannelID, quiz); err != nil {
+		channels, err := c.channelService.GetAdminChannels(ctx)
+		if err != nil {
+			c.log.Error("channelService.GetAdminChannels: %v", err)
 			return err
 		}
+		// with a single channel there is nothing to choose
+		if len(channels) < 2 {
+			return c.publishWithPreflight(ctx, update, publication, false)
+		}
+
+		c.store.Set(&store.Data{
+			Data:          publication,
+			OperationType: store.QuizPublishTargets,
+			QuestionID:    questionID,
+		}, update.FromChat().ID)
+
+		return c.showPublishTargets(ctx, update, publication)
+	}
+}
+
+// CallbackSendQuizAnyway - send_anyway_{question_id}
+func (c *callbackQuiz) CallbackSendQuizAnyway() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		questionID := GetThirdValue(update.CallbackData())
+		if questionID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
 
-		if err = c.quizService.SetSendStatus(ctx, quiz.Question.ID); err != nil {
-			c.log.Error("failed to set quiz status: %v", err)
+		publication, ok := c.storedPublication(update, questionID)
+		if !ok {
+			var err error
+			if publication, err = c.newPublication(ctx, update, questionID); err != nil {
+				return err
+			}
+		}
+
+		return c.publishWithPreflight(ctx, update, publication, true)
+	}
+}
+
+// publishWithPreflight runs the preflight checks and publishes the question when
+// nothing blocks it. Warnings are skipped only when ignoreWarnings is set.
+func (c *callbackQuiz) publishWithPreflight(ctx context.Context, update *tgbotapi.Update, publication *entity.Publication, ignoreWarnings bool) error {
+	preflight, err := c.publishService.Preflight(ctx, publication)
+	if err != nil {
+		c.log.Error("publishService.Preflight: %v", err)
+		return err
+	}
+
+	text := PreflightToText(preflight)
+	if preflight.HasErrors() || (preflight.HasWarnings() && !ignoreWarnings) {
+		m := markup.PublishPreflight(publication.QuestionID, !preflight.HasErrors())
+		if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			text); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	result, err := c.publishService.Publish(ctx, publication)
+	if err != nil {
+		c.log.Error("publishService.Publish: %v", err)
+		return err
+	}
+
+	if _, ok := c.storedPublication(update, publication.QuestionID); ok {
+		c.store.Delete(update.FromChat().ID)
+	}
+
+	questionSetting := markup.QuestionSetting(publication.QuestionID)
+	if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&questionSetting,
+		text+"\n\n"+PublishResultToText(result)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CallbackSyncPost - sync_post_{question_id}
+func (c *callbackQuiz) CallbackSyncPost() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		questionID := GetThirdValue(update.CallbackData())
+		if questionID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		sync, err := c.publishService.SyncPosts(ctx, questionID)
+		if err != nil {
+			c.log.Error("publishService.SyncPosts: %v", err)
+			return err
+		}
+
+		questionSetting := markup.QuestionSetting(questionID)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&questionSetting,
+			PostSyncToText(sync)); err != nil {
 			return err
 		}
 
@@ -416,7 +732,7 @@ func (c *callbackQuiz) CallbackAddImage() tgbot.ViewFunc {
 			return customErr.ErrNotFound
 		}
 
-		text := "Отправьте изображение"
+		text := "Отправьте фото, видео, GIF, документ, аудио или голосовое сообщение"
 		cancelCommand := markup.CancelCommandQuestion(id)
 		sentMsg, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
 			update.CallbackQuery.Message.MessageID,
@@ -437,6 +753,75 @@ func (c *callbackQuiz) CallbackAddImage() tgbot.ViewFunc {
 	}
 }
 
+// CallbackDeleteImage - delete_image_{question_id}
+func (c *callbackQuiz) CallbackDeleteImage() tgbot.ViewFunc {
+	return c.withConfirmation(c.deleteImageConfirmation, func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		question, err := c.quizService.GetQuestionByID(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question by id: %v", err)
+			return err
+		}
+
+		text := "У вопроса нет медиа"
+		questionSetting := markup.QuestionSetting(id)
+		if question.FileID != nil {
+			if err = c.quizService.DeleteImage(ctx, id, update.FromChat().ID); err != nil {
+				c.log.Error("failed to delete question media: %v", err)
+				return err
+			}
+
+			if question.IsSend {
+				questionSetting = markup.WithSyncPost(questionSetting, id)
+			}
+
+			fileID, mediaType := *question.FileID, question.MediaType
+			var note string
+			questionSetting, note = c.offerUndo(update, questionSetting, fmt.Sprintf("question_get_%d", id),
+				func(ctx context.Context) error {
+					return c.quizService.UpdateImage(ctx, id, update.FromChat().ID, fileID, mediaType)
+				})
+			text = "Медиа удалено" + note
+		}
+
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&questionSetting,
+			text+"\n\nВопрос: "+question.HTML()); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (c *callbackQuiz) deleteImageConfirmation(ctx context.Context, update *tgbotapi.Update) (*confirmation, error) {
+	id := GetThirdValue(update.CallbackData())
+	if id == 0 {
+		c.log.Error("GetThirdValue: failed to get id from  button")
+		return nil, customErr.ErrNotFound
+	}
+
+	question, err := c.quizService.GetQuestionByID(ctx, id)
+	if err != nil {
+		c.log.Error("failed to get question by id: %v", err)
+		return nil, err
+	}
+	if question.FileID == nil {
+		return nil, nil
+	}
+
+	return &confirmation{
+		text:   fmt.Sprintf("Будет удалено медиа (%s) вопроса: %s", question.MediaType, question.HTML()),
+		cancel: fmt.Sprintf("question_get_%d", id),
+	}, nil
+}
+
 // CallbackUpdateQuestion - update_question_{question_id}
 func (c *callbackQuiz) CallbackUpdateQuestion() tgbot.ViewFunc {
 	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
@@ -485,7 +870,7 @@ func (c *callbackQuiz) CallbackCancelUpdate() tgbot.ViewFunc {
 		}
 
 		questionSetting := markup.QuestionSetting(id)
-		text := question.QuestionName
+		text := question.HTML()
 		_, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
 			update.CallbackQuery.Message.MessageID,
 			&questionSetting,
@@ -552,36 +937,285 @@ func (c *callbackQuiz) CallbackGetUserResultExcelFile() tgbot.ViewFunc {
 			c.log.Error("GetThirdValue: failed to get id from  button")
 			return customErr.ErrNotFound
 		}
+		c.store.Delete(update.FromChat().ID)
+
+		m := markup.RatingFilter(int64(channelID))
+		if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			"Какие ответы выгрузить?"); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackRatingFilter - rfilter_{all|current|answered|published|questions|seasons|done}_{channel_id}
+func (c *callbackQuiz) CallbackRatingFilter() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		filter := &entity.ExportFilter{Timezone: c.channelLocation(ctx, int64(channelID)).String()}
+		switch GetSecondValueString(update.CallbackData()) {
+		case "all":
+		case "current":
+			filter.CurrentSeason = true
+		case "answered", "published":
+			filter.DateField = entity.ExportByAnsweredAt
+			if GetSecondValueString(update.CallbackData()) == "published" {
+				filter.DateField = entity.ExportByPublishedAt
+			}
+			back := markup.RatingFilter(int64(channelID))
+			sentMsg, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+				update.CallbackQuery.Message.MessageID,
+				&back,
+				fmt.Sprintf("Отправьте период в формате 01.09.2024-30.09.2024 или одну дату 01.09.2024. "+
+					"Даты считаются в часовом поясе канала: %s", filter.Timezone))
+			if err != nil {
+				return err
+			}
+
+			c.store.Set(&store.Data{
+				Data:          filter,
+				CurrentMsgID:  sentMsg,
+				OperationType: store.RatingExportPeriod,
+				ChannelID:     channelID,
+			}, update.FromChat().ID)
+			return nil
+		case "seasons":
+			return c.sendSeasons(ctx, update, channelID)
+		case "questions":
+			c.store.Set(&store.Data{
+				Data:          filter,
+				OperationType: store.RatingExportQuestions,
+				ChannelID:     channelID,
+			}, update.FromChat().ID)
+			return c.sendRatingQuestions(ctx, update, channelID, filter)
+		case "done":
+			storeData, ok := c.store.Read(update.FromChat().ID)
+			if !ok || storeData.OperationType != store.RatingExportQuestions || storeData.ChannelID != channelID {
+				break
+			}
+			if filter, ok = storeData.Data.(*entity.ExportFilter); !ok {
+				c.log.Error("unexpected export filter type: %T", storeData.Data)
+				return customErr.ErrServerError
+			}
+			if len(filter.QuestionIDs) == 0 {
+				callback := tgbotapi.NewCallback(update.CallbackQuery.ID, "Выберите хотя бы один вопрос")
+				if _, err := bot.Request(callback); err != nil {
+					c.log.Error("failed to send callback message: %v", err)
+				}
+				return nil
+			}
+		default:
+			return customErr.ErrNotFound
+		}
+
+		c.store.Set(&store.Data{
+			Data:          filter,
+			OperationType: store.RatingExport,
+			ChannelID:     channelID,
+		}, update.FromChat().ID)
+
+		m := markup.RatingFormat(int64(channelID))
+		if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			markup.RatingFormatText(filter)); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+func (c *callbackQuiz) sendSeasons(ctx context.Context, update *tgbotapi.Update, channelID int) error {
+	seasons, err := c.seasonService.GetSeasonsByChannelID(ctx, int64(channelID))
+	if err != nil {
+		c.log.Error("seasonService.GetSeasonsByChannelID: %v", err)
+		return err
+	}
+
+	text := "Выберите сезон для выгрузки"
+	if len(seasons) == 0 {
+		text = "Закрытых сезонов пока нет. Сезон закрывается кнопкой «Обнулить рейтинг»"
+	}
+
+	m := markup.SeasonList(seasons, int64(channelID), c.channelLocation(ctx, int64(channelID)))
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		text); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CallbackSeasonExport - rseason_{season_id}
+func (c *callbackQuiz) CallbackSeasonExport() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		seasonID := GetSecondValue(update.CallbackData())
+		if seasonID == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
 
-		userResult, err := c.quizService.GetAllUserResultsByChannelID(ctx, channelID)
+		season, err := c.seasonService.GetSeasonByID(ctx, seasonID)
 		if err != nil {
-			c.log.Error("quizService.GetAllUserResults: failed to get contest: %v", err)
+			c.log.Error("seasonService.GetSeasonByID: %v", err)
 			return err
 		}
 
-		c.mu.Lock()
-		fileName, err := c.excel.GenerateUserResultsExcelFile(userResult, update.CallbackQuery.From.UserName)
+		filter := &entity.ExportFilter{
+			Season:   season.Number,
+			Timezone: c.channelLocation(ctx, season.ChannelTgID).String(),
+		}
+		c.store.Set(&store.Data{
+			Data:          filter,
+			OperationType: store.RatingExport,
+			ChannelID:     int(season.ChannelTgID),
+		}, update.FromChat().ID)
+
+		m := markup.RatingFormat(season.ChannelTgID)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			markup.RatingFormatText(filter)); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackSeasonCompare - season_compare_{channel_id}
+func (c *callbackQuiz) CallbackSeasonCompare() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		seasons, err := c.seasonService.GetSeasonsByChannelID(ctx, int64(channelID))
 		if err != nil {
-			c.log.Error("Excel.GenerateExcelFile: failed to generate excel file: %v", err)
+			c.log.Error("seasonService.GetSeasonsByChannelID: %v", err)
 			return err
 		}
 
-		fileIDBytes, err := c.excel.GetExcelFile(fileName)
+		var buf bytes.Buffer
+		if err = c.excel.WriteSeasonComparison(&buf, seasons, func(yield func(*entity.SeasonStanding) error) error {
+			return c.seasonService.StreamStandingsByChannelID(ctx, int64(channelID), yield)
+		}, c.channelLocation(ctx, int64(channelID))); err != nil {
+			c.log.Error("Excel.WriteSeasonComparison: %v", err)
+			return err
+		}
+
+		if _, err = c.tgMsg.SendDocument(update.FromChat().ID,
+			fmt.Sprintf("seasons_%d.xlsx", channelID),
+			&buf,
+			fmt.Sprintf("Сравнение сезонов: %d", len(seasons)),
+		); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackRatingToggleQuestion - rtoggle_{question_id}
+func (c *callbackQuiz) CallbackRatingToggleQuestion() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		questionID := GetSecondValue(update.CallbackData())
+		if questionID == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		storeData, ok := c.store.Read(update.FromChat().ID)
+		if !ok || storeData.OperationType != store.RatingExportQuestions {
+			return customErr.ErrNotFound
+		}
+		filter, ok := storeData.Data.(*entity.ExportFilter)
+		if !ok {
+			c.log.Error("unexpected export filter type: %T", storeData.Data)
+			return customErr.ErrServerError
+		}
+		filter.ToggleQuestion(questionID)
+
+		return c.sendRatingQuestions(ctx, update, storeData.ChannelID, filter)
+	}
+}
+
+func (c *callbackQuiz) sendRatingQuestions(ctx context.Context, update *tgbotapi.Update, channelID int, filter *entity.ExportFilter) error {
+	questions, err := c.quizService.GetAllQuestionsByChannelID(ctx, int64(channelID))
+	if err != nil {
+		c.log.Error("quizService.GetAllQuestionsByChannelID: %v", err)
+		return err
+	}
+
+	m := markup.RatingQuestionSelect(questions, filter, int64(channelID))
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		fmt.Sprintf("Отметьте вопросы для выгрузки. Выбрано: %d", len(filter.QuestionIDs))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CallbackDownloadRating - rating_{format}_{channel_id}
+func (c *callbackQuiz) CallbackDownloadRating() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		exporter, err := export.NewExporter(export.Format(GetSecondValueString(update.CallbackData())),
+			c.excel,
+			update.CallbackQuery.From.UserName)
 		if err != nil {
-			c.log.Error("Excel.GetExcelFile: failed to get excel file: %v", err)
+			c.log.Error("export.NewExporter: %v", err)
 			return err
 		}
-		c.mu.Unlock()
 
-		if fileIDBytes == nil {
-			c.log.Error("fileIDBytes is nil")
-			return errors.New("nil file")
+		// the filter stays in the store, so the same selection can be downloaded in another format
+		var filter *entity.ExportFilter
+		if storeData, ok := c.store.Read(update.FromChat().ID); ok &&
+			storeData.OperationType == store.RatingExport && storeData.ChannelID == channelID {
+			filter, _ = storeData.Data.(*entity.ExportFilter)
 		}
 
-		if _, err := c.tgMsg.SendDocument(update.FromChat().ID,
-			fileName,
-			fileIDBytes,
-			"Рейтинг пользователей",
+		var buf bytes.Buffer
+		if err = exporter.Export(&buf, export.Dataset{
+			Results: func(yield func(*entity.UserResult) error) error {
+				return c.quizService.StreamUserResultsByChannelID(ctx, channelID, filter, yield)
+			},
+			Leaderboard: func(yield func(*entity.LeaderboardRow) error) error {
+				return c.quizService.StreamLeaderboardByChannelID(ctx, channelID, filter, yield)
+			},
+			Statistics: func(yield func(*entity.QuestionStatistics) error) error {
+				return c.quizService.StreamQuestionStatisticsByChannelID(ctx, channelID, filter, yield)
+			},
+			Location: c.channelLocation(ctx, int64(channelID)),
+		}); err != nil {
+			c.log.Error("exporter.Export: failed to generate rating: %v", err)
+			return err
+		}
+
+		if _, err = c.tgMsg.SendDocument(update.FromChat().ID,
+			fmt.Sprintf("contest_results_%d.%s", channelID, exporter.Ext()),
+			&buf,
+			"Рейтинг пользователей, "+filter.String(),
 		); err != nil {
 			return err
 		}
@@ -592,28 +1226,137 @@ func (c *callbackQuiz) CallbackGetUserResultExcelFile() tgbot.ViewFunc {
 
 // CallbackResetRating - reset_rating_{channel_id}
 func (c *callbackQuiz) CallbackResetRating() tgbot.ViewFunc {
-	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+	return c.withConfirmation(c.resetRatingConfirmation, func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
 		id := GetThirdValue(update.CallbackData())
 		if id == 0 {
 			c.log.Error("GetThirdValue: failed to get id from  button")
 			return customErr.ErrNotFound
 		}
 
-		err := c.quizService.ResetAllUserResult(ctx, id)
+		season, err := c.seasonService.CloseSeason(ctx, int64(id))
 		if err != nil {
-			c.log.Error("failed to reset user result: %v", err)
+			c.log.Error("failed to close season: %v", err)
+			return err
+		}
+
+		m, note := c.offerUndo(update, markup.SeasonClosed(int64(id), season.ID), fmt.Sprintf("channel_get_%d", id),
+			func(ctx context.Context) error {
+				return c.seasonService.ReopenSeason(ctx, season.ID)
+			})
+
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			fmt.Sprintf("Сезон %d закрыт, итоги сохранены в архив. Участников: %d.\nНачался сезон %d, на вопросы можно отвечать заново",
+				season.Number, season.Participants, season.Number+1)+note); err != nil {
 			return err
 		}
 
-		go func(bot *tgbotapi.BotAPI, update *tgbotapi.Update) {
-			newCtx, cancel := context.WithTimeout(context.Background(), contextTimeout)
-			defer cancel()
+		return nil
+	})
+}
 
-			if err = c.CallbackGetUserResultExcelFile()(newCtx, bot, update); err != nil {
-				c.log.Error("failed to reset user result: %v", err)
+func (c *callbackQuiz) resetRatingConfirmation(ctx context.Context, update *tgbotapi.Update) (*confirmation, error) {
+	id := GetThirdValue(update.CallbackData())
+	if id == 0 {
+		c.log.Error("GetThirdValue: failed to get id from  button")
+		return nil, customErr.ErrNotFound
+	}
+
+	participants, points := 0, 0
+	if err := c.quizService.StreamLeaderboardByChannelID(ctx, id, &entity.ExportFilter{CurrentSeason: true},
+		func(row *entity.LeaderboardRow) error {
+			participants++
+			points += row.TotalPoints
+			return nil
+		}); err != nil {
+		c.log.Error("failed to get leaderboard: %v", err)
+		return nil, err
+	}
+
+	return &confirmation{
+		text: fmt.Sprintf("Текущий сезон будет закрыт, рейтинг начнется с нуля.\n\nУчастников: %d, набрано очков: %d. "+
+			"Итоги сохранятся в архиве сезонов, на опубликованные вопросы можно будет ответить заново", participants, points),
+		cancel: fmt.Sprintf("channel_get_%d", id),
+	}, nil
+}
+
+// ForwardCreateQuestion - a post or a poll forwarded to the bot
+func (c *callbackQuiz) ForwardCreateQuestion() tgbot.ForwardViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *customMsg.Update) error {
+		quiz, ok := ForwardToQuizModel(update)
+		if !ok {
+			return customErr.ErrUnsupportedMedia
+		}
+
+		channelsButton, err := c.channelService.GetAdminChannelMarkup(ctx, "forward")
+		if err != nil {
+			c.log.Error("failed to get all admin channels: %v", err)
+			return err
+		}
+
+		text := QuizToText(quiz)
+		if update.Message.Poll != nil && update.Message.Poll.Type != "quiz" {
+			text += "\n\nОпрос не является викториной: перед публикацией укажите баллы за верные ответы"
+		}
+		text += "\n\nВыберите канал, в котором создать вопрос"
+		sentMsg, err := c.tgMsg.SendNewMessage(update.FromChat().ID, channelsButton, text)
+		if err != nil {
+			return err
+		}
+
+		c.store.Set(&store.Data{
+			Data:          quiz,
+			CurrentMsgID:  sentMsg,
+			OperationType: store.QuizForward,
+		}, update.FromChat().ID)
+
+		return nil
+	}
+}
+
+// CallbackForwardChannel - channel_forward_{channel_tg_id}
+func (c *callbackQuiz) CallbackForwardChannel() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
 
+		storeData, ok := c.store.Read(update.FromChat().ID)
+		if !ok || storeData.OperationType != store.QuizForward {
+			if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+				update.CallbackQuery.Message.MessageID,
+				&markup.MainMenu,
+				"Черновик вопроса не найден, перешлите сообщение ещё раз"); err != nil {
+				return err
 			}
-		}(bot, update)
+			return nil
+		}
+
+		quiz, ok := storeData.Data.(*entity.Quiz)
+		if !ok {
+			c.log.Error("unexpected forward draft type: %T", storeData.Data)
+			return customErr.ErrServerError
+		}
+		quiz.Question.ChannelID = int64(channelID)
+		quiz.Question.CreatedByUser = update.FromChat().ID
+
+		questionID, err := c.quizService.CreateQuiz(ctx, quiz)
+		if err != nil {
+			c.log.Error("quizService.CreateQuiz: %v", err)
+			return err
+		}
+		c.store.Delete(update.FromChat().ID)
+
+		questionSetting := markup.QuestionSetting(questionID)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&questionSetting,
+			success+QuizToText(quiz)); err != nil {
+			return err
+		}
 
 		return nil
 	}
@@ -657,6 +1400,9 @@ func (c *callbackQuiz) CallbackGetChannelSettingV2() tgbot.ViewFunc {
 			return err
 		}
 
+		// leaving to the channel screen cancels any unfinished dialog
+		c.store.Delete(update.FromChat().ID)
+
 		m := markup.QuizSettingV2(int64(id))
 		text := "Управление каналом: " + ch.ChannelName
 
@@ -670,3 +1416,149 @@ func (c *callbackQuiz) CallbackGetChannelSettingV2() tgbot.ViewFunc {
 		return nil
 	}
 }
+
+// CallbackImportQuestion - import_question_{channel_id}
+func (c *callbackQuiz) CallbackImportQuestion() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		text := "Отправьте файл с вопросами в формате .xlsx, .csv или .json.\n\n" +
+			"Одна строка таблицы — один вариант ответа. Строки с одинаковым номером вопроса объединяются в один вопрос. " +
+			"Перед сохранением бот покажет отчёт проверки."
+		back := markup.BackToChannel(int64(id))
+		sentMsg, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&back,
+			text)
+		if err != nil {
+			return err
+		}
+
+		c.store.Set(&store.Data{
+			CurrentMsgID:  sentMsg,
+			PreferMsgID:   update.CallbackQuery.Message.MessageID,
+			OperationType: store.QuizImport,
+			ChannelID:     id,
+		}, update.FromChat().ID)
+
+		return nil
+	}
+}
+
+// CallbackImportConfirm - import_confirm_{channel_id}
+func (c *callbackQuiz) CallbackImportConfirm() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+		back := markup.BackToChannel(int64(id))
+
+		storeData, ok := c.store.Read(update.FromChat().ID)
+		if !ok || storeData.OperationType != store.QuizImportConfirm || storeData.ChannelID != id {
+			if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+				update.CallbackQuery.Message.MessageID,
+				&back,
+				"Данные импорта не найдены, отправьте файл ещё раз"); err != nil {
+				return err
+			}
+			return nil
+		}
+
+		quizzes, ok := storeData.Data.([]entity.Quiz)
+		if !ok {
+			c.log.Error("unexpected import draft type: %T", storeData.Data)
+			return customErr.ErrServerError
+		}
+
+		ids, err := c.quizService.ImportQuizzes(ctx, quizzes)
+		if err != nil {
+			c.log.Error("quizService.ImportQuizzes: %v", err)
+			return err
+		}
+		c.store.Delete(update.FromChat().ID)
+
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&back,
+			fmt.Sprintf("Импортировано вопросов: %d", len(ids))); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackExportQuestion - export_question_{channel_id}
+func (c *callbackQuiz) CallbackExportQuestion() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		m := markup.ExportQuestionFormat(int64(id))
+		if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			"Выберите формат файла. Его можно загрузить обратно через «Импорт вопросов»"); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackExportQuestionXLSX - bank_xlsx_{channel_id}
+func (c *callbackQuiz) CallbackExportQuestionXLSX() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		return c.exportQuestionBank(ctx, update, "xlsx", question_bank.EncodeXLSX)
+	}
+}
+
+// CallbackExportQuestionJSON - bank_json_{channel_id}
+func (c *callbackQuiz) CallbackExportQuestionJSON() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		return c.exportQuestionBank(ctx, update, "json", question_bank.EncodeJSON)
+	}
+}
+
+func (c *callbackQuiz) exportQuestionBank(ctx context.Context, update *tgbotapi.Update, ext string,
+	encode func(w io.Writer, questions []question_bank.Question) error) error {
+	channelID := GetThirdValue(update.CallbackData())
+	if channelID == 0 {
+		c.log.Error("GetThirdValue: failed to get id from  button")
+		return customErr.ErrNotFound
+	}
+
+	questions, err := c.quizService.ExportQuestionBank(ctx, int64(channelID))
+	if err != nil {
+		c.log.Error("quizService.ExportQuestionBank: %v", err)
+		return err
+	}
+	location := c.channelLocation(ctx, int64(channelID))
+	question_bank.InLocation(questions, location)
+
+	var buf bytes.Buffer
+	if err = encode(&buf, questions); err != nil {
+		c.log.Error("question_bank encode %s: %v", ext, err)
+		return err
+	}
+
+	fileName := fmt.Sprintf("questions_%d_%s.%s", channelID, time.Now().In(location).Format("2006-01-02"), ext)
+	if _, err = c.tgMsg.SendDocument(update.FromChat().ID,
+		fileName,
+		&buf,
+		fmt.Sprintf("Вопросы канала: %d", len(questions)),
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,380 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"strings"
+	"time"
+)
+
+// seriesIntervals are the minutes a question of a series can be open.
+var seriesIntervals = []int{1, 5, 10, 15, 30, 60}
+
+// seriesRatingTop is how many places the rating of a series shows in the bot.
+const seriesRatingTop = 20
+
+// CallbackSeriesList - series_list_{channel_id}
+func (c *callbackQuiz) CallbackSeriesList() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		return c.showSeriesList(ctx, update, int64(channelID), "")
+	}
+}
+
+// CallbackSeriesNew - series_new_{channel_id}
+func (c *callbackQuiz) CallbackSeriesNew() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		// coming back from the intervals keeps the questions already picked
+		draft, ok := c.storedSeriesDraft(update)
+		if !ok || draft.ChannelTgID != int64(channelID) {
+			draft = &entity.Series{ChannelTgID: int64(channelID), RevealResults: true}
+			c.store.Set(&store.Data{
+				Data:          draft,
+				OperationType: store.QuizSeries,
+				ChannelID:     channelID,
+			}, update.FromChat().ID)
+		}
+
+		return c.showSeriesQuestions(ctx, update, draft, "")
+	}
+}
+
+// CallbackSeriesToggle - series_toggle_{question_id}
+func (c *callbackQuiz) CallbackSeriesToggle() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		draft, ok := c.storedSeriesDraft(update)
+		if !ok {
+			return c.seriesDraftExpired(update)
+		}
+		draft.ToggleQuestion(id)
+
+		return c.showSeriesQuestions(ctx, update, draft, "")
+	}
+}
+
+// CallbackSeriesInterval - series_interval_{channel_id}
+func (c *callbackQuiz) CallbackSeriesInterval() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		draft, ok := c.storedSeriesDraft(update)
+		if !ok || draft.ChannelTgID != int64(channelID) {
+			return c.seriesDraftExpired(update)
+		}
+		if len(draft.QuestionIDs) == 0 {
+			return c.showSeriesQuestions(ctx, update, draft, "Отметьте хотя бы один вопрос\n\n")
+		}
+
+		m := markup.SeriesIntervals(seriesIntervals, draft.ChannelTgID)
+		if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			fmt.Sprintf("Вопросов в серии: %d\nСколько минут каждый вопрос принимает ответы?", len(draft.QuestionIDs))); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackSeriesWindow - series_window_{minutes}
+func (c *callbackQuiz) CallbackSeriesWindow() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		minutes := GetThirdValue(update.CallbackData())
+		if minutes <= 0 {
+			c.log.Error("GetThirdValue: failed to get minutes from  button")
+			return customErr.ErrNotFound
+		}
+
+		draft, ok := c.storedSeriesDraft(update)
+		if !ok || len(draft.QuestionIDs) == 0 {
+			return c.seriesDraftExpired(update)
+		}
+
+		draft.Interval = time.Duration(minutes) * time.Minute
+		draft.CreatedBy = update.FromChat().ID
+		draft.Title = fmt.Sprintf("Серия от %s", time.Now().In(c.channelLocation(ctx, draft.ChannelTgID)).Format("02.01.2006 15:04"))
+		if err := c.seriesService.CreateSeries(ctx, draft); err != nil {
+			c.log.Error("seriesService.CreateSeries: %v", err)
+			return err
+		}
+		c.store.Delete(update.FromChat().ID)
+
+		return c.showSeries(ctx, update, draft.ID, "Серия создана. ")
+	}
+}
+
+// CallbackSeriesGet - series_get_{series_id}
+func (c *callbackQuiz) CallbackSeriesGet() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		return c.showSeries(ctx, update, id, "")
+	}
+}
+
+// CallbackSeriesStart - series_start_{series_id}
+func (c *callbackQuiz) CallbackSeriesStart() tgbot.ViewFunc {
+	return c.seriesAction(c.seriesService.Start, "Серия запущена, первый вопрос опубликован. ")
+}
+
+// CallbackSeriesPause - series_pause_{series_id}
+func (c *callbackQuiz) CallbackSeriesPause() tgbot.ViewFunc {
+	return c.seriesAction(c.seriesService.Pause, "Серия на паузе, текущий вопрос продолжает принимать ответы. ")
+}
+
+// CallbackSeriesResume - series_resume_{series_id}
+func (c *callbackQuiz) CallbackSeriesResume() tgbot.ViewFunc {
+	return c.seriesAction(c.seriesService.Resume, "Серия продолжена. ")
+}
+
+// CallbackSeriesSkip - series_skip_{series_id}
+func (c *callbackQuiz) CallbackSeriesSkip() tgbot.ViewFunc {
+	return c.seriesAction(c.seriesService.Skip, "Текущий вопрос закрыт. ")
+}
+
+// CallbackSeriesReveal - series_reveal_{series_id}
+func (c *callbackQuiz) CallbackSeriesReveal() tgbot.ViewFunc {
+	return c.seriesAction(c.seriesService.ToggleReveal, "")
+}
+
+// CallbackSeriesDelete - series_delete_{series_id}
+func (c *callbackQuiz) CallbackSeriesDelete() tgbot.ViewFunc {
+	return c.withConfirmation(c.deleteSeriesConfirmation, func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		series, err := c.seriesService.GetSeriesByID(ctx, id)
+		if err != nil {
+			c.log.Error("seriesService.GetSeriesByID: %v", err)
+			return err
+		}
+
+		if err = c.seriesService.DeleteSeries(ctx, id); err != nil {
+			c.log.Error("seriesService.DeleteSeries: %v", err)
+			return err
+		}
+
+		return c.showSeriesList(ctx, update, series.ChannelTgID, "Серия удалена, опубликованные вопросы остались в канале\n\n")
+	})
+}
+
+func (c *callbackQuiz) deleteSeriesConfirmation(ctx context.Context, update *tgbotapi.Update) (*confirmation, error) {
+	id := GetThirdValue(update.CallbackData())
+	if id == 0 {
+		c.log.Error("GetThirdValue: failed to get id from  button")
+		return nil, customErr.ErrNotFound
+	}
+
+	series, err := c.seriesService.GetSeriesByID(ctx, id)
+	if err != nil {
+		c.log.Error("seriesService.GetSeriesByID: %v", err)
+		return nil, err
+	}
+
+	text := fmt.Sprintf("Серия «%s» будет удалена", html.EscapeString(series.Title))
+	if series.Status == entity.SeriesRunning || series.Status == entity.SeriesPaused {
+		text += ", оставшиеся вопросы не будут опубликованы"
+	}
+
+	return &confirmation{
+		text:   text,
+		cancel: fmt.Sprintf("series_get_%d", id),
+	}, nil
+}
+
+// CallbackSeriesRating - series_rating_{series_id}
+func (c *callbackQuiz) CallbackSeriesRating() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		series, err := c.seriesService.GetSeriesByID(ctx, id)
+		if err != nil {
+			c.log.Error("seriesService.GetSeriesByID: %v", err)
+			return err
+		}
+
+		var (
+			sb     strings.Builder
+			places int
+		)
+		sb.WriteString(fmt.Sprintf("Рейтинг серии «%s»:\n", html.EscapeString(series.Title)))
+		if series.StartedAt != nil {
+			if err = c.quizService.StreamLeaderboardByChannelID(ctx, int(series.ChannelTgID), series.LeaderboardFilter(),
+				func(row *entity.LeaderboardRow) error {
+					if places < seriesRatingTop {
+						sb.WriteString(fmt.Sprintf("\n%d. %s — %d (верных %d из %d)",
+							row.Rank, html.EscapeString(row.Name()), row.TotalPoints, row.CorrectAnswers, row.Answers))
+					}
+					places++
+					return nil
+				}); err != nil {
+				c.log.Error("quizService.StreamLeaderboardByChannelID: %v", err)
+				return err
+			}
+		}
+		if places == 0 {
+			sb.WriteString("\nОтветов пока нет")
+		} else if places > seriesRatingTop {
+			sb.WriteString(fmt.Sprintf("\n\nВсего участников: %d", places))
+		}
+
+		m := markup.Back(fmt.Sprintf("series_get_%d", id))
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			sb.String()); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// seriesAction runs a control of the series and shows the series after it.
+func (c *callbackQuiz) seriesAction(action func(ctx context.Context, id int) (*entity.Series, error), prefix string) tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		if _, err := action(ctx, id); err != nil {
+			c.log.Error("failed to change series %d: %v", id, err)
+			// a failed post pauses the series, the card shows the error
+			var botErr *customErr.BotError
+			if errors.As(err, &botErr) {
+				return err
+			}
+			return c.showSeries(ctx, update, id, "")
+		}
+
+		return c.showSeries(ctx, update, id, prefix)
+	}
+}
+
+func (c *callbackQuiz) showSeriesList(ctx context.Context, update *tgbotapi.Update, channelID int64, prefix string) error {
+	list, err := c.seriesService.GetSeriesByChannelID(ctx, channelID)
+	if err != nil {
+		c.log.Error("seriesService.GetSeriesByChannelID: %v", err)
+		return err
+	}
+
+	text := prefix + "Серии публикуют вопросы канала по очереди: каждый вопрос принимает ответы заданное время, " +
+		"затем закрывается и выходит следующий"
+	if len(list) == 0 {
+		text += "\n\nСерий пока нет"
+	}
+
+	m := markup.SeriesList(list, channelID)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		text); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) showSeries(ctx context.Context, update *tgbotapi.Update, id int, prefix string) error {
+	series, err := c.seriesService.GetSeriesByID(ctx, id)
+	if err != nil {
+		c.log.Error("seriesService.GetSeriesByID: %v", err)
+		return err
+	}
+
+	m := markup.SeriesSetting(series)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		prefix+SeriesToText(series, c.channelLocation(ctx, series.ChannelTgID))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) showSeriesQuestions(ctx context.Context, update *tgbotapi.Update, draft *entity.Series, prefix string) error {
+	questions, err := c.quizService.GetAllQuestionsByChannelID(ctx, draft.ChannelTgID)
+	if err != nil {
+		c.log.Error("quizService.GetAllQuestionsByChannelID: %v", err)
+		return err
+	}
+
+	m := markup.SeriesQuestionSelect(questions, draft)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		fmt.Sprintf("%sОтмечайте вопросы в том порядке, в котором они выйдут. Выбрано: %d", prefix, len(draft.QuestionIDs))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) storedSeriesDraft(update *tgbotapi.Update) (*entity.Series, bool) {
+	storeData, ok := c.store.Read(update.FromChat().ID)
+	if !ok || storeData.OperationType != store.QuizSeries {
+		return nil, false
+	}
+
+	draft, ok := storeData.Data.(*entity.Series)
+	if !ok {
+		c.log.Error("unexpected series draft type: %T", storeData.Data)
+	}
+	return draft, ok
+}
+
+func (c *callbackQuiz) seriesDraftExpired(update *tgbotapi.Update) error {
+	m := markup.Back("main_menu")
+	if _, err := c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		"Черновик серии не найден, начните заново"); err != nil {
+		return err
+	}
+
+	return nil
+}
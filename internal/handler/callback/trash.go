@@ -0,0 +1,204 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CallbackTrashList - trash_list_{channel_id}
+func (c *callbackQuiz) CallbackTrashList() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		channelID := GetThirdValue(update.CallbackData())
+		if channelID == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		return c.showTrashList(ctx, update, int64(channelID), "")
+	}
+}
+
+// CallbackTrashQuestion - trash_get_{question_id}
+func (c *callbackQuiz) CallbackTrashQuestion() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		return c.showTrashQuestion(ctx, update, id)
+	}
+}
+
+// CallbackTrashRestore - trash_restore_{question_id}
+func (c *callbackQuiz) CallbackTrashRestore() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		if err := c.quizService.RestoreQuestion(ctx, id); err != nil {
+			c.log.Error("failed to restore question: %v", err)
+			return err
+		}
+
+		question, err := c.quizService.GetQuestionByID(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question by id: %v", err)
+			return err
+		}
+
+		questionSetting := markup.QuestionSetting(id)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&questionSetting,
+			"Вопрос восстановлен из корзины\n\nВопрос: "+question.HTML()); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackTrashPoints - trash_points_{question_id}
+func (c *callbackQuiz) CallbackTrashPoints() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		question, err := c.quizService.GetQuestionByID(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question by id: %v", err)
+			return err
+		}
+
+		if err = c.quizService.SetCountPoints(ctx, id, !question.CountPoints); err != nil {
+			c.log.Error("failed to set count points: %v", err)
+			return err
+		}
+
+		return c.showTrashQuestion(ctx, update, id)
+	}
+}
+
+// CallbackTrashPurge - trash_purge_{question_id}
+func (c *callbackQuiz) CallbackTrashPurge() tgbot.ViewFunc {
+	return c.withConfirmation(c.purgeQuestionConfirmation, func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetThirdValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetThirdValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		snapshot, err := c.quizService.PurgeQuestion(ctx, id)
+		if err != nil {
+			c.log.Error("failed to purge question: %v", err)
+			return err
+		}
+
+		questions, err := c.quizService.GetDeletedQuestionsByChannelID(ctx, snapshot.ChannelTgID)
+		if err != nil {
+			c.log.Error("failed to get deleted questions: %v", err)
+			return err
+		}
+
+		m, note := c.offerUndo(update, markup.TrashList(questions, snapshot.ChannelTgID, c.channelLocation(ctx, snapshot.ChannelTgID)), fmt.Sprintf("trash_get_%d", id),
+			func(ctx context.Context) error {
+				return c.quizService.RestorePurgedQuestion(ctx, snapshot)
+			})
+
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			"Вопрос удален навсегда"+note); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (c *callbackQuiz) purgeQuestionConfirmation(ctx context.Context, update *tgbotapi.Update) (*confirmation, error) {
+	id := GetThirdValue(update.CallbackData())
+	if id == 0 {
+		c.log.Error("GetThirdValue: failed to get id from  button")
+		return nil, customErr.ErrNotFound
+	}
+
+	question, err := c.quizService.GetQuestionByID(ctx, id)
+	if err != nil {
+		c.log.Error("failed to get question by id: %v", err)
+		return nil, err
+	}
+
+	points := "Очки участников за вопрос пропадут из рейтинга"
+	if !question.CountPoints {
+		points = "Очки участников за вопрос уже не учитываются в рейтинге"
+	}
+
+	return &confirmation{
+		text: fmt.Sprintf("Вопрос будет удален навсегда вместе с вариантами ответа и ответами участников: %s\n\n%s",
+			question.HTML(), points),
+		cancel: fmt.Sprintf("trash_get_%d", id),
+	}, nil
+}
+
+func (c *callbackQuiz) showTrashList(ctx context.Context, update *tgbotapi.Update, channelID int64, prefix string) error {
+	questions, err := c.quizService.GetDeletedQuestionsByChannelID(ctx, channelID)
+	if err != nil {
+		c.log.Error("failed to get deleted questions: %v", err)
+		return err
+	}
+
+	text := fmt.Sprintf("Корзина, вопросов: %d", len(questions))
+	if len(questions) == 0 {
+		text = "Корзина пуста"
+	}
+
+	m := markup.TrashList(questions, channelID, c.channelLocation(ctx, channelID))
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		prefix+text); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *callbackQuiz) showTrashQuestion(ctx context.Context, update *tgbotapi.Update, id int) error {
+	question, err := c.quizService.GetQuestionByID(ctx, id)
+	if err != nil {
+		c.log.Error("failed to get question by id: %v", err)
+		return err
+	}
+	if question.DeletedAt == nil {
+		return c.showTrashList(ctx, update, question.ChannelID, "Вопрос уже восстановлен из корзины\n\n")
+	}
+
+	points := "учитываются в рейтинге"
+	if !question.CountPoints {
+		points = "не учитываются в рейтинге"
+	}
+
+	m := markup.TrashQuestion(question)
+	if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+		update.CallbackQuery.Message.MessageID,
+		&m,
+		fmt.Sprintf("Вопрос: %s\n\nУдален: %s\nОчки участников за вопрос: %s",
+			question.HTML(), question.DeletedAt.In(c.channelLocation(ctx, question.ChannelID)).Format("02.01.2006 15:04"), points)); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"strings"
+)
+
+const maxHistoryVersions = 20
+
+// CallbackQuestionHistory - qhistory_{question_id}
+func (c *callbackQuiz) CallbackQuestionHistory() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id := GetSecondValue(update.CallbackData())
+		if id == 0 {
+			c.log.Error("GetSecondValue: failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		question, err := c.quizService.GetQuestionByID(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question by id: %v", err)
+			return err
+		}
+
+		versions, err := c.quizService.GetQuestionVersions(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question versions: %v", err)
+			return err
+		}
+
+		location := c.channelLocation(ctx, question.ChannelID)
+
+		var sb strings.Builder
+		sb.WriteString("История изменений вопроса: " + question.HTML() + "\n\n")
+		if len(versions) == 0 {
+			sb.WriteString("Вопрос еще не изменяли")
+		}
+		if len(versions) > maxHistoryVersions {
+			versions = versions[:maxHistoryVersions]
+		}
+		for _, version := range versions {
+			sb.WriteString(fmt.Sprintf("Версия %d — %s, %s: %s\n", version.Version,
+				version.ChangedAt.In(location).Format("02.01.2006 15:04"), versionAuthor(&version), versionTitle(&version)))
+		}
+
+		m := markup.QuestionHistory(versions, id, location)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			sb.String()); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackQuestionVersion - qversion_{question_id}_{version}
+func (c *callbackQuiz) CallbackQuestionVersion() tgbot.ViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id, number := GetSecondValue(update.CallbackData()), GetThirdValue(update.CallbackData())
+		if id == 0 || number == 0 {
+			c.log.Error("failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		versions, err := c.quizService.GetQuestionVersions(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question versions: %v", err)
+			return err
+		}
+
+		key := findVersion(versions, number)
+		if key < 0 {
+			return customErr.ErrNotFound
+		}
+		version := versions[key]
+
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Версия %d — %s\n%s, %s\n\n", version.Version, versionTitle(&version),
+			version.ChangedAt.In(c.questionLocation(ctx, id)).Format("02.01.2006 15:04"), versionAuthor(&version)))
+		if key+1 < len(versions) {
+			sb.WriteString("Изменения:\n" + diffToText(version.Content.Diff(versions[key+1].Content)) + "\n")
+		}
+		sb.WriteString(QuizToText(&entity.Quiz{Question: version.Content.Question(), Answer: version.Content.Answers}))
+
+		m := markup.QuestionVersion(id, number, key > 0)
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&m,
+			sb.String()); err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// CallbackQuestionRollback - qrollback_{question_id}_{version}
+func (c *callbackQuiz) CallbackQuestionRollback() tgbot.ViewFunc {
+	return c.withConfirmation(c.rollbackConfirmation, func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error {
+		id, number := GetSecondValue(update.CallbackData()), GetThirdValue(update.CallbackData())
+		if id == 0 || number == 0 {
+			c.log.Error("failed to get id from  button")
+			return customErr.ErrNotFound
+		}
+
+		if err := c.quizService.RollbackQuestion(ctx, id, number, update.FromChat().ID); err != nil {
+			c.log.Error("failed to rollback question: %v", err)
+			return err
+		}
+
+		if err := c.publishService.RefreshPostKeyboards(ctx, id); err != nil {
+			c.log.Error("failed to refresh post keyboards: %v", err)
+		}
+
+		question, err := c.quizService.GetQuestionByID(ctx, id)
+		if err != nil {
+			c.log.Error("failed to get question by id: %v", err)
+			return err
+		}
+
+		questionSetting := markup.QuestionSetting(id)
+		if question.IsSend {
+			questionSetting = markup.WithSyncPost(questionSetting, id)
+		}
+		if _, err = c.tgMsg.SendEditMessage(update.FromChat().ID,
+			update.CallbackQuery.Message.MessageID,
+			&questionSetting,
+			fmt.Sprintf("Вопрос возвращен к версии %d\n\nВопрос: %s", number, question.HTML())); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (c *callbackQuiz) rollbackConfirmation(ctx context.Context, update *tgbotapi.Update) (*confirmation, error) {
+	id, number := GetSecondValue(update.CallbackData()), GetThirdValue(update.CallbackData())
+	if id == 0 || number == 0 {
+		c.log.Error("failed to get id from  button")
+		return nil, customErr.ErrNotFound
+	}
+
+	versions, err := c.quizService.GetQuestionVersions(ctx, id)
+	if err != nil {
+		c.log.Error("failed to get question versions: %v", err)
+		return nil, err
+	}
+
+	key := findVersion(versions, number)
+	if key < 0 {
+		return nil, customErr.ErrNotFound
+	}
+	target, current := versions[key].Content, versions[0].Content
+
+	text := fmt.Sprintf("Вопрос будет возвращен к версии %d. Изменения:\n%s", number, diffToText(target.Diff(current)))
+	if !target.SameAnswers(current) {
+		text += "\n\nКнопки опубликованных постов будут обновлены, уже полученные ответы участников и их очки сохранятся"
+	}
+
+	return &confirmation{
+		text:   text,
+		cancel: fmt.Sprintf("qversion_%d_%d", id, number),
+	}, nil
+}
+
+func findVersion(versions []entity.QuestionVersion, number int) int {
+	for key := range versions {
+		if versions[key].Version == number {
+			return key
+		}
+	}
+	return -1
+}
+
+func versionTitle(version *entity.QuestionVersion) string {
+	if version.Change == entity.VersionRollback && version.RolledBackTo != nil {
+		return fmt.Sprintf("%s к версии %d", version.Change.Title(), *version.RolledBackTo)
+	}
+	return version.Change.Title()
+}
+
+func versionAuthor(version *entity.QuestionVersion) string {
+	if version.ChangedByUsername != "" {
+		return "@" + html.EscapeString(version.ChangedByUsername)
+	}
+	return fmt.Sprintf("id %d", version.ChangedBy)
+}
+
+func diffToText(lines []string) string {
+	if len(lines) == 0 {
+		return "нет изменений"
+	}
+
+	escaped := make([]string, len(lines))
+	for key, line := range lines {
+		escaped[key] = html.EscapeString(line)
+	}
+	return strings.Join(escaped, "\n")
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Enthreeka/tg-bot-quiz/internal/handler/tgbot"
 	service "github.com/Enthreeka/tg-bot-quiz/internal/usecase"
 	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	customMsg "github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -68,3 +69,13 @@ func SuperAdminMiddleware(service service.UserService, next tgbot.ViewFunc) tgbo
 		return customErr.ErrIsNotAdmin
 	}
 }
+
+// AdminForwardMiddleware is AdminMiddleware for the forward view
+func AdminForwardMiddleware(service service.UserService, next tgbot.ForwardViewFunc) tgbot.ForwardViewFunc {
+	return func(ctx context.Context, bot *tgbotapi.BotAPI, update *customMsg.Update) error {
+		view := AdminMiddleware(service, func(ctx context.Context, bot *tgbotapi.BotAPI, _ *tgbotapi.Update) error {
+			return next(ctx, bot, update)
+		})
+		return view(ctx, bot, &update.Update)
+	}
+}
@@ -19,18 +19,26 @@ import (
 
 type ViewFunc func(ctx context.Context, bot *tgbotapi.BotAPI, update *tgbotapi.Update) error
 
+// ForwardViewFunc gets the update with the raw entities of the forwarded message
+type ForwardViewFunc func(ctx context.Context, bot *tgbotapi.BotAPI, update *customMsg.Update) error
+
 type Bot struct {
-	bot            *tgbotapi.BotAPI
-	log            *logger.Logger
-	store          store.LocalStorage
-	tgMsg          *customMsg.TelegramMsg
-	userService    service.UserService
-	channelService service.ChannelService
-	quizService    service.QuizService
-	callbackStore  *store.CallbackStorage
+	bot              *tgbotapi.BotAPI
+	log              *logger.Logger
+	store            store.LocalStorage
+	tgMsg            *customMsg.TelegramMsg
+	userService      service.UserService
+	channelService   service.ChannelService
+	quizService      service.QuizService
+	publishService   service.PublishService
+	autopilotService service.AutopilotService
+	cronJobService   service.CronJobService
+	settingsService  service.ChannelSettingsService
+	callbackStore    *store.CallbackStorage
 
 	cmdView      map[string]ViewFunc
 	callbackView map[string]ViewFunc
+	forwardView  ForwardViewFunc
 
 	mu      sync.RWMutex
 	isDebug bool
@@ -44,6 +52,10 @@ func NewBot(bot *tgbotapi.BotAPI,
 	quizService service.QuizService,
 	callbackStore *store.CallbackStorage,
 	channelService service.ChannelService,
+	publishService service.PublishService,
+	autopilotService service.AutopilotService,
+	cronJobService service.CronJobService,
+	settingsService service.ChannelSettingsService,
 ) (*Bot, error) {
 	if log == nil {
 		return nil, errors.New("log is nil")
@@ -66,16 +78,32 @@ func NewBot(bot *tgbotapi.BotAPI,
 	if quizService == nil {
 		return nil, errors.New("quizService is nil")
 	}
+	if publishService == nil {
+		return nil, errors.New("publishService is nil")
+	}
+	if autopilotService == nil {
+		return nil, errors.New("autopilotService is nil")
+	}
+	if cronJobService == nil {
+		return nil, errors.New("cronJobService is nil")
+	}
+	if settingsService == nil {
+		return nil, errors.New("settingsService is nil")
+	}
 
 	return &Bot{
-		bot:            bot,
-		log:            log,
-		store:          store,
-		tgMsg:          tgMsg,
-		userService:    userService,
-		quizService:    quizService,
-		callbackStore:  callbackStore,
-		channelService: channelService,
+		bot:              bot,
+		log:              log,
+		store:            store,
+		tgMsg:            tgMsg,
+		userService:      userService,
+		quizService:      quizService,
+		callbackStore:    callbackStore,
+		channelService:   channelService,
+		publishService:   publishService,
+		autopilotService: autopilotService,
+		cronJobService:   cronJobService,
+		settingsService:  settingsService,
 	}, nil
 }
 
@@ -96,18 +124,26 @@ func (b *Bot) RegisterCommandCallback(callback string, view ViewFunc) {
 	b.callbackView[callback] = view
 }
 
+// RegisterForwardView sets the view for messages forwarded to the bot in a private chat
+func (b *Bot) RegisterForwardView(view ForwardViewFunc) {
+	b.forwardView = view
+}
+
 func (b *Bot) Run(ctx context.Context) error {
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
-	updates := b.bot.GetUpdatesChan(u)
+	updates := b.tgMsg.GetUpdatesChan(ctx, u)
 	for {
 		select {
-		case update := <-updates:
+		case update, ok := <-updates:
+			if !ok {
+				return ctx.Err()
+			}
 			updateCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 
 			b.isDebug = false
-			b.jsonDebug(update)
+			b.jsonDebug(update.Update)
 
 			b.handlerUpdate(updateCtx, &update)
 
@@ -128,7 +164,8 @@ func (b *Bot) jsonDebug(update any) {
 	}
 }
 
-func (b *Bot) handlerUpdate(ctx context.Context, update *tgbotapi.Update) {
+func (b *Bot) handlerUpdate(ctx context.Context, rawUpdate *customMsg.Update) {
+	update := &rawUpdate.Update
 	defer func() {
 		if p := recover(); p != nil {
 			b.log.Error("panic recovered: %v, %s", p, string(debug.Stack()))
@@ -139,7 +176,7 @@ func (b *Bot) handlerUpdate(ctx context.Context, update *tgbotapi.Update) {
 	if update.Message != nil {
 		b.log.Info("[%s] %s", update.Message.From.UserName, update.Message.Text)
 
-		isProcessing, err := b.isStoreProcessing(ctx, update)
+		isProcessing, err := b.isStoreProcessing(ctx, rawUpdate)
 		if err != nil {
 			b.log.Error("failed in isStoreProcessing: %v", err)
 			handler.HandleError(b.bot, update, err)
@@ -155,6 +192,14 @@ func (b *Bot) handlerUpdate(ctx context.Context, update *tgbotapi.Update) {
 			return
 		}
 
+		if b.forwardView != nil && isForwardToBot(update.Message) {
+			if err := b.forwardView(ctx, b.bot, rawUpdate); err != nil {
+				b.log.Error("failed to handle FORWARD update: %v", err)
+				handler.HandleError(b.bot, update, err)
+			}
+			return
+		}
+
 		var view ViewFunc
 
 		cmd := update.Message.Command()
@@ -12,7 +12,7 @@ func userUpdateToModel(update *tgbotapi.Update) *entity.User {
 	if update != nil {
 		user.ID = update.Message.From.ID
 		user.TGUsername = update.Message.From.UserName
-		user.CreatedAt = time.Now().Local()
+		user.CreatedAt = time.Now().UTC()
 		user.UserRole = entity.UserType
 	}
 
@@ -33,3 +33,11 @@ func channelUpdateToModel(update *tgbotapi.Update) *entity.Channel {
 
 	return channel
 }
+
+func isForwardToBot(message *tgbotapi.Message) bool {
+	if message == nil || !message.Chat.IsPrivate() {
+		return false
+	}
+
+	return message.ForwardDate != 0 || message.Poll != nil
+}
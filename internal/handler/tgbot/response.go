@@ -2,10 +2,12 @@ package tgbot
 
 import (
 	"context"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
 	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"strings"
+	"time"
 )
 
 const (
@@ -60,8 +62,61 @@ func (b *Bot) responseText(storeData *store.Data) (string, *tgbotapi.InlineKeybo
 		}
 
 		questionSetting := markup.QuestionSetting(storeData.QuestionID)
-		text := question.QuestionName
+		if question.IsSend {
+			questionSetting = markup.WithSyncPost(questionSetting, storeData.QuestionID)
+		}
+		text := question.HTML()
 		return text, &questionSetting
+	case store.QuizUpdateTags:
+		question, err := b.quizService.GetQuestionByID(context.Background(), storeData.QuestionID)
+		if err != nil {
+			b.log.Error("failed to get question by id: %v", err)
+			return "", nil
+		}
+
+		questionMeta := markup.QuestionMeta(question)
+		return success + markup.QuestionMetaText(question), &questionMeta
+	case store.AutopilotSchedule, store.AutopilotTag:
+		autopilot, err := b.autopilotService.GetAutopilot(context.Background(), int64(storeData.ChannelID))
+		if err != nil {
+			b.log.Error("failed to get autopilot: %v", err)
+			return "", nil
+		}
+		unsent, err := b.autopilotService.CountUnsentQuestions(context.Background(), autopilot)
+		if err != nil {
+			b.log.Error("failed to count unsent questions: %v", err)
+		}
+
+		autopilotSetting := markup.AutopilotSetting(autopilot)
+		return success + markup.AutopilotText(autopilot, unsent), &autopilotSetting
+	case store.ChannelFeedback, store.ChannelSignature, store.ChannelTimezone:
+		settings, err := b.settingsService.GetSettings(context.Background(), int64(storeData.ChannelID))
+		if err != nil {
+			b.log.Error("failed to get channel settings: %v", err)
+			return "", nil
+		}
+		channel, err := b.channelService.GetByChannelID(context.Background(), int64(storeData.ChannelID))
+		if err != nil {
+			b.log.Error("failed to get channel: %v", err)
+			return "", nil
+		}
+
+		channelSettings := markup.ChannelSettings(settings, channel.Timezone)
+		return success + markup.ChannelSettingsText(settings, channel.Timezone), &channelSettings
+	case store.CronJobCreate, store.CronJobSchedule:
+		created, ok := storeData.Data.(*entity.CronJob)
+		if !ok {
+			return success, nil
+		}
+		job, err := b.cronJobService.GetCronJobByID(context.Background(), created.ID)
+		if err != nil {
+			b.log.Error("failed to get cron job: %v", err)
+			return "", nil
+		}
+
+		runs := b.cronJobService.UpcomingRuns([]entity.CronJob{*job}, time.Now(), markup.CronJobRuns)
+		jobSetting := markup.CronJobSetting(job)
+		return success + markup.CronJobText(job, runs), &jobSetting
 	}
 	return success, nil
 }
@@ -2,10 +2,18 @@ package tgbot
 
 import (
 	"context"
+	"fmt"
 	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/cron"
 	store "github.com/Enthreeka/tg-bot-quiz/pkg/local_storage"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/question_bank"
+	customMsg "github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/markup"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"strings"
 )
 
 func (b *Bot) isStateExist(userID int64) (*store.Data, bool) {
@@ -13,18 +21,22 @@ func (b *Bot) isStateExist(userID int64) (*store.Data, bool) {
 	return data, exist
 }
 
-func (b *Bot) isStoreProcessing(ctx context.Context, update *tgbotapi.Update) (bool, error) {
+func (b *Bot) isStoreProcessing(ctx context.Context, update *customMsg.Update) (bool, error) {
 	userID := update.Message.From.ID
 	storeData, isExist := b.isStateExist(userID)
 	if !isExist || storeData == nil {
 		return false, nil
 	}
-	defer b.store.Delete(userID)
+	// delete before handling, so the handler can set the next step of the dialog
+	b.store.Delete(userID)
 
-	return b.switchStoreData(ctx, update, storeData)
+	return b.switchStoreData(ctx, &update.Update, update.TextEntities(), storeData)
 }
 
-func (b *Bot) switchStoreData(ctx context.Context, update *tgbotapi.Update, storeData *store.Data) (bool, error) {
+// switchStoreData handles the reply of the dialog step, entities are the text
+// entities of the reply with custom emoji ids.
+func (b *Bot) switchStoreData(ctx context.Context, update *tgbotapi.Update, entities []coverter.MessageEntity,
+	storeData *store.Data) (bool, error) {
 	var (
 		err error
 	)
@@ -42,28 +54,81 @@ func (b *Bot) switchStoreData(ctx context.Context, update *tgbotapi.Update, stor
 		}
 
 	case store.QuizCreate:
-		if _, err = b.quizService.CreateQuestion(ctx, nil, &entity.Question{QuestionName: coverter.ConvertToMarkdownV2(update.Message.Text, update.Message.Entities),
-			CreatedByUser: update.FromChat().ID, ChannelID: int64(storeData.ChannelID)}); err != nil {
+		if _, err = b.quizService.CreateQuestion(ctx, nil, &entity.Question{QuestionName: update.Message.Text,
+			QuestionEntities: entities, CreatedByUser: update.FromChat().ID, ChannelID: int64(storeData.ChannelID)}); err != nil {
 			b.log.Error("isStoreExist::store.QuizCreate:CreateQuestion: %v", err)
 		}
 	case store.QuizUpdateAnswer:
-		if err = b.quizService.QuizUpdateAnswer(ctx, update.Message.Text, storeData.QuestionID); err != nil {
+		if err = b.quizService.QuizUpdateAnswer(ctx, update.Message.Text, storeData.QuestionID, update.FromChat().ID); err != nil {
 			b.log.Error("isStoreExist::store.QuizUpdateAnswer: %v", err)
+			break
 		}
+		b.refreshPostKeyboards(ctx, storeData.QuestionID)
 
 	case store.QuizUpdateImage:
-		if err = b.quizService.UpdateImage(ctx, storeData.QuestionID, update.Message.Photo[len(update.Message.Photo)-1].FileID); err != nil {
+		fileID, mediaType, ok := customMsg.MediaFromMessage(update.Message)
+		if !ok {
+			err = customErr.ErrUnsupportedMedia
+			break
+		}
+		if err = b.quizService.UpdateImage(ctx, storeData.QuestionID, update.FromChat().ID, fileID, mediaType); err != nil {
 			b.log.Error("isStoreExist::store.QuizUpdateImage: %v", err)
 		}
 
 	case store.QuizUpdateQuestion:
-		if err = b.quizService.UpdateQuestion(ctx, storeData.QuestionID, update.Message.Text); err != nil {
+		if err = b.quizService.UpdateQuestion(ctx, storeData.QuestionID, update.FromChat().ID, update.Message.Text, entities); err != nil {
 			b.log.Error("isStoreExist::store.QuizUpdateQuestion: %v", err)
 		}
 	case store.QuizUpdateOldAnswer:
-		if err = b.quizService.QuizUpdateOldAnswer(ctx, update.Message.Text, storeData.QuestionID); err != nil {
+		if err = b.quizService.QuizUpdateOldAnswer(ctx, update.Message.Text, storeData.QuestionID, update.FromChat().ID); err != nil {
 			b.log.Error("isStoreExist::store.QuizUpdateQuestion: %v", err)
+			break
+		}
+		b.refreshPostKeyboards(ctx, storeData.QuestionID)
+	case store.QuizUpdateTags:
+		if err = b.quizService.UpdateQuestionTags(ctx, storeData.QuestionID, entity.ParseTags(update.Message.Text)); err != nil {
+			b.log.Error("isStoreExist::store.QuizUpdateTags: %v", err)
+		}
+	case store.AutopilotSchedule:
+		if _, parseErr := cron.Parse(update.Message.Text); parseErr != nil {
+			return true, b.askAgain(update, storeData, "Не удалось разобрать расписание: "+html.EscapeString(parseErr.Error())+
+				". Отправьте пять полей через пробел, например <code>0 12 * * 1-5</code>")
+		}
+		if _, err = b.autopilotService.SetSchedule(ctx, int64(storeData.ChannelID), update.FromChat().ID, update.Message.Text); err != nil {
+			b.log.Error("isStoreExist::store.AutopilotSchedule: %v", err)
+		}
+	case store.AutopilotTag:
+		var tag *string
+		if tags := entity.ParseTags(update.Message.Text); len(tags) > 0 {
+			tag = &tags[0]
+		}
+		if _, err = b.autopilotService.SetTag(ctx, int64(storeData.ChannelID), update.FromChat().ID, tag); err != nil {
+			b.log.Error("isStoreExist::store.AutopilotTag: %v", err)
+		}
+	case store.CronJobCreate, store.CronJobSchedule:
+		return true, b.cronJobSchedule(ctx, update, storeData)
+	case store.ChannelTimezone:
+		if _, loadErr := entity.ParseLocation(update.Message.Text); loadErr != nil {
+			return true, b.askAgain(update, storeData,
+				"Такого часового пояса нет. Отправьте его как в базе IANA, например <code>Europe/Moscow</code>")
 		}
+		if err = b.channelService.SetTimezone(ctx, int64(storeData.ChannelID), update.Message.Text); err != nil {
+			b.log.Error("isStoreExist::store.ChannelTimezone: %v", err)
+		}
+	case store.ChannelFeedback:
+		if _, err = b.settingsService.SetFeedbackText(ctx, int64(storeData.ChannelID), update.FromChat().ID,
+			settingText(update.Message.Text)); err != nil {
+			b.log.Error("isStoreExist::store.ChannelFeedback: %v", err)
+		}
+	case store.ChannelSignature:
+		if _, err = b.settingsService.SetSignature(ctx, int64(storeData.ChannelID), update.FromChat().ID,
+			settingText(update.Message.Text)); err != nil {
+			b.log.Error("isStoreExist::store.ChannelSignature: %v", err)
+		}
+	case store.QuizImport:
+		return true, b.importQuestions(ctx, update, storeData)
+	case store.RatingExportPeriod:
+		return true, b.ratingExportPeriod(update, storeData)
 	default:
 		return false, nil
 	}
@@ -73,3 +138,143 @@ func (b *Bot) switchStoreData(ctx context.Context, update *tgbotapi.Update, stor
 	}
 	return true, err
 }
+
+// askAgain keeps the dialog step after a typo and tells the admin what was wrong.
+func (b *Bot) askAgain(update *tgbotapi.Update, storeData *store.Data, text string) error {
+	if _, err := b.tgMsg.SendNewMessage(update.FromChat().ID, nil, text); err != nil {
+		return err
+	}
+	b.store.Set(storeData, update.FromChat().ID)
+	return nil
+}
+
+// settingText is a text setting sent by the admin, nil for «-» which brings
+// back the default.
+func settingText(text string) *string {
+	text = strings.TrimSpace(text)
+	if text == "" || text == "-" {
+		return nil
+	}
+	return &text
+}
+
+// cronJobSchedule reads the schedule of a new job or of a job being changed.
+// On a typo the admin is asked again.
+func (b *Bot) cronJobSchedule(ctx context.Context, update *tgbotapi.Update, storeData *store.Data) error {
+	job, ok := storeData.Data.(*entity.CronJob)
+	if !ok {
+		b.log.Error("isStoreExist::store.%s: unexpected job type: %T", storeData.OperationType, storeData.Data)
+		return customErr.ErrServerError
+	}
+
+	schedule, err := cron.Parse(update.Message.Text)
+	if err != nil {
+		return b.askAgain(update, storeData, "Не удалось разобрать расписание: "+html.EscapeString(err.Error())+
+			". Отправьте пять полей через пробел, например <code>0 12 * * 1-5</code>")
+	}
+
+	if storeData.OperationType == store.CronJobCreate {
+		job.Schedule = schedule.String()
+		err = b.cronJobService.CreateCronJob(ctx, job)
+	} else {
+		_, err = b.cronJobService.SetSchedule(ctx, job.ID, schedule.String())
+	}
+	if err != nil {
+		b.log.Error("isStoreExist::store.%s: %v", storeData.OperationType, err)
+		return err
+	}
+
+	b.response(storeData, update)
+	return nil
+}
+
+// refreshPostKeyboards updates the buttons of published posts after the answers
+// were changed. The edit is already saved, so a failure is only logged.
+func (b *Bot) refreshPostKeyboards(ctx context.Context, questionID int) {
+	if err := b.publishService.RefreshPostKeyboards(ctx, questionID); err != nil {
+		b.log.Error("failed to refresh post keyboards: %v", err)
+	}
+}
+
+const maxReportErrors = 30
+
+// importQuestions parses the uploaded bank file and shows a dry-run report.
+// The questions are saved only after the admin confirms the import.
+func (b *Bot) importQuestions(ctx context.Context, update *tgbotapi.Update, storeData *store.Data) error {
+	document := update.Message.Document
+	if document == nil {
+		return question_bank.ErrUnknownFormat
+	}
+
+	data, err := b.tgMsg.DownloadFile(ctx, document.FileID)
+	if err != nil {
+		b.log.Error("isStoreExist::store.QuizImport:DownloadFile: %v", err)
+		return err
+	}
+
+	quizzes, rowErrors, err := b.quizService.ParseQuestionBank(document.FileName, data, int64(storeData.ChannelID), update.FromChat().ID)
+	if err != nil {
+		b.log.Error("isStoreExist::store.QuizImport:ParseQuestionBank: %v", err)
+		return err
+	}
+
+	answers := 0
+	for _, quiz := range quizzes {
+		answers += len(quiz.Answer)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Проверка файла %s\nВопросов к импорту: %d, ответов: %d\n",
+		html.EscapeString(document.FileName), len(quizzes), answers))
+	if len(rowErrors) > 0 {
+		sb.WriteString(fmt.Sprintf("\nОшибки (%d), такие вопросы будут пропущены:\n", len(rowErrors)))
+		for key, rowErr := range rowErrors {
+			if key == maxReportErrors {
+				sb.WriteString(fmt.Sprintf("… и ещё %d\n", len(rowErrors)-maxReportErrors))
+				break
+			}
+			sb.WriteString(html.EscapeString(rowErr.String()) + "\n")
+		}
+	}
+
+	confirm := markup.ImportConfirm(int64(storeData.ChannelID), len(quizzes))
+	if _, err = b.tgMsg.SendNewMessage(update.FromChat().ID, &confirm, sb.String()); err != nil {
+		return err
+	}
+
+	b.store.Set(&store.Data{
+		Data:          quizzes,
+		OperationType: store.QuizImportConfirm,
+		ChannelID:     storeData.ChannelID,
+	}, update.FromChat().ID)
+
+	return nil
+}
+
+// ratingExportPeriod reads the period of a filtered export and shows the
+// format choice. On a typo the admin is asked again.
+func (b *Bot) ratingExportPeriod(update *tgbotapi.Update, storeData *store.Data) error {
+	filter, ok := storeData.Data.(*entity.ExportFilter)
+	if !ok {
+		b.log.Error("isStoreExist::store.RatingExportPeriod: unexpected filter type: %T", storeData.Data)
+		return customErr.ErrServerError
+	}
+
+	if err := filter.SetPeriod(update.Message.Text); err != nil {
+		return b.askAgain(update, storeData,
+			"Не удалось разобрать период. Отправьте его в формате 01.09.2024-30.09.2024 или одну дату 01.09.2024")
+	}
+
+	b.store.Set(&store.Data{
+		Data:          filter,
+		OperationType: store.RatingExport,
+		ChannelID:     storeData.ChannelID,
+	}, update.FromChat().ID)
+
+	m := markup.RatingFormat(int64(storeData.ChannelID))
+	if _, err := b.tgMsg.SendNewMessage(update.FromChat().ID, &m, markup.RatingFormatText(filter)); err != nil {
+		return err
+	}
+
+	return nil
+}
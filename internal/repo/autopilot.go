@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/postgres"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type AutopilotRepo interface {
+	GetAutopilot(ctx context.Context, channelTgID int64) (*entity.Autopilot, error)
+	GetDueAutopilots(ctx context.Context, now time.Time) ([]entity.Autopilot, error)
+	SaveAutopilotSettings(ctx context.Context, autopilot *entity.Autopilot) error
+	UpdateAutopilotRun(ctx context.Context, autopilot *entity.Autopilot) error
+
+	PickUnsentQuestion(ctx context.Context, autopilot *entity.Autopilot) (int, error)
+	CountUnsentQuestions(ctx context.Context, autopilot *entity.Autopilot) (int, error)
+}
+
+type autopilotRepo struct {
+	*postgres.Postgres
+}
+
+func NewAutopilotRepo(pg *postgres.Postgres) (AutopilotRepo, error) {
+	if pg == nil {
+		return nil, errors.New("nil postgres")
+	}
+	return &autopilotRepo{
+		Postgres: pg,
+	}, nil
+}
+
+const autopilotColumns = `a.channel_tg_id, a.is_enabled, a.schedule, a.tag, a.difficulty, a.low_bank_threshold,
+			a.next_run_at, a.last_run_at, a.last_error, a.low_bank_warned_at, coalesce(a.updated_by, 0), c.timezone`
+
+func scanAutopilot(row pgx.Row, autopilot *entity.Autopilot) error {
+	return row.Scan(&autopilot.ChannelTgID,
+		&autopilot.Enabled,
+		&autopilot.Schedule,
+		&autopilot.Tag,
+		&autopilot.Difficulty,
+		&autopilot.LowBankThreshold,
+		&autopilot.NextRunAt,
+		&autopilot.LastRunAt,
+		&autopilot.LastError,
+		&autopilot.LowBankWarnedAt,
+		&autopilot.UpdatedBy,
+		&autopilot.Timezone,
+	)
+}
+
+func (a *autopilotRepo) GetAutopilot(ctx context.Context, channelTgID int64) (*entity.Autopilot, error) {
+	query := `SELECT ` + autopilotColumns + ` FROM autopilot a JOIN channel c ON c.tg_id = a.channel_tg_id
+			WHERE a.channel_tg_id = $1`
+
+	autopilot := new(entity.Autopilot)
+	if err := scanAutopilot(a.Pool.QueryRow(ctx, query, channelTgID), autopilot); err != nil {
+		return nil, ErrorHandler(err)
+	}
+	return autopilot, nil
+}
+
+func (a *autopilotRepo) GetDueAutopilots(ctx context.Context, now time.Time) ([]entity.Autopilot, error) {
+	query := `SELECT ` + autopilotColumns + ` FROM autopilot a JOIN channel c ON c.tg_id = a.channel_tg_id
+			WHERE a.is_enabled AND a.next_run_at <= $1 ORDER BY a.next_run_at`
+
+	rows, err := a.Pool.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.Autopilot
+	for rows.Next() {
+		var autopilot entity.Autopilot
+		if err := scanAutopilot(rows, &autopilot); err != nil {
+			return nil, err
+		}
+		list = append(list, autopilot)
+	}
+
+	return list, rows.Err()
+}
+
+// SaveAutopilotSettings creates or updates the settings of the channel
+// together with the next slot they give.
+func (a *autopilotRepo) SaveAutopilotSettings(ctx context.Context, autopilot *entity.Autopilot) error {
+	query := `INSERT INTO autopilot (channel_tg_id, is_enabled, schedule, tag, difficulty, low_bank_threshold,
+				next_run_at, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (channel_tg_id) DO UPDATE SET
+				is_enabled = excluded.is_enabled,
+				schedule = excluded.schedule,
+				tag = excluded.tag,
+				difficulty = excluded.difficulty,
+				low_bank_threshold = excluded.low_bank_threshold,
+				next_run_at = excluded.next_run_at,
+				updated_by = excluded.updated_by,
+				updated_at = now()`
+
+	_, err := a.Pool.Exec(ctx, query, autopilot.ChannelTgID, autopilot.Enabled, autopilot.Schedule, autopilot.Tag,
+		autopilot.Difficulty, autopilot.LowBankThreshold, autopilot.NextRunAt, autopilot.UpdatedBy)
+	return err
+}
+
+// UpdateAutopilotRun saves the outcome of a slot, the settings stay as they are.
+func (a *autopilotRepo) UpdateAutopilotRun(ctx context.Context, autopilot *entity.Autopilot) error {
+	query := `UPDATE autopilot SET next_run_at = $1, last_run_at = $2, last_error = $3, low_bank_warned_at = $4,
+				is_enabled = $5
+			WHERE channel_tg_id = $6`
+
+	_, err := a.Pool.Exec(ctx, query, autopilot.NextRunAt, autopilot.LastRunAt, autopilot.LastError,
+		autopilot.LowBankWarnedAt, autopilot.Enabled, autopilot.ChannelTgID)
+	return err
+}
+
+// unsentQuestions are the questions the autopilot can post: never sent, not
+// in the trash, with answers, matching the filter and not waiting in a series.
+// $1 is the channel, $2 the tag and $3 the difficulty.
+const unsentQuestions = `FROM questions q
+			WHERE q.channel_tg_id = $1
+			  AND NOT q.is_send
+			  AND q.deleted_at IS NULL
+			  AND ($2::text IS NULL OR $2::text = ANY(q.tags))
+			  AND ($3::smallint IS NULL OR q.difficulty = $3::smallint)
+			  AND EXISTS (SELECT 1 FROM answers a WHERE a.question_id = q.id AND NOT a.is_retired)
+			  AND NOT EXISTS (SELECT 1 FROM series_question sq
+								  JOIN series s ON s.id = sq.series_id
+							  WHERE sq.question_id = q.id AND s.status <> 'finished')`
+
+// PickUnsentQuestion returns a random question for the autopilot, ErrNoRows
+// when the bank is empty.
+func (a *autopilotRepo) PickUnsentQuestion(ctx context.Context, autopilot *entity.Autopilot) (int, error) {
+	query := `SELECT q.id ` + unsentQuestions + ` ORDER BY random() LIMIT 1`
+
+	var id int
+	err := a.Pool.QueryRow(ctx, query, autopilot.ChannelTgID, autopilot.Tag, autopilot.Difficulty).Scan(&id)
+	return id, ErrorHandler(err)
+}
+
+func (a *autopilotRepo) CountUnsentQuestions(ctx context.Context, autopilot *entity.Autopilot) (int, error) {
+	query := `SELECT count(*) ` + unsentQuestions
+
+	var count int
+	err := a.Pool.QueryRow(ctx, query, autopilot.ChannelTgID, autopilot.Tag, autopilot.Difficulty).Scan(&count)
+	return count, err
+}
@@ -19,6 +19,7 @@ type ChannelRepo interface {
 	GetChannelIDByChannelName(ctx context.Context, channelName string) (int64, error)
 	GetByChannelName(ctx context.Context, channelName string) (*entity.Channel, error)
 	GetByChannelID(ctx context.Context, channelID int64) (*entity.Channel, error)
+	UpdateTimezone(ctx context.Context, telegramID int64, timezone string) error
 	//GetChannelByUserID(ctx context.Context, userID int64) (string, error)
 }
 
@@ -36,9 +37,11 @@ func NewChannelRepo(pg *postgres.Postgres) (ChannelRepo, error) {
 	}, nil
 }
 
+const channelColumns = `id, tg_id, channel_name, channel_url, channel_status, timezone`
+
 func (u *channelRepo) collectRow(row pgx.Row) (*entity.Channel, error) {
 	var channel entity.Channel
-	err := row.Scan(&channel.ID, &channel.TgID, &channel.ChannelName, &channel.ChannelUrl, &channel.ChannelStatus)
+	err := row.Scan(&channel.ID, &channel.TgID, &channel.ChannelName, &channel.ChannelUrl, &channel.ChannelStatus, &channel.Timezone)
 	if checkErr := ErrorHandler(err); checkErr != nil {
 		return nil, checkErr
 	}
@@ -60,14 +63,14 @@ func (u *channelRepo) Create(ctx context.Context, channel *entity.Channel) error
 }
 
 func (u *channelRepo) GetByID(ctx context.Context, id int) (*entity.Channel, error) {
-	query := `select * from channel where id = $1`
+	query := `select ` + channelColumns + ` from channel where id = $1`
 
 	row := u.Pool.QueryRow(ctx, query, id)
 	return u.collectRow(row)
 }
 
 func (u *channelRepo) GetByChannelID(ctx context.Context, channelID int64) (*entity.Channel, error) {
-	query := `select * from channel where tg_id = $1`
+	query := `select ` + channelColumns + ` from channel where tg_id = $1`
 
 	row := u.Pool.QueryRow(ctx, query, channelID)
 	return u.collectRow(row)
@@ -81,7 +84,7 @@ func (u *channelRepo) DeleteByID(ctx context.Context, id int) error {
 }
 
 func (u *channelRepo) GetAll(ctx context.Context) ([]entity.Channel, error) {
-	query := `select * from channel`
+	query := `select ` + channelColumns + ` from channel`
 
 	rows, err := u.Pool.Query(ctx, query)
 	if err != nil {
@@ -101,6 +104,13 @@ func (u *channelRepo) UpdateStatusByTgID(ctx context.Context, status entity.Chan
 	return err
 }
 
+func (u *channelRepo) UpdateTimezone(ctx context.Context, telegramID int64, timezone string) error {
+	query := `update channel set timezone = $1 where tg_id = $2`
+
+	_, err := u.Pool.Exec(ctx, query, timezone, telegramID)
+	return err
+}
+
 func (u *channelRepo) IsChannelExistByTgID(ctx context.Context, telegramID int64) (bool, error) {
 	query := `select exists (select id from channel where tg_id = $1)`
 	var isExist bool
@@ -114,7 +124,7 @@ func (u *channelRepo) IsChannelExistByTgID(ctx context.Context, telegramID int64
 }
 
 func (u *channelRepo) GetAllAdminChannel(ctx context.Context) ([]entity.Channel, error) {
-	query := `select * from channel where channel_status = 'administrator'`
+	query := `select ` + channelColumns + ` from channel where channel_status = 'administrator'`
 
 	rows, err := u.Pool.Query(ctx, query)
 	if err != nil {
@@ -136,21 +146,10 @@ func (u *channelRepo) GetChannelIDByChannelName(ctx context.Context, channelName
 }
 
 func (u *channelRepo) GetByChannelName(ctx context.Context, channelName string) (*entity.Channel, error) {
-	query := `select * from channel where channel_name = $1`
-	channel := new(entity.Channel)
-
-	err := u.Pool.QueryRow(ctx, query, channelName).Scan(
-		&channel.ID,
-		&channel.ChannelName,
-		&channel.ChannelUrl,
-		&channel.ChannelStatus,
-		&channel.TgID,
-	)
-	if checkErr := ErrorHandler(err); checkErr != nil {
-		return channel, checkErr
-	}
+	query := `select ` + channelColumns + ` from channel where channel_name = $1`
 
-	return channel, err
+	row := u.Pool.QueryRow(ctx, query, channelName)
+	return u.collectRow(row)
 }
 
 //func (u *channelRepo) GetChannelByUserID(ctx context.Context, userID int64) (string, error) {
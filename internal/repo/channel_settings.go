@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/postgres"
+	"time"
+)
+
+type ChannelSettingsRepo interface {
+	GetChannelSettings(ctx context.Context, channelTgID int64) (*entity.ChannelSettings, error)
+	SaveChannelSettings(ctx context.Context, settings *entity.ChannelSettings) error
+}
+
+type channelSettingsRepo struct {
+	*postgres.Postgres
+}
+
+func NewChannelSettingsRepo(pg *postgres.Postgres) (ChannelSettingsRepo, error) {
+	if pg == nil {
+		return nil, errors.New("nil postgres")
+	}
+	return &channelSettingsRepo{
+		Postgres: pg,
+	}, nil
+}
+
+func (c *channelSettingsRepo) GetChannelSettings(ctx context.Context, channelTgID int64) (*entity.ChannelSettings, error) {
+	query := `SELECT channel_tg_id, buttons_per_row, shuffle_answers, feedback_text, show_points, require_membership,
+				answer_change_seconds, default_deadline_seconds, signature, coalesce(updated_by, 0)
+			FROM channel_settings WHERE channel_tg_id = $1`
+
+	var (
+		settings        = new(entity.ChannelSettings)
+		changeSeconds   int
+		deadlineSeconds int
+	)
+	err := c.Pool.QueryRow(ctx, query, channelTgID).Scan(&settings.ChannelTgID,
+		&settings.ButtonsPerRow,
+		&settings.ShuffleAnswers,
+		&settings.FeedbackText,
+		&settings.ShowPoints,
+		&settings.RequireMembership,
+		&changeSeconds,
+		&deadlineSeconds,
+		&settings.Signature,
+		&settings.UpdatedBy,
+	)
+	if err != nil {
+		return nil, ErrorHandler(err)
+	}
+	settings.AnswerChange = time.Duration(changeSeconds) * time.Second
+	settings.DefaultDeadline = time.Duration(deadlineSeconds) * time.Second
+
+	return settings, nil
+}
+
+// SaveChannelSettings creates or updates the settings of the channel.
+func (c *channelSettingsRepo) SaveChannelSettings(ctx context.Context, settings *entity.ChannelSettings) error {
+	query := `INSERT INTO channel_settings (channel_tg_id, buttons_per_row, shuffle_answers, feedback_text, show_points,
+				require_membership, answer_change_seconds, default_deadline_seconds, signature, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (channel_tg_id) DO UPDATE SET
+				buttons_per_row = excluded.buttons_per_row,
+				shuffle_answers = excluded.shuffle_answers,
+				feedback_text = excluded.feedback_text,
+				show_points = excluded.show_points,
+				require_membership = excluded.require_membership,
+				answer_change_seconds = excluded.answer_change_seconds,
+				default_deadline_seconds = excluded.default_deadline_seconds,
+				signature = excluded.signature,
+				updated_by = excluded.updated_by,
+				updated_at = now()`
+
+	_, err := c.Pool.Exec(ctx, query, settings.ChannelTgID, settings.ButtonsPerRow, settings.ShuffleAnswers,
+		settings.FeedbackText, settings.ShowPoints, settings.RequireMembership, int(settings.AnswerChange.Seconds()),
+		int(settings.DefaultDeadline.Seconds()), settings.Signature, settings.UpdatedBy)
+	return err
+}
@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/postgres"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type CronJobRepo interface {
+	CreateCronJob(ctx context.Context, job *entity.CronJob) error
+	GetCronJobByID(ctx context.Context, id int) (*entity.CronJob, error)
+	GetCronJobsByChannelID(ctx context.Context, channelTgID int64) ([]entity.CronJob, error)
+	GetDueCronJobs(ctx context.Context, now time.Time) ([]entity.CronJob, error)
+	UpdateCronJobSchedule(ctx context.Context, job *entity.CronJob) error
+	UpdateCronJobRun(ctx context.Context, job *entity.CronJob) error
+	DeleteCronJob(ctx context.Context, id int) error
+
+	NextUnsentQuestion(ctx context.Context, channelTgID int64) (int, error)
+	GetOpenQuestionIDs(ctx context.Context, channelTgID int64) ([]int, error)
+}
+
+type cronJobRepo struct {
+	*postgres.Postgres
+}
+
+func NewCronJobRepo(pg *postgres.Postgres) (CronJobRepo, error) {
+	if pg == nil {
+		return nil, errors.New("nil postgres")
+	}
+	return &cronJobRepo{
+		Postgres: pg,
+	}, nil
+}
+
+const cronJobColumns = `j.id, j.channel_tg_id, j.kind, j.schedule, j.is_paused, j.next_run_at, j.last_run_at, j.last_result,
+			j.is_last_failed, coalesce(j.created_by, 0), j.created_at, c.timezone`
+
+func scanCronJob(row pgx.Row, job *entity.CronJob) error {
+	return row.Scan(&job.ID,
+		&job.ChannelTgID,
+		&job.Kind,
+		&job.Schedule,
+		&job.Paused,
+		&job.NextRunAt,
+		&job.LastRunAt,
+		&job.LastResult,
+		&job.LastFailed,
+		&job.CreatedBy,
+		&job.CreatedAt,
+		&job.Timezone,
+	)
+}
+
+func (c *cronJobRepo) queryCronJobs(ctx context.Context, query string, args ...any) ([]entity.CronJob, error) {
+	rows, err := c.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.CronJob
+	for rows.Next() {
+		var job entity.CronJob
+		if err := scanCronJob(rows, &job); err != nil {
+			return nil, err
+		}
+		list = append(list, job)
+	}
+
+	return list, rows.Err()
+}
+
+func (c *cronJobRepo) CreateCronJob(ctx context.Context, job *entity.CronJob) error {
+	query := `INSERT INTO cron_job (channel_tg_id, kind, schedule, is_paused, next_run_at, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+
+	return c.Pool.QueryRow(ctx, query, job.ChannelTgID, job.Kind, job.Schedule, job.Paused, job.NextRunAt, job.CreatedBy).
+		Scan(&job.ID, &job.CreatedAt)
+}
+
+func (c *cronJobRepo) GetCronJobByID(ctx context.Context, id int) (*entity.CronJob, error) {
+	query := `SELECT ` + cronJobColumns + ` FROM cron_job j JOIN channel c ON c.tg_id = j.channel_tg_id WHERE j.id = $1`
+
+	job := new(entity.CronJob)
+	if err := scanCronJob(c.Pool.QueryRow(ctx, query, id), job); err != nil {
+		return nil, ErrorHandler(err)
+	}
+	return job, nil
+}
+
+func (c *cronJobRepo) GetCronJobsByChannelID(ctx context.Context, channelTgID int64) ([]entity.CronJob, error) {
+	query := `SELECT ` + cronJobColumns + ` FROM cron_job j JOIN channel c ON c.tg_id = j.channel_tg_id
+			WHERE j.channel_tg_id = $1 ORDER BY j.id`
+
+	return c.queryCronJobs(ctx, query, channelTgID)
+}
+
+func (c *cronJobRepo) GetDueCronJobs(ctx context.Context, now time.Time) ([]entity.CronJob, error) {
+	query := `SELECT ` + cronJobColumns + ` FROM cron_job j JOIN channel c ON c.tg_id = j.channel_tg_id
+			WHERE NOT j.is_paused AND j.next_run_at <= $1 ORDER BY j.next_run_at`
+
+	return c.queryCronJobs(ctx, query, now)
+}
+
+// UpdateCronJobSchedule saves the schedule, the pause and the next run they give.
+func (c *cronJobRepo) UpdateCronJobSchedule(ctx context.Context, job *entity.CronJob) error {
+	query := `UPDATE cron_job SET schedule = $1, is_paused = $2, next_run_at = $3 WHERE id = $4`
+
+	_, err := c.Pool.Exec(ctx, query, job.Schedule, job.Paused, job.NextRunAt, job.ID)
+	return err
+}
+
+// UpdateCronJobRun saves the outcome of a run together with the next run.
+func (c *cronJobRepo) UpdateCronJobRun(ctx context.Context, job *entity.CronJob) error {
+	query := `UPDATE cron_job SET next_run_at = $1, last_run_at = $2, last_result = $3, is_last_failed = $4, is_paused = $5
+			WHERE id = $6`
+
+	_, err := c.Pool.Exec(ctx, query, job.NextRunAt, job.LastRunAt, job.LastResult, job.LastFailed, job.Paused, job.ID)
+	return err
+}
+
+func (c *cronJobRepo) DeleteCronJob(ctx context.Context, id int) error {
+	query := `DELETE FROM cron_job WHERE id = $1`
+
+	_, err := c.Pool.Exec(ctx, query, id)
+	return err
+}
+
+// NextUnsentQuestion returns the oldest question the channel can post,
+// ErrNoRows when there is none.
+func (c *cronJobRepo) NextUnsentQuestion(ctx context.Context, channelTgID int64) (int, error) {
+	query := `SELECT q.id ` + unsentQuestions + ` ORDER BY q.id LIMIT 1`
+
+	var id int
+	err := c.Pool.QueryRow(ctx, query, channelTgID, nil, nil).Scan(&id)
+	return id, ErrorHandler(err)
+}
+
+// GetOpenQuestionIDs returns the posted questions of the channel that still
+// take answers. Questions of unfinished series are closed by the series.
+func (c *cronJobRepo) GetOpenQuestionIDs(ctx context.Context, channelTgID int64) ([]int, error) {
+	query := `SELECT q.id FROM questions q
+			WHERE q.channel_tg_id = $1
+			  AND q.is_send
+			  AND q.closed_at IS NULL
+			  AND q.deleted_at IS NULL
+			  AND NOT EXISTS (SELECT 1 FROM series_question sq
+								  JOIN series s ON s.id = sq.series_id
+							  WHERE sq.question_id = q.id AND s.status <> 'finished')
+			ORDER BY q.id`
+
+	rows, err := c.Pool.Query(ctx, query, channelTgID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
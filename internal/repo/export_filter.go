@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"strings"
+)
+
+// exportFilter holds the SQL conditions of an entity.ExportFilter. The
+// conditions start with " AND " and refer to user_results ur, questions q and
+// channel c, the channel the results count in. $1 is always the channel id.
+type exportFilter struct {
+	// questions narrows only the questions
+	questions string
+	// results narrows the questions and the answers of users
+	results string
+	args    []interface{}
+}
+
+func newExportFilter(channelID int, filter *entity.ExportFilter) exportFilter {
+	args := []interface{}{channelID}
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var questions, results []string
+	if filter != nil {
+		if len(filter.QuestionIDs) > 0 {
+			questions = append(questions, "q.id = ANY("+param(filter.QuestionIDs)+")")
+		}
+
+		column := "ur.answered_at"
+		if filter.DateField == entity.ExportByPublishedAt {
+			column = "q.published_at"
+		}
+		var dates []string
+		if filter.From != nil {
+			dates = append(dates, column+" >= "+param(*filter.From))
+		}
+		if filter.To != nil {
+			dates = append(dates, column+" < "+param(*filter.To))
+		}
+		if filter.DateField == entity.ExportByPublishedAt {
+			questions = append(questions, dates...)
+		} else {
+			results = append(results, dates...)
+		}
+
+		if filter.CurrentSeason {
+			results = append(results, "ur.season = c.current_season")
+		}
+		if filter.Season > 0 {
+			results = append(results, "ur.season = "+param(filter.Season))
+		}
+	}
+
+	// questions in the trash are left out, their results count until an admin
+	// turns the points of the question off
+	deleted := []string{"q.deleted_at IS NULL"}
+	counted := []string{"(q.deleted_at IS NULL OR q.count_points)"}
+
+	return exportFilter{
+		questions: conditions(append(deleted, questions...)),
+		results:   conditions(append(append(counted, questions...), results...)),
+		args:      args,
+	}
+}
+
+func conditions(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return " AND " + strings.Join(list, " AND ")
+}
@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/jackc/pgx/v5"
+)
+
+// questionContent builds entity.QuestionContent of questions q as jsonb.
+const questionContent = `jsonb_build_object(
+				'question_name', q.question_name,
+				'question_entities', q.question_entities,
+				'text_format', q.text_format,
+				'file_id', q.file_id,
+				'media_type', coalesce(q.media_type, ''),
+				'answers', coalesce((SELECT jsonb_agg(jsonb_build_object(
+							'answer', a.answer,
+							'cost_of_response', a.cost_of_response,
+							'is_correct', a.is_correct) ORDER BY a.position, a.id)
+						FROM answers a WHERE a.question_id = q.id AND NOT a.is_retired), '[]'))`
+
+// VersionedTx runs fn in a transaction that holds the lock on the question
+// row, so concurrent changes of the question get consecutive versions.
+func (q *quizRepo) VersionedTx(ctx context.Context, questionID int, fn func(tx pgx.Tx) error) (err error) {
+	query := `SELECT id FROM questions WHERE id = $1 FOR UPDATE`
+
+	tx, err := q.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	var id int
+	if err = tx.QueryRow(ctx, query, questionID).Scan(&id); err != nil {
+		if checkErr := ErrorHandler(err); checkErr != nil {
+			return checkErr
+		}
+		return err
+	}
+
+	return fn(tx)
+}
+
+// CreateBaselineVersion saves the current content as version 1 if the
+// question has no versions yet. It is called before the first tracked change.
+func (q *quizRepo) CreateBaselineVersion(ctx context.Context, tx pgx.Tx, questionID int) error {
+	query := `INSERT INTO question_version (question_id, version, changed_by, changed_at, change, content)
+			SELECT q.id, 1, q.created_by_user, coalesce(q.created_at, now()), $2::varchar, ` + questionContent + `
+			FROM questions q
+			WHERE q.id = $1 AND NOT EXISTS (SELECT 1 FROM question_version v WHERE v.question_id = q.id)
+			ON CONFLICT DO NOTHING`
+
+	_, err := tx.Exec(ctx, query, questionID, entity.VersionCreated)
+	return err
+}
+
+// CreateQuestionVersion saves the current content as the next version. Nothing
+// is saved if the content is the same as in the last version.
+func (q *quizRepo) CreateQuestionVersion(ctx context.Context, tx pgx.Tx, questionID int, changedBy int64, change entity.VersionChange, rolledBackTo int) error {
+	query := `WITH last AS (
+				SELECT version, content FROM question_version
+				WHERE question_id = $1
+				ORDER BY version DESC
+				LIMIT 1
+			),
+			current AS (
+				SELECT ` + questionContent + ` AS content
+				FROM questions q
+				WHERE q.id = $1
+			)
+			INSERT INTO question_version (question_id, version, changed_by, change, rolled_back_to, content)
+			SELECT $1::int, coalesce((SELECT version FROM last), 0) + 1, $2::bigint, $3::varchar, nullif($4::int, 0), current.content
+			FROM current
+			WHERE current.content IS DISTINCT FROM (SELECT content FROM last)`
+
+	_, err := tx.Exec(ctx, query, questionID, changedBy, change, rolledBackTo)
+	return err
+}
+
+// GetQuestionVersions returns the versions of the question, newest first.
+func (q *quizRepo) GetQuestionVersions(ctx context.Context, questionID int) ([]entity.QuestionVersion, error) {
+	query := `SELECT v.id, v.question_id, v.version, coalesce(v.changed_by, 0), coalesce(u.tg_username, ''),
+				v.changed_at, v.change, v.rolled_back_to, v.content
+			FROM question_version v
+					 LEFT JOIN "user" u ON u.id = v.changed_by
+			WHERE v.question_id = $1
+			ORDER BY v.version DESC`
+
+	rows, err := q.Pool.Query(ctx, query, questionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []entity.QuestionVersion
+	for rows.Next() {
+		var version entity.QuestionVersion
+		err := rows.Scan(&version.ID,
+			&version.QuestionID,
+			&version.Version,
+			&version.ChangedBy,
+			&version.ChangedByUsername,
+			&version.ChangedAt,
+			&version.Change,
+			&version.RolledBackTo,
+			&version.Content,
+		)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// RollbackQuestion writes content back to the question. Answers keep their
+// ids where possible, see syncAnswers.
+func (q *quizRepo) RollbackQuestion(ctx context.Context, tx pgx.Tx, questionID int, content *entity.QuestionContent) error {
+	queryQuestion := `UPDATE questions SET question_name = $1, question_entities = $2, text_format = $3,
+				file_id = $4, media_type = nullif($5, '')
+			WHERE id = $6`
+
+	if _, err := tx.Exec(ctx, queryQuestion, content.QuestionName, content.QuestionEntities, content.TextFormat,
+		content.FileID, content.MediaType, questionID); err != nil {
+		return err
+	}
+
+	return q.syncAnswers(ctx, tx, content.Answers, questionID)
+}
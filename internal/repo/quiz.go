@@ -3,32 +3,64 @@ package repo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/postgres"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
 	"github.com/jackc/pgx/v5"
+	"time"
 )
 
 type QuizRepo interface {
 	CreateQuestion(ctx context.Context, tx pgx.Tx, question *entity.Question) (int, error)
+	CreateQuiz(ctx context.Context, quiz *entity.Quiz) (int, error)
+	CreateQuizzes(ctx context.Context, quizzes []entity.Quiz) ([]int, error)
 	GetAllQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error)
+	GetAllQuizzesByChannelID(ctx context.Context, channelID int64) ([]entity.Quiz, error)
 	GetQuestionByID(ctx context.Context, id int) (*entity.Question, error)
-	UpdateQuestion(ctx context.Context, questionID int, question string) error
+	UpdateQuestion(ctx context.Context, tx pgx.Tx, questionID int, question string, entities []coverter.MessageEntity) error
 	DeleteQuestion(ctx context.Context, id int) error
-	UpdateImage(ctx context.Context, questionID int, image string) error
+	GetDeletedQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error)
+	RestoreQuestion(ctx context.Context, id int) error
+	SetCountPoints(ctx context.Context, id int, countPoints bool) error
+	PurgeQuestion(ctx context.Context, id int) (*entity.QuestionSnapshot, error)
+	RestorePurgedQuestion(ctx context.Context, snapshot *entity.QuestionSnapshot) error
+	UpdateImage(ctx context.Context, tx pgx.Tx, questionID int, fileID string, mediaType entity.MediaType) error
+	DeleteImage(ctx context.Context, tx pgx.Tx, questionID int) error
 	SetSendStatus(ctx context.Context, id int) error
+	CloseQuestion(ctx context.Context, id int) error
+	UpdateQuestionTags(ctx context.Context, id int, tags []string) error
+	UpdateQuestionDifficulty(ctx context.Context, id int, difficulty *int) error
+	UpdateQuestionButtonsPerRow(ctx context.Context, id int, buttonsPerRow *int) error
+	UpdateQuestionShuffle(ctx context.Context, id int, shuffle *bool) error
 	GetChannelTgIDByQuestionID(ctx context.Context, questionID int) (int, error)
+	SetDefaultDeadline(ctx context.Context, id int, deadline time.Time) error
+	GetOverdueQuestionIDs(ctx context.Context, now time.Time) ([]int, error)
 
 	CreateAnswers(ctx context.Context, tx pgx.Tx, answers []entity.Answer, questionID int) ([]int, error)
 	GetAnswerByID(ctx context.Context, id int) (int, int, error)
 	GetQuizByQuestionID(ctx context.Context, id int) (*entity.Quiz, error)
 	UpdateAnswer(ctx context.Context, answer *entity.Answer) error
-	DeleteAnswer(ctx context.Context, tx pgx.Tx, id int) error
 	IsAnswerExists(ctx context.Context, questionID int) (bool, error)
-	DeleteAndInsertNewAnswers(ctx context.Context, answers []entity.Answer, questionID int) error
+	UpdateAnswers(ctx context.Context, tx pgx.Tx, answers []entity.Answer, questionID int) error
 
+	CreatePublication(ctx context.Context, publication *entity.Publication) error
+	CreateQuestionPost(ctx context.Context, post *entity.QuestionPost) error
+	GetQuestionPosts(ctx context.Context, questionID int) ([]entity.QuestionPost, error)
+	UpdateQuestionPostFile(ctx context.Context, id int, fileID *string) error
+
+	VersionedTx(ctx context.Context, questionID int, fn func(tx pgx.Tx) error) error
+	CreateBaselineVersion(ctx context.Context, tx pgx.Tx, questionID int) error
+	CreateQuestionVersion(ctx context.Context, tx pgx.Tx, questionID int, changedBy int64, change entity.VersionChange, rolledBackTo int) error
+	GetQuestionVersions(ctx context.Context, questionID int) ([]entity.QuestionVersion, error)
+	RollbackQuestion(ctx context.Context, tx pgx.Tx, questionID int, content *entity.QuestionContent) error
+
+	GetAnswerChannel(ctx context.Context, answerID int, chatID int64, messageID int) (int64, error)
 	CreateUserResult(ctx context.Context, userResult *entity.UserResult) error
-	GetAllUserResultsByChannelID(ctx context.Context, channelID int) ([]entity.UserResult, error)
-	ResetAllUserResult(ctx context.Context, channelTgID int) error
+	ChangeUserResult(ctx context.Context, userResult *entity.UserResult, window time.Duration) (bool, error)
+	StreamUserResultsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(result *entity.UserResult) error) error
+	StreamLeaderboardByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(row *entity.LeaderboardRow) error) error
+	StreamQuestionStatisticsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(stat *entity.QuestionStatistics) error) error
 
 	CreateBooleanUserAnswer(ctx context.Context, answer *entity.IsUserAnswer) error
 	IsUserAnswerExists(ctx context.Context, userAnswer *entity.IsUserAnswer) (bool, error)
@@ -60,37 +92,170 @@ func (q *quizRepo) GetChannelTgIDByQuestionID(ctx context.Context, questionID in
 }
 
 func (q *quizRepo) SetSendStatus(ctx context.Context, id int) error {
-	query := `UPDATE questions SET is_send = true WHERE id = $1;`
+	// a new post has buttons, so the question takes answers again
+	query := `UPDATE questions SET is_send = true, published_at = coalesce(published_at, now()), closed_at = NULL WHERE id = $1;`
 
 	_, err := q.Pool.Exec(ctx, query, id)
 	return err
 }
 
+func (q *quizRepo) CloseQuestion(ctx context.Context, id int) error {
+	query := `UPDATE questions SET closed_at = coalesce(closed_at, now()) WHERE id = $1;`
+
+	_, err := q.Pool.Exec(ctx, query, id)
+	return err
+}
+
+// SetDefaultDeadline sets the deadline of a question that has none.
+func (q *quizRepo) SetDefaultDeadline(ctx context.Context, id int, deadline time.Time) error {
+	query := `UPDATE questions SET deadline = $1 WHERE id = $2 AND deadline IS NULL`
+
+	_, err := q.Pool.Exec(ctx, query, deadline, id)
+	return err
+}
+
+// GetOverdueQuestionIDs returns the open questions whose deadline has passed.
+// Questions of unfinished series are closed by the series.
+func (q *quizRepo) GetOverdueQuestionIDs(ctx context.Context, now time.Time) ([]int, error) {
+	query := `SELECT q.id FROM questions q
+			WHERE q.deadline <= $1
+			  AND q.closed_at IS NULL
+			  AND q.deleted_at IS NULL
+			  AND NOT EXISTS (SELECT 1 FROM series_question sq
+								  JOIN series s ON s.id = sq.series_id
+							  WHERE sq.question_id = q.id AND s.status <> 'finished')
+			ORDER BY q.deadline`
+
+	rows, err := q.Pool.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+func (q *quizRepo) UpdateQuestionTags(ctx context.Context, id int, tags []string) error {
+	query := `UPDATE questions SET tags = coalesce($1::text[], '{}') WHERE id = $2`
+
+	_, err := q.Pool.Exec(ctx, query, tags, id)
+	return err
+}
+
+func (q *quizRepo) UpdateQuestionDifficulty(ctx context.Context, id int, difficulty *int) error {
+	query := `UPDATE questions SET difficulty = $1 WHERE id = $2`
+
+	_, err := q.Pool.Exec(ctx, query, difficulty, id)
+	return err
+}
+
+func (q *quizRepo) UpdateQuestionButtonsPerRow(ctx context.Context, id int, buttonsPerRow *int) error {
+	query := `UPDATE questions SET buttons_per_row = $1 WHERE id = $2`
+
+	_, err := q.Pool.Exec(ctx, query, buttonsPerRow, id)
+	return err
+}
+
+func (q *quizRepo) UpdateQuestionShuffle(ctx context.Context, id int, shuffle *bool) error {
+	query := `UPDATE questions SET shuffle_answers = $1 WHERE id = $2`
+
+	_, err := q.Pool.Exec(ctx, query, shuffle, id)
+	return err
+}
+
 func (q *quizRepo) CreateQuestion(ctx context.Context, tx pgx.Tx, question *entity.Question) (int, error) {
-	query := `INSERT INTO questions (created_by_user, question_name, file_id, channel_tg_id) VALUES ($1, $2, $3, $4) RETURNING id`
+	query := `INSERT INTO questions (created_by_user, question_name, question_entities, text_format, file_id, media_type, channel_tg_id,
+				tags, difficulty, buttons_per_row, shuffle_answers)
+			VALUES ($1, $2, $3, coalesce(nullif($4, ''), 'entities'), $5, nullif($6, ''), $7, coalesce($8::text[], '{}'), $9,
+				$10, $11) RETURNING id`
+
+	args := []any{question.CreatedByUser, question.QuestionName, question.QuestionEntities, question.TextFormat,
+		question.FileID, question.MediaType, question.ChannelID, question.Tags, question.Difficulty,
+		question.ButtonsPerRow, question.ShuffleAnswers}
 
 	var err error
 	var id int
 	if tx == nil {
-		err = q.Pool.QueryRow(ctx, query, question.CreatedByUser, question.QuestionName, question.FileID, question.ChannelID).Scan(&id)
+		err = q.Pool.QueryRow(ctx, query, args...).Scan(&id)
 	} else {
-		err = tx.QueryRow(ctx, query, question.CreatedByUser, question.QuestionName, question.FileID, question.ChannelID).Scan(&id)
+		err = tx.QueryRow(ctx, query, args...).Scan(&id)
 	}
 
 	return id, err
 }
 
+func (q *quizRepo) CreateQuiz(ctx context.Context, quiz *entity.Quiz) (id int, err error) {
+	tx, err := q.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	if id, err = q.createQuiz(ctx, tx, quiz); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (q *quizRepo) CreateQuizzes(ctx context.Context, quizzes []entity.Quiz) (ids []int, err error) {
+	tx, err := q.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	ids = make([]int, 0, len(quizzes))
+	for key := range quizzes {
+		var id int
+		if id, err = q.createQuiz(ctx, tx, &quizzes[key]); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (q *quizRepo) createQuiz(ctx context.Context, tx pgx.Tx, quiz *entity.Quiz) (int, error) {
+	id, err := q.CreateQuestion(ctx, tx, &quiz.Question)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = q.CreateAnswers(ctx, tx, quiz.Answer, id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
 func (q *quizRepo) GetAllQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error) {
 	query := `SELECT 
     id,
     created_by_user,
     created_at,
     question_name,
+    question_entities,
+    text_format,
     file_id,
+    coalesce(media_type, ''),
     deadline,
     is_send
 	FROM questions
-	WHERE channel_tg_id = $1`
+	WHERE channel_tg_id = $1 AND deleted_at IS NULL`
 
 	rows, err := q.Pool.Query(ctx, query, channelID)
 	if err != nil {
@@ -105,7 +270,10 @@ func (q *quizRepo) GetAllQuestionsByChannelID(ctx context.Context, channelID int
 			&question.CreatedByUser,
 			&question.CreatedAt,
 			&question.QuestionName,
+			&question.QuestionEntities,
+			&question.TextFormat,
 			&question.FileID,
+			&question.MediaType,
 			&question.Deadline,
 			&question.IsSend,
 		)
@@ -115,19 +283,110 @@ func (q *quizRepo) GetAllQuestionsByChannelID(ctx context.Context, channelID int
 		questions = append(questions, question)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return questions, nil
+}
+
+// GetAllQuizzesByChannelID returns questions of the channel with their answers,
+// in creation order.
+func (q *quizRepo) GetAllQuizzesByChannelID(ctx context.Context, channelID int64) ([]entity.Quiz, error) {
+	queryQuestion := `SELECT
+    id,
+    created_by_user,
+    created_at,
+    question_name,
+    question_entities,
+    text_format,
+    file_id,
+    coalesce(media_type, ''),
+    deadline,
+    is_send,
+    tags,
+    difficulty,
+    buttons_per_row,
+    shuffle_answers
+	FROM questions
+	WHERE channel_tg_id = $1 AND deleted_at IS NULL
+	ORDER BY id`
+
+	queryAnswer := `SELECT a.id, a.answer, a.cost_of_response, a.is_correct, a.question_id FROM answers a
+					JOIN questions q ON q.id = a.question_id
+								WHERE q.channel_tg_id = $1 AND q.deleted_at IS NULL AND NOT a.is_retired
+								ORDER BY a.question_id, a.position, a.id`
+
+	rows, err := q.Pool.Query(ctx, queryQuestion, channelID)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
+	var quizzes []entity.Quiz
+	byID := make(map[int]int)
+	for rows.Next() {
+		var question entity.Question
+		err := rows.Scan(&question.ID,
+			&question.CreatedByUser,
+			&question.CreatedAt,
+			&question.QuestionName,
+			&question.QuestionEntities,
+			&question.TextFormat,
+			&question.FileID,
+			&question.MediaType,
+			&question.Deadline,
+			&question.IsSend,
+			&question.Tags,
+			&question.Difficulty,
+			&question.ButtonsPerRow,
+			&question.ShuffleAnswers,
+		)
+		if err != nil {
+			return nil, err
+		}
+		question.ChannelID = channelID
+		byID[question.ID] = len(quizzes)
+		quizzes = append(quizzes, entity.Quiz{Question: question})
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return questions, nil
+	answerRows, err := q.Pool.Query(ctx, queryAnswer, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer answerRows.Close()
+
+	for answerRows.Next() {
+		var answer entity.Answer
+		err := answerRows.Scan(&answer.ID, &answer.Answer, &answer.CostOfResponse, &answer.IsCorrect, &answer.QuestionID)
+		if err != nil {
+			return nil, err
+		}
+		if idx, ok := byID[answer.QuestionID]; ok {
+			quizzes[idx].Answer = append(quizzes[idx].Answer, answer)
+		}
+	}
+	if err := answerRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return quizzes, nil
+}
+
+func (q *quizRepo) UpdateImage(ctx context.Context, tx pgx.Tx, questionID int, fileID string, mediaType entity.MediaType) error {
+	query := `UPDATE questions SET file_id = $1, media_type = $2 WHERE id = $3`
+
+	_, err := tx.Exec(ctx, query, fileID, mediaType, questionID)
+	return err
 }
 
-func (q *quizRepo) UpdateImage(ctx context.Context, questionID int, image string) error {
-	query := `UPDATE questions SET file_id = $1 WHERE id= $2`
+func (q *quizRepo) DeleteImage(ctx context.Context, tx pgx.Tx, questionID int) error {
+	query := `UPDATE questions SET file_id = null, media_type = null WHERE id = $1`
 
-	_, err := q.Pool.Exec(ctx, query, image, questionID)
+	_, err := tx.Exec(ctx, query, questionID)
 	return err
 }
 
@@ -137,10 +396,20 @@ func (q *quizRepo) GetQuestionByID(ctx context.Context, id int) (*entity.Questio
     created_by_user,
     created_at,
     question_name,
+    question_entities,
+    text_format,
     file_id,
+    coalesce(media_type, ''),
     deadline,
     is_send,
-    channel_tg_id
+    channel_tg_id,
+    deleted_at,
+    count_points,
+    closed_at,
+    tags,
+    difficulty,
+    buttons_per_row,
+    shuffle_answers
 	FROM questions
 	WHERE id = $1`
 	question := new(entity.Question)
@@ -149,43 +418,175 @@ func (q *quizRepo) GetQuestionByID(ctx context.Context, id int) (*entity.Questio
 		&question.CreatedByUser,
 		&question.CreatedAt,
 		&question.QuestionName,
+		&question.QuestionEntities,
+		&question.TextFormat,
 		&question.FileID,
+		&question.MediaType,
 		&question.Deadline,
 		&question.IsSend,
 		&question.ChannelID,
+		&question.DeletedAt,
+		&question.CountPoints,
+		&question.ClosedAt,
+		&question.Tags,
+		&question.Difficulty,
+		&question.ButtonsPerRow,
+		&question.ShuffleAnswers,
 	)
 	return question, err
 }
 
-func (q *quizRepo) UpdateQuestion(ctx context.Context, questionID int, question string) error {
-	query := `UPDATE questions SET question_name = $1 WHERE id = $2`
+func (q *quizRepo) UpdateQuestion(ctx context.Context, tx pgx.Tx, questionID int, question string, entities []coverter.MessageEntity) error {
+	query := `UPDATE questions SET question_name = $1, question_entities = $2, text_format = 'entities' WHERE id = $3`
 
-	_, err := q.Pool.Exec(ctx, query, question, questionID)
+	_, err := tx.Exec(ctx, query, question, entities, questionID)
 	return err
 }
 
+// DeleteQuestion moves the question to the trash. Its answers and results
+// are kept until the question is purged.
 func (q *quizRepo) DeleteQuestion(ctx context.Context, id int) error {
-	query := `DELETE FROM questions WHERE id = $1`
+	query := `UPDATE questions SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
 
 	_, err := q.Pool.Exec(ctx, query, id)
 	return err
 }
 
-// Answer domain
+// GetDeletedQuestionsByChannelID returns the trash of the channel, recently
+// deleted first.
+func (q *quizRepo) GetDeletedQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error) {
+	query := `SELECT id, question_name, question_entities, text_format, deleted_at, count_points
+	FROM questions
+	WHERE channel_tg_id = $1 AND deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC`
 
-func (q *quizRepo) IsAnswerExists(ctx context.Context, questionID int) (bool, error) {
-	query := `select exists (select id from answers where question_id = $1)`
-	var isExist bool
+	rows, err := q.Pool.Query(ctx, query, channelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	err := q.Pool.QueryRow(ctx, query, questionID).Scan(&isExist)
-	if checkErr := ErrorHandler(err); checkErr != nil {
-		return isExist, checkErr
+	var questions []entity.Question
+	for rows.Next() {
+		question := entity.Question{ChannelID: channelID}
+		err := rows.Scan(&question.ID,
+			&question.QuestionName,
+			&question.QuestionEntities,
+			&question.TextFormat,
+			&question.DeletedAt,
+			&question.CountPoints,
+		)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
 	}
 
-	return isExist, err
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return questions, nil
+}
+
+// RestoreQuestion takes the question out of the trash.
+func (q *quizRepo) RestoreQuestion(ctx context.Context, id int) error {
+	query := `UPDATE questions SET deleted_at = null, count_points = true WHERE id = $1`
+
+	_, err := q.Pool.Exec(ctx, query, id)
+	return err
+}
+
+func (q *quizRepo) SetCountPoints(ctx context.Context, id int, countPoints bool) error {
+	query := `UPDATE questions SET count_points = $1 WHERE id = $2`
+
+	_, err := q.Pool.Exec(ctx, query, countPoints, id)
+	return err
 }
 
-func (q *quizRepo) DeleteAndInsertNewAnswers(ctx context.Context, answers []entity.Answer, questionID int) error {
+// PurgeQuestion deletes a question from the trash for good, together with its
+// answers, results and places in series, and returns the deleted rows for RestorePurgedQuestion.
+func (q *quizRepo) PurgeQuestion(ctx context.Context, id int) (snapshot *entity.QuestionSnapshot, err error) {
+	queryQuestion := `SELECT to_jsonb(q), q.channel_tg_id FROM questions q
+			WHERE q.id = $1 AND q.deleted_at IS NOT NULL FOR UPDATE`
+	queryAnswers := `SELECT coalesce(jsonb_agg(a ORDER BY a.id), '[]') FROM answers a WHERE a.question_id = $1`
+	queryResults := `SELECT coalesce(jsonb_agg(ur ORDER BY ur.id), '[]') FROM user_results ur WHERE ur.questions_id = $1`
+	queryUserAnswers := `SELECT coalesce(jsonb_agg(ua), '[]') FROM is_user_answer ua WHERE ua.question_id = $1`
+	queryVersions := `SELECT coalesce(jsonb_agg(v ORDER BY v.id), '[]') FROM question_version v WHERE v.question_id = $1`
+	queryPublications := `SELECT coalesce(jsonb_agg(p ORDER BY p.id), '[]') FROM publication p WHERE p.question_id = $1`
+	queryPosts := `SELECT coalesce(jsonb_agg(p ORDER BY p.id), '[]') FROM question_post p WHERE p.question_id = $1`
+	querySeries := `SELECT coalesce(jsonb_agg(s), '[]') FROM series_question s WHERE s.question_id = $1`
+	queryDelete := `DELETE FROM questions WHERE id = $1`
+
+	tx, err := q.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	snapshot = &entity.QuestionSnapshot{QuestionID: id}
+	if err = tx.QueryRow(ctx, queryQuestion, id).Scan(&snapshot.Question, &snapshot.ChannelTgID); err != nil {
+		return nil, ErrorHandler(err)
+	}
+	if err = tx.QueryRow(ctx, queryAnswers, id).Scan(&snapshot.Answers); err != nil {
+		return nil, err
+	}
+	if err = tx.QueryRow(ctx, queryResults, id).Scan(&snapshot.Results); err != nil {
+		return nil, err
+	}
+	if err = tx.QueryRow(ctx, queryUserAnswers, id).Scan(&snapshot.UserAnswers); err != nil {
+		return nil, err
+	}
+	if err = tx.QueryRow(ctx, queryVersions, id).Scan(&snapshot.Versions); err != nil {
+		return nil, err
+	}
+	if err = tx.QueryRow(ctx, queryPublications, id).Scan(&snapshot.Publications); err != nil {
+		return nil, err
+	}
+	if err = tx.QueryRow(ctx, queryPosts, id).Scan(&snapshot.Posts); err != nil {
+		return nil, err
+	}
+	if err = tx.QueryRow(ctx, querySeries, id).Scan(&snapshot.Series); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, queryDelete, id); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// RestorePurgedQuestion inserts the rows returned by PurgeQuestion back with
+// their original ids.
+func (q *quizRepo) RestorePurgedQuestion(ctx context.Context, snapshot *entity.QuestionSnapshot) (err error) {
+	queryQuestion := `INSERT INTO questions OVERRIDING SYSTEM VALUE
+			SELECT * FROM jsonb_populate_record(null::questions, $1)`
+	queryAnswers := `INSERT INTO answers OVERRIDING SYSTEM VALUE
+			SELECT * FROM jsonb_populate_recordset(null::answers, $1)`
+	queryResults := `INSERT INTO user_results OVERRIDING SYSTEM VALUE
+			SELECT * FROM jsonb_populate_recordset(null::user_results, $1)`
+	queryUserAnswers := `INSERT INTO is_user_answer
+			SELECT * FROM jsonb_populate_recordset(null::is_user_answer, $1)`
+	queryVersions := `INSERT INTO question_version OVERRIDING SYSTEM VALUE
+			SELECT * FROM jsonb_populate_recordset(null::question_version, $1)`
+	queryPublications := `INSERT INTO publication OVERRIDING SYSTEM VALUE
+			SELECT * FROM jsonb_populate_recordset(null::publication, $1)`
+	queryPosts := `INSERT INTO question_post OVERRIDING SYSTEM VALUE
+			SELECT * FROM jsonb_populate_recordset(null::question_post, $1)`
+	// series deleted since the purge are skipped
+	querySeries := `INSERT INTO series_question
+			SELECT * FROM jsonb_populate_recordset(null::series_question, $1) s
+			WHERE EXISTS (SELECT 1 FROM series WHERE id = s.series_id)
+			ON CONFLICT DO NOTHING`
+
 	tx, err := q.Pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return err
@@ -199,19 +600,132 @@ func (q *quizRepo) DeleteAndInsertNewAnswers(ctx context.Context, answers []enti
 		}
 	}()
 
-	if err = q.DeleteAnswer(ctx, tx, questionID); err != nil {
+	if _, err = tx.Exec(ctx, queryQuestion, snapshot.Question); err != nil {
 		return err
 	}
+	if _, err = tx.Exec(ctx, queryAnswers, snapshot.Answers); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, queryResults, snapshot.Results); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, queryUserAnswers, snapshot.UserAnswers); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, queryVersions, snapshot.Versions); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, queryPublications, snapshot.Publications); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, queryPosts, snapshot.Posts); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, querySeries, snapshot.Series); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Answer domain
+
+func (q *quizRepo) IsAnswerExists(ctx context.Context, questionID int) (bool, error) {
+	query := `select exists (select id from answers where question_id = $1 and not is_retired)`
+	var isExist bool
+
+	err := q.Pool.QueryRow(ctx, query, questionID).Scan(&isExist)
+	if checkErr := ErrorHandler(err); checkErr != nil {
+		return isExist, checkErr
+	}
+
+	return isExist, err
+}
+
+// UpdateAnswers replaces the answers of the question keeping the ids of the
+// answers that stay, so buttons of published posts keep working.
+func (q *quizRepo) UpdateAnswers(ctx context.Context, tx pgx.Tx, answers []entity.Answer, questionID int) error {
+	return q.syncAnswers(ctx, tx, answers, questionID)
+}
 
-	if _, err = q.CreateAnswers(ctx, tx, answers, questionID); err != nil {
+// syncAnswers makes answers the active answers of the question. Old answers
+// are matched by text, matched ones are updated in place, the rest of the new
+// ones are added with new ids and the rest of the old ones are retired, so
+// results always point to the text they were given for.
+func (q *quizRepo) syncAnswers(ctx context.Context, tx pgx.Tx, answers []entity.Answer, questionID int) error {
+	queryActive := `SELECT id, answer FROM answers WHERE question_id = $1 AND NOT is_retired ORDER BY position, id FOR UPDATE`
+	queryUpdate := `UPDATE answers SET answer = $1, cost_of_response = $2, is_correct = $3, position = $4 WHERE id = $5`
+	queryInsert := `INSERT INTO answers (answer, cost_of_response, is_correct, question_id, position) VALUES ($1, $2, $3, $4, $5)`
+	queryRetire := `UPDATE answers SET is_retired = true WHERE id = ANY($1)`
+
+	rows, err := tx.Query(ctx, queryActive, questionID)
+	if err != nil {
+		return err
+	}
+	var current []entity.Answer
+	for rows.Next() {
+		var answer entity.Answer
+		if err = rows.Scan(&answer.ID, &answer.Answer); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, answer)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
 		return err
 	}
 
+	matched, used := matchAnswers(current, answers)
+	for key, answer := range answers {
+		position := key + 1
+		if idx := matched[key]; idx >= 0 {
+			_, err = tx.Exec(ctx, queryUpdate, answer.Answer, answer.CostOfResponse, answer.IsCorrect, position, current[idx].ID)
+		} else {
+			_, err = tx.Exec(ctx, queryInsert, answer.Answer, answer.CostOfResponse, answer.IsCorrect, questionID, position)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var retired []int
+	for idx, answer := range current {
+		if !used[idx] {
+			retired = append(retired, answer.ID)
+		}
+	}
+	if len(retired) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, queryRetire, retired)
 	return err
 }
 
+// matchAnswers returns for every new answer the index of the old answer it
+// replaces or -1, and which old answers are taken.
+func matchAnswers(current, answers []entity.Answer) ([]int, []bool) {
+	matched := make([]int, len(answers))
+	used := make([]bool, len(current))
+
+	for key, answer := range answers {
+		matched[key] = -1
+		for idx, old := range current {
+			if !used[idx] && old.Answer == answer.Answer {
+				matched[key], used[idx] = idx, true
+				break
+			}
+		}
+	}
+
+	return matched, used
+}
+
 func (q *quizRepo) CreateAnswers(ctx context.Context, tx pgx.Tx, answers []entity.Answer, questionID int) ([]int, error) {
-	query := `INSERT INTO answers (answer, cost_of_response,question_id) VALUES ($1, $2,$3) RETURNING id`
+	query := `INSERT INTO answers (answer, cost_of_response, is_correct, question_id, position)
+			SELECT $1::varchar, $2::int, $3::boolean, $4::int, coalesce(max(position), 0) + 1 FROM answers WHERE question_id = $4
+			RETURNING id`
 	var newID []int
 
 	for _, value := range answers {
@@ -219,9 +733,9 @@ func (q *quizRepo) CreateAnswers(ctx context.Context, tx pgx.Tx, answers []entit
 		var err error
 
 		if tx != nil {
-			err = tx.QueryRow(ctx, query, value.Answer, value.CostOfResponse, questionID).Scan(&id)
+			err = tx.QueryRow(ctx, query, value.Answer, value.CostOfResponse, value.IsCorrect, questionID).Scan(&id)
 		} else {
-			err = q.Pool.QueryRow(ctx, query, value.Answer, value.CostOfResponse, questionID).Scan(&id)
+			err = q.Pool.QueryRow(ctx, query, value.Answer, value.CostOfResponse, value.IsCorrect, questionID).Scan(&id)
 
 		}
 
@@ -235,22 +749,29 @@ func (q *quizRepo) CreateAnswers(ctx context.Context, tx pgx.Tx, answers []entit
 }
 
 func (q *quizRepo) GetAnswerByID(ctx context.Context, id int) (int, int, error) {
-	query := `SELECT cost_of_response,question_id from answers WHERE id = $1`
+	// questions in the trash, closed or past the deadline questions and retired answers don't take answers
+	query := `SELECT a.cost_of_response, a.question_id FROM answers a
+				JOIN questions q ON q.id = a.question_id
+			WHERE a.id = $1 AND q.deleted_at IS NULL AND q.closed_at IS NULL AND NOT a.is_retired
+			  AND (q.deadline IS NULL OR q.deadline > now())`
 	var (
 		costOfResponse int
 		questionID     int
 	)
 
 	err := q.Pool.QueryRow(ctx, query, id).Scan(&costOfResponse, &questionID)
-	return costOfResponse, questionID, err
+	return costOfResponse, questionID, ErrorHandler(err)
 }
 
 func (q *quizRepo) GetQuizByQuestionID(ctx context.Context, id int) (*entity.Quiz, error) {
-	queryQuestion := `SELECT question_name, file_id, channel_tg_id FROM questions WHERE id = $1`
+	queryQuestion := `SELECT question_name, question_entities, text_format, file_id, coalesce(media_type, ''), channel_tg_id, deleted_at, closed_at,
+						tags, difficulty, buttons_per_row, shuffle_answers
+					FROM questions WHERE id = $1`
 
-	queryAnswer := `SELECT a.id, a.answer, a.cost_of_response FROM answers a
+	queryAnswer := `SELECT a.id, a.answer, a.cost_of_response, a.is_correct FROM answers a
 					JOIN questions q ON q.id = a.question_id
-								WHERE a.question_id = $1`
+								WHERE a.question_id = $1 AND NOT a.is_retired
+								ORDER BY a.position, a.id`
 
 	tx, err := q.Pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -268,8 +789,17 @@ func (q *quizRepo) GetQuizByQuestionID(ctx context.Context, id int) (*entity.Qui
 
 	if err = tx.QueryRow(ctx, queryQuestion, id).Scan(
 		&qu.Question.QuestionName,
+		&qu.Question.QuestionEntities,
+		&qu.Question.TextFormat,
 		&qu.Question.FileID,
+		&qu.Question.MediaType,
 		&qu.Question.ChannelID,
+		&qu.Question.DeletedAt,
+		&qu.Question.ClosedAt,
+		&qu.Question.Tags,
+		&qu.Question.Difficulty,
+		&qu.Question.ButtonsPerRow,
+		&qu.Question.ShuffleAnswers,
 	); err != nil {
 		return nil, err
 	}
@@ -283,7 +813,7 @@ func (q *quizRepo) GetQuizByQuestionID(ctx context.Context, id int) (*entity.Qui
 	var results []entity.Answer
 	for rows.Next() {
 		var result entity.Answer
-		err := rows.Scan(&result.ID, &result.Answer, &result.CostOfResponse)
+		err := rows.Scan(&result.ID, &result.Answer, &result.CostOfResponse, &result.IsCorrect)
 		if err != nil {
 			return nil, err
 		}
@@ -306,96 +836,360 @@ func (q *quizRepo) UpdateAnswer(ctx context.Context, answer *entity.Answer) erro
 	return err
 }
 
-func (q *quizRepo) DeleteAnswer(ctx context.Context, tx pgx.Tx, questionID int) error {
-	query := `DELETE FROM answers WHERE question_id = $1`
+// Post domain
 
-	var err error
-	if tx != nil {
-		_, err = tx.Exec(ctx, query, questionID)
-	} else {
-		_, err = q.Pool.Exec(ctx, query, questionID)
+func (q *quizRepo) CreatePublication(ctx context.Context, publication *entity.Publication) error {
+	query := `INSERT INTO publication (question_id, scoring, created_by) VALUES ($1, $2, $3) RETURNING id, created_at`
+
+	return q.Pool.QueryRow(ctx, query, publication.QuestionID, publication.Scoring, publication.CreatedBy).
+		Scan(&publication.ID, &publication.CreatedAt)
+}
+
+func (q *quizRepo) CreateQuestionPost(ctx context.Context, post *entity.QuestionPost) error {
+	query := `INSERT INTO question_post (question_id, chat_id, message_id, file_id, publication_id, buttons_per_row,
+				answer_order, signature)
+			VALUES ($1, $2, $3, $4, $5, $6, coalesce($7::int[], '{}'), $8)
+			ON CONFLICT (chat_id, message_id) DO NOTHING`
+
+	_, err := q.Pool.Exec(ctx, query, post.QuestionID, post.ChatID, post.MessageID, post.FileID, post.PublicationID,
+		post.Layout.ButtonsPerRow, post.Layout.AnswerOrder, post.Layout.Signature)
+	return err
+}
+
+func (q *quizRepo) GetQuestionPosts(ctx context.Context, questionID int) ([]entity.QuestionPost, error) {
+	query := `SELECT id, question_id, chat_id, message_id, file_id, publication_id, published_at, buttons_per_row,
+				answer_order, signature FROM question_post
+			WHERE question_id = $1
+			ORDER BY id`
+
+	rows, err := q.Pool.Query(ctx, query, questionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []entity.QuestionPost
+	for rows.Next() {
+		var post entity.QuestionPost
+		if err := rows.Scan(&post.ID, &post.QuestionID, &post.ChatID, &post.MessageID, &post.FileID,
+			&post.PublicationID, &post.PublishedAt, &post.Layout.ButtonsPerRow, &post.Layout.AnswerOrder,
+			&post.Layout.Signature); err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
+	return posts, nil
+}
+
+func (q *quizRepo) UpdateQuestionPostFile(ctx context.Context, id int, fileID *string) error {
+	query := `UPDATE question_post SET file_id = $1 WHERE id = $2`
+
+	_, err := q.Pool.Exec(ctx, query, fileID, id)
 	return err
 }
 
 // User result entity
 
+// GetAnswerChannel returns the channel whose rating an answer given on the post
+// counts in. Answers on posts of separate publications count in the channel of
+// the post, all other answers count in the channel of the question.
+func (q *quizRepo) GetAnswerChannel(ctx context.Context, answerID int, chatID int64, messageID int) (int64, error) {
+	query := `SELECT CASE WHEN p.scoring = 'separate' AND ch.tg_id IS NOT NULL THEN qp.chat_id ELSE q.channel_tg_id END
+			FROM answers a
+					 JOIN questions q ON q.id = a.question_id
+					 LEFT JOIN question_post qp ON qp.question_id = q.id AND qp.chat_id = $2 AND qp.message_id = $3
+					 LEFT JOIN publication p ON p.id = qp.publication_id
+					 LEFT JOIN channel ch ON ch.tg_id = qp.chat_id
+			WHERE a.id = $1`
+	var channelTgID int64
+
+	err := q.Pool.QueryRow(ctx, query, answerID, chatID, messageID).Scan(&channelTgID)
+	return channelTgID, ErrorHandler(err)
+}
+
 func (q *quizRepo) CreateUserResult(ctx context.Context, userResult *entity.UserResult) error {
 	//query := `INSERT INTO user_results (user_id,total_points) VALUES ($1, $2)
 	//		ON CONFLICT (user_id) DO UPDATE SET
 	//			total_points = user_results.total_points + $2`
 
-	query := `INSERT INTO user_results (user_id,points,questions_id) VALUES ($1, $2, $3)`
+	query := `INSERT INTO user_results (user_id,points,questions_id,answer_id,channel_tg_id,chat_id,season) VALUES ($1, $2, $3, $4, $5, $6,
+				(SELECT c.current_season FROM channel c WHERE c.tg_id = $5))`
 
-	_, err := q.Pool.Exec(ctx, query, userResult.UserID, userResult.Points, userResult.QuestionID)
+	_, err := q.Pool.Exec(ctx, query, userResult.UserID, userResult.Points, userResult.QuestionID, userResult.AnswerID,
+		userResult.ChannelTgID, userResult.ChatID)
 	return err
 }
 
-func (q *quizRepo) GetAllUserResultsByChannelID(ctx context.Context, channelID int) ([]entity.UserResult, error) {
-	query := `SELECT
+// ChangeUserResult moves the answer of the user in the current season to
+// another answer of the same question when the first answer was given within
+// the window. It reports false when there is no such answer to change.
+func (q *quizRepo) ChangeUserResult(ctx context.Context, userResult *entity.UserResult, window time.Duration) (bool, error) {
+	query := `UPDATE user_results r SET answer_id = $1, points = $2
+			FROM channel c
+			WHERE c.tg_id = r.channel_tg_id
+			  AND r.user_id = $3
+			  AND r.questions_id = $4
+			  AND r.channel_tg_id = $5
+			  AND r.season = c.current_season
+			  AND r.answer_id IS DISTINCT FROM $1
+			  AND r.answered_at > now() - $6 * interval '1 second'`
+
+	tag, err := q.Pool.Exec(ctx, query, userResult.AnswerID, userResult.Points, userResult.UserID, userResult.QuestionID,
+		userResult.ChannelTgID, int(window.Seconds()))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// StreamUserResultsByChannelID calls fn for every row while the cursor is open,
+// so the results are never collected into a slice.
+func (q *quizRepo) StreamUserResultsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(result *entity.UserResult) error) error {
+	where := newExportFilter(channelID, filter)
+	query := fmt.Sprintf(`SELECT
 				u.tg_username,
-				user_results.user_id,
-				user_results.id,
-				user_results.points,
+				ur.user_id,
+				ur.id,
+				ur.points,
 				q.question_name,
-				a.answer
-			FROM user_results
+				coalesce(a.answer, ''),
+				ur.questions_id,
+				coalesce(ur.answer_id, 0),
+				ur.answered_at,
+				ur.channel_tg_id,
+				coalesce(ur.chat_id, 0),
+				coalesce(ch.channel_name, '')
+			FROM user_results ur
 					 JOIN "user" u
-						  ON u.id = user_results.user_id
-					 JOIN questions q on user_results.questions_id = q.id
-					 JOIN channel c on q.channel_tg_id = c.tg_id
-					 JOIN public.answers a on user_results.questions_id = a.question_id and user_results.points = a.cost_of_response
-			WHERE c.tg_id = $1;`
-
-	rows, err := q.Pool.Query(ctx, query, channelID)
+						  ON u.id = ur.user_id
+					 JOIN questions q on ur.questions_id = q.id
+					 JOIN channel c on ur.channel_tg_id = c.tg_id
+					 LEFT JOIN channel ch on ch.tg_id = ur.chat_id
+					 LEFT JOIN public.answers a on a.id = ur.answer_id
+			WHERE c.tg_id = $1%s
+			ORDER BY ur.id;`, where.results)
+
+	rows, err := q.Pool.Query(ctx, query, where.args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var results []entity.UserResult
+	var result entity.UserResult
 	for rows.Next() {
-		var result entity.UserResult
-		err := rows.Scan(&result.TGUsername, &result.UserID, &result.ID, &result.Points, &result.QuestionName, &result.Answer)
+		err := rows.Scan(&result.TGUsername, &result.UserID, &result.ID, &result.Points, &result.QuestionName, &result.Answer,
+			&result.QuestionID, &result.AnswerID, &result.AnsweredAt, &result.ChannelTgID, &result.ChatID, &result.ChatName)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err = fn(&result); err != nil {
+			return err
 		}
-		results = append(results, result)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+
+	return rows.Err()
+}
+
+// leaderboardQuery ranks the users of $1 by their total points. Among equal
+// totals the user who reached the total first ranks higher. The %s is the
+// " AND ..." filter of the answers.
+const leaderboardQuery = `WITH scored AS (
+		SELECT ur.user_id,
+			   ur.answered_at,
+			   coalesce(a.is_correct, false) AS is_correct,
+			   sum(ur.points) OVER (PARTITION BY ur.user_id ORDER BY ur.answered_at, ur.id) AS running,
+			   sum(ur.points) OVER (PARTITION BY ur.user_id) AS total
+		FROM user_results ur
+				 JOIN questions q ON q.id = ur.questions_id
+				 JOIN channel c ON c.tg_id = ur.channel_tg_id
+				 LEFT JOIN answers a ON a.id = ur.answer_id
+		WHERE ur.channel_tg_id = $1%s
+	),
+	totals AS (
+		SELECT user_id,
+			   max(total) AS total,
+			   count(*) AS answers,
+			   count(*) FILTER (WHERE is_correct) AS correct,
+			   max(answered_at) AS last_answer_at,
+			   min(answered_at) FILTER (WHERE running = total) AS reached_at
+		FROM scored
+		GROUP BY user_id
+	)
+	SELECT row_number() OVER (ORDER BY t.total DESC, t.reached_at, t.user_id) AS rank,
+		   u.tg_username,
+		   t.user_id,
+		   t.total,
+		   t.answers,
+		   t.correct,
+		   t.last_answer_at
+	FROM totals t
+			 JOIN "user" u ON u.id = t.user_id
+	ORDER BY 1`
+
+// StreamLeaderboardByChannelID calls fn for every user of the channel in rank order.
+func (q *quizRepo) StreamLeaderboardByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(row *entity.LeaderboardRow) error) error {
+	where := newExportFilter(channelID, filter)
+	query := fmt.Sprintf(leaderboardQuery, where.results)
+
+	rows, err := q.Pool.Query(ctx, query, where.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var row entity.LeaderboardRow
+	for rows.Next() {
+		err := rows.Scan(&row.Rank, &row.TGUsername, &row.UserID, &row.TotalPoints, &row.Answers, &row.CorrectAnswers, &row.LastAnswerAt)
+		if err != nil {
+			return err
+		}
+		if err = fn(&row); err != nil {
+			return err
+		}
 	}
 
-	return results, nil
+	return rows.Err()
 }
 
-func (q *quizRepo) ResetAllUserResult(ctx context.Context, channelTgID int) error {
-	query := `UPDATE user_results
-			SET points = 0
-			FROM questions q
-					 JOIN channel c ON q.channel_tg_id = c.tg_id
-			WHERE user_results.questions_id = q.id AND c.tg_id = $1;`
+// StreamQuestionStatisticsByChannelID calls fn for every published or answered
+// question of the channel. All numbers are aggregated by the database, one row
+// per answer, and the rows of a question are grouped before fn is called.
+func (q *quizRepo) StreamQuestionStatisticsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(stat *entity.QuestionStatistics) error) error {
+	where := newExportFilter(channelID, filter)
+	query := fmt.Sprintf(`WITH results AS (
+				SELECT ur.*
+				FROM user_results ur
+						 JOIN questions q ON q.id = ur.questions_id
+						 JOIN channel c ON c.tg_id = ur.channel_tg_id
+				WHERE ur.channel_tg_id = $1%s
+			),
+			per_question AS (
+				SELECT q.id,
+					   count(DISTINCT ur.user_id) AS participants,
+					   count(ur.id) AS answers,
+					   coalesce(100.0 * count(ur.id) FILTER (WHERE a.is_correct) / nullif(count(ur.id), 0), 0)::float8 AS correct_percent,
+					   coalesce(avg(ur.points), 0)::float8 AS average_points
+				FROM questions q
+						 LEFT JOIN results ur ON ur.questions_id = q.id
+						 LEFT JOIN answers a ON a.id = ur.answer_id
+				WHERE (q.channel_tg_id = $1 OR q.id IN (SELECT questions_id FROM results))%s
+				GROUP BY q.id
+			),
+			per_answer AS (
+				SELECT a.id,
+					   a.question_id,
+					   a.answer,
+					   a.is_correct,
+					   a.position,
+					   count(ur.id) AS picked
+				FROM answers a
+						 JOIN per_question pq ON pq.id = a.question_id
+						 LEFT JOIN results ur ON ur.answer_id = a.id
+				GROUP BY a.id
+				HAVING NOT a.is_retired OR count(ur.id) > 0
+			)
+			SELECT q.id,
+				   q.question_name,
+				   q.question_entities,
+				   q.text_format,
+				   pq.participants,
+				   pq.correct_percent,
+				   pq.average_points,
+				   pa.id,
+				   pa.answer,
+				   pa.is_correct,
+				   pa.picked
+			FROM per_question pq
+					 JOIN questions q ON q.id = pq.id
+					 LEFT JOIN per_answer pa ON pa.question_id = q.id
+			WHERE q.is_send OR pq.answers > 0
+			ORDER BY q.id, pa.position, pa.id;`, where.results, where.questions)
+
+	rows, err := q.Pool.Query(ctx, query, where.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-	_, err := q.Pool.Exec(ctx, query, channelTgID)
-	return err
+	var current *entity.QuestionStatistics
+	for rows.Next() {
+		var (
+			stat      entity.QuestionStatistics
+			answerID  *int
+			answer    *string
+			isCorrect *bool
+			picked    *int
+		)
+		err := rows.Scan(&stat.Question.ID,
+			&stat.Question.QuestionName,
+			&stat.Question.QuestionEntities,
+			&stat.Question.TextFormat,
+			&stat.Participants,
+			&stat.CorrectPercent,
+			&stat.AveragePoints,
+			&answerID,
+			&answer,
+			&isCorrect,
+			&picked,
+		)
+		if err != nil {
+			return err
+		}
+
+		if current == nil || current.Question.ID != stat.Question.ID {
+			if current != nil {
+				if err = fn(current); err != nil {
+					return err
+				}
+			}
+			current = &stat
+		}
+		if answerID != nil {
+			current.Answers = append(current.Answers, entity.AnswerStatistics{
+				AnswerID:  *answerID,
+				Answer:    *answer,
+				IsCorrect: *isCorrect,
+				Picked:    *picked,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if current != nil {
+		return fn(current)
+	}
+	return nil
 }
 
 // Is user answer domain
 
 func (q *quizRepo) CreateBooleanUserAnswer(ctx context.Context, answer *entity.IsUserAnswer) error {
-	query := `INSERT INTO is_user_answer (user_id,is_answer,question_id) VALUES ($1, true, (select question_id from answers where id = $2));`
+	query := `INSERT INTO is_user_answer (user_id,is_answer,question_id,channel_tg_id,season)
+				SELECT $1, true, a.question_id, c.tg_id, c.current_season FROM answers a
+					JOIN channel c ON c.tg_id = $3
+				WHERE a.id = $2;`
 
-	_, err := q.Pool.Exec(ctx, query, answer.UserID, answer.AnswerID)
+	_, err := q.Pool.Exec(ctx, query, answer.UserID, answer.AnswerID, answer.ChannelTgID)
 	return err
 }
 
 func (q *quizRepo) IsUserAnswerExists(ctx context.Context, userAnswer *entity.IsUserAnswer) (bool, error) {
-	query := `SELECT EXISTS (SELECT user_id from is_user_answer WHERE user_id = $1 AND
-         question_id = (select question_id from answers where id = $2) AND is_answer = true)`
+	// answers of previous seasons don't count, a question can be answered again in a new season.
+	// The answer is checked in the channel it counts in, see GetAnswerChannel
+	query := `SELECT EXISTS (SELECT iua.user_id FROM is_user_answer iua
+				JOIN answers a ON a.question_id = iua.question_id
+				JOIN channel c ON c.tg_id = iua.channel_tg_id
+			WHERE iua.user_id = $1 AND a.id = $2 AND iua.channel_tg_id = $3 AND iua.is_answer = true AND iua.season = c.current_season)`
 	var isExist bool
 
-	err := q.Pool.QueryRow(ctx, query, userAnswer.UserID, userAnswer.AnswerID).Scan(&isExist)
+	err := q.Pool.QueryRow(ctx, query, userAnswer.UserID, userAnswer.AnswerID, userAnswer.ChannelTgID).Scan(&isExist)
 	if checkErr := ErrorHandler(err); checkErr != nil {
 		return isExist, checkErr
 	}
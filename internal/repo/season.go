@@ -0,0 +1,248 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/postgres"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type SeasonRepo interface {
+	CloseSeason(ctx context.Context, channelTgID int64) (*entity.Season, error)
+	ReopenSeason(ctx context.Context, seasonID int) error
+	GetSeasonByID(ctx context.Context, id int) (*entity.Season, error)
+	GetSeasonsByChannelID(ctx context.Context, channelTgID int64) ([]entity.Season, error)
+	StreamStandingsByChannelID(ctx context.Context, channelTgID int64, fn func(standing *entity.SeasonStanding) error) error
+}
+
+type seasonRepo struct {
+	*postgres.Postgres
+}
+
+func NewSeasonRepo(pg *postgres.Postgres) (SeasonRepo, error) {
+	if pg == nil {
+		return nil, errors.New("nil postgres")
+	}
+	return &seasonRepo{
+		Postgres: pg,
+	}, nil
+}
+
+// CloseSeason archives the standings of the current season of the channel and
+// starts the next one. Answers of the closed season stay in user_results.
+func (s *seasonRepo) CloseSeason(ctx context.Context, channelTgID int64) (season *entity.Season, err error) {
+	queryCurrent := `SELECT current_season FROM channel WHERE tg_id = $1 FOR UPDATE`
+
+	querySeason := `INSERT INTO season (channel_tg_id, number, started_at)
+			VALUES ($1, $2, coalesce(
+				(SELECT closed_at FROM season WHERE channel_tg_id = $1 AND number = $2 - 1),
+				(SELECT min(ur.answered_at) FROM user_results ur
+				WHERE ur.channel_tg_id = $1 AND ur.season = $2)))
+			RETURNING id, started_at, closed_at`
+
+	queryChannel := `UPDATE channel SET current_season = current_season + 1, last_reset_at = now() WHERE tg_id = $1`
+
+	tx, err := s.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	season = &entity.Season{ChannelTgID: channelTgID}
+	if err = tx.QueryRow(ctx, queryCurrent, channelTgID).Scan(&season.Number); err != nil {
+		return nil, err
+	}
+
+	if err = tx.QueryRow(ctx, querySeason, channelTgID, season.Number).Scan(&season.ID, &season.StartedAt, &season.ClosedAt); err != nil {
+		return nil, err
+	}
+
+	where := newExportFilter(int(channelTgID), &entity.ExportFilter{Season: season.Number})
+	queryStanding := fmt.Sprintf(`INSERT INTO season_standing
+				(season_id, rank, tg_username, user_id, total_points, answers, correct_answers, last_answer_at)
+			SELECT $%d, l.* FROM (`+leaderboardQuery+`) l`, len(where.args)+1, where.results)
+
+	tag, err := tx.Exec(ctx, queryStanding, append(where.args, season.ID)...)
+	if err != nil {
+		return nil, err
+	}
+	season.Participants = int(tag.RowsAffected())
+
+	if _, err = tx.Exec(ctx, queryChannel, channelTgID); err != nil {
+		return nil, err
+	}
+
+	return season, nil
+}
+
+// ReopenSeason reverts CloseSeason: the archived standings are dropped and the
+// season becomes the current one again. It fails with ErrUndoUnavailable once
+// a later season was closed or got answers.
+func (s *seasonRepo) ReopenSeason(ctx context.Context, seasonID int) (err error) {
+	querySeason := `SELECT channel_tg_id, number FROM season WHERE id = $1`
+
+	queryCurrent := `SELECT current_season FROM channel WHERE tg_id = $1 FOR UPDATE`
+
+	queryAnswered := `SELECT exists(SELECT 1 FROM user_results ur
+				WHERE ur.channel_tg_id = $1 AND ur.season = $2)`
+
+	queryDelete := `DELETE FROM season WHERE id = $1`
+
+	queryChannel := `UPDATE channel SET current_season = $2, last_reset_at = (
+				SELECT closed_at FROM season WHERE channel_tg_id = $1 AND number = $2 - 1)
+			WHERE tg_id = $1`
+
+	tx, err := s.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	var (
+		channelTgID   int64
+		number        int
+		currentSeason int
+		answered      bool
+	)
+	if err = tx.QueryRow(ctx, querySeason, seasonID).Scan(&channelTgID, &number); err != nil {
+		return err
+	}
+
+	if err = tx.QueryRow(ctx, queryCurrent, channelTgID).Scan(&currentSeason); err != nil {
+		return err
+	}
+	if currentSeason != number+1 {
+		return customErr.ErrUndoUnavailable
+	}
+
+	if err = tx.QueryRow(ctx, queryAnswered, channelTgID, currentSeason).Scan(&answered); err != nil {
+		return err
+	}
+	if answered {
+		return customErr.ErrUndoUnavailable
+	}
+
+	if _, err = tx.Exec(ctx, queryDelete, seasonID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, queryChannel, channelTgID, number); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const seasonColumns = `s.id, s.channel_tg_id, s.number, s.started_at, s.closed_at,
+				(SELECT count(*) FROM season_standing ss WHERE ss.season_id = s.id),
+				coalesce(w.tg_username, ''),
+				coalesce(w.total_points, 0)
+			FROM season s
+					 LEFT JOIN season_standing w ON w.season_id = s.id AND w.rank = 1`
+
+func (s *seasonRepo) collectSeason(row pgx.Row) (*entity.Season, error) {
+	var season entity.Season
+	err := row.Scan(&season.ID,
+		&season.ChannelTgID,
+		&season.Number,
+		&season.StartedAt,
+		&season.ClosedAt,
+		&season.Participants,
+		&season.WinnerUsername,
+		&season.WinnerPoints,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (s *seasonRepo) GetSeasonByID(ctx context.Context, id int) (*entity.Season, error) {
+	query := `SELECT ` + seasonColumns + ` WHERE s.id = $1`
+
+	return s.collectSeason(s.Pool.QueryRow(ctx, query, id))
+}
+
+func (s *seasonRepo) GetSeasonsByChannelID(ctx context.Context, channelTgID int64) ([]entity.Season, error) {
+	query := `SELECT ` + seasonColumns + ` WHERE s.channel_tg_id = $1 ORDER BY s.number`
+
+	rows, err := s.Pool.Query(ctx, query, channelTgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []entity.Season
+	for rows.Next() {
+		season, err := s.collectSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, *season)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return seasons, nil
+}
+
+// StreamStandingsByChannelID calls fn for the archived standings of all closed
+// seasons of the channel, grouped by user and ordered by season.
+func (s *seasonRepo) StreamStandingsByChannelID(ctx context.Context, channelTgID int64, fn func(standing *entity.SeasonStanding) error) error {
+	query := `SELECT s.number, ss.rank, coalesce(ss.tg_username, ''), ss.user_id, ss.total_points, ss.answers, ss.correct_answers, ss.last_answer_at
+			FROM season_standing ss
+					 JOIN season s ON s.id = ss.season_id
+			WHERE s.channel_tg_id = $1
+			ORDER BY ss.user_id, s.number`
+
+	rows, err := s.Pool.Query(ctx, query, channelTgID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var standing entity.SeasonStanding
+	for rows.Next() {
+		var lastAnswerAt *time.Time
+		err := rows.Scan(&standing.SeasonNumber,
+			&standing.Rank,
+			&standing.TGUsername,
+			&standing.UserID,
+			&standing.TotalPoints,
+			&standing.Answers,
+			&standing.CorrectAnswers,
+			&lastAnswerAt,
+		)
+		if err != nil {
+			return err
+		}
+		standing.LastAnswerAt = time.Time{}
+		if lastAnswerAt != nil {
+			standing.LastAnswerAt = *lastAnswerAt
+		}
+		if err = fn(&standing); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
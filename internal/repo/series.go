@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/postgres"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type SeriesRepo interface {
+	CreateSeries(ctx context.Context, series *entity.Series) error
+	GetSeriesByID(ctx context.Context, id int) (*entity.Series, error)
+	GetSeriesByChannelID(ctx context.Context, channelTgID int64) ([]entity.Series, error)
+	GetDueSeriesIDs(ctx context.Context, now time.Time) ([]int, error)
+	UpdateSeriesState(ctx context.Context, series *entity.Series) error
+	UpdateSeriesQuestion(ctx context.Context, seriesID int, question *entity.SeriesQuestion) error
+	DeleteSeries(ctx context.Context, id int) error
+}
+
+type seriesRepo struct {
+	*postgres.Postgres
+}
+
+func NewSeriesRepo(pg *postgres.Postgres) (SeriesRepo, error) {
+	if pg == nil {
+		return nil, errors.New("nil postgres")
+	}
+	return &seriesRepo{
+		Postgres: pg,
+	}, nil
+}
+
+const seriesColumns = `id, channel_tg_id, title, interval_seconds, reveal_results, status, current_position,
+			next_run_at, paused_at, last_error, started_at, finished_at, coalesce(created_by, 0), created_at`
+
+func scanSeries(row pgx.Row, series *entity.Series) error {
+	var seconds int
+	if err := row.Scan(&series.ID,
+		&series.ChannelTgID,
+		&series.Title,
+		&seconds,
+		&series.RevealResults,
+		&series.Status,
+		&series.CurrentPosition,
+		&series.NextRunAt,
+		&series.PausedAt,
+		&series.LastError,
+		&series.StartedAt,
+		&series.FinishedAt,
+		&series.CreatedBy,
+		&series.CreatedAt,
+	); err != nil {
+		return err
+	}
+	series.Interval = time.Duration(seconds) * time.Second
+
+	return nil
+}
+
+// CreateSeries saves the draft with its questions in the order of QuestionIDs.
+func (s *seriesRepo) CreateSeries(ctx context.Context, series *entity.Series) (err error) {
+	querySeries := `INSERT INTO series (channel_tg_id, title, interval_seconds, reveal_results, created_by)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id, status, created_at`
+
+	queryQuestion := `INSERT INTO series_question (series_id, question_id, position) VALUES ($1, $2, $3)`
+
+	tx, err := s.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback(ctx)
+		} else {
+			err = tx.Commit(ctx)
+		}
+	}()
+
+	if err = tx.QueryRow(ctx, querySeries, series.ChannelTgID, series.Title, int(series.Interval.Seconds()),
+		series.RevealResults, series.CreatedBy).Scan(&series.ID, &series.Status, &series.CreatedAt); err != nil {
+		return err
+	}
+
+	for key, questionID := range series.QuestionIDs {
+		if _, err = tx.Exec(ctx, queryQuestion, series.ID, questionID, key+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *seriesRepo) GetSeriesByID(ctx context.Context, id int) (*entity.Series, error) {
+	querySeries := `SELECT ` + seriesColumns + ` FROM series WHERE id = $1`
+
+	queryQuestions := `SELECT sq.position, sq.published_at, sq.closed_at,
+				q.id, q.question_name, q.question_entities, q.text_format, q.deleted_at, q.closed_at
+			FROM series_question sq
+					 JOIN questions q ON q.id = sq.question_id
+			WHERE sq.series_id = $1
+			ORDER BY sq.position`
+
+	series := new(entity.Series)
+	if err := scanSeries(s.Pool.QueryRow(ctx, querySeries, id), series); err != nil {
+		return nil, ErrorHandler(err)
+	}
+
+	rows, err := s.Pool.Query(ctx, queryQuestions, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var question entity.SeriesQuestion
+		if err := rows.Scan(&question.Position,
+			&question.PublishedAt,
+			&question.ClosedAt,
+			&question.Question.ID,
+			&question.Question.QuestionName,
+			&question.Question.QuestionEntities,
+			&question.Question.TextFormat,
+			&question.Question.DeletedAt,
+			&question.Question.ClosedAt,
+		); err != nil {
+			return nil, err
+		}
+		series.Questions = append(series.Questions, question)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (s *seriesRepo) GetSeriesByChannelID(ctx context.Context, channelTgID int64) ([]entity.Series, error) {
+	query := `SELECT ` + seriesColumns + ` FROM series WHERE channel_tg_id = $1 ORDER BY id DESC`
+
+	rows, err := s.Pool.Query(ctx, query, channelTgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []entity.Series
+	for rows.Next() {
+		var series entity.Series
+		if err := scanSeries(rows, &series); err != nil {
+			return nil, err
+		}
+		list = append(list, series)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetDueSeriesIDs returns the running series whose current question is over.
+func (s *seriesRepo) GetDueSeriesIDs(ctx context.Context, now time.Time) ([]int, error) {
+	query := `SELECT id FROM series WHERE status = 'running' AND next_run_at <= $1 ORDER BY next_run_at`
+
+	rows, err := s.Pool.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// UpdateSeriesState saves the run state of the series, the title, the
+// interval and the questions stay as they are.
+func (s *seriesRepo) UpdateSeriesState(ctx context.Context, series *entity.Series) error {
+	query := `UPDATE series SET reveal_results = $1, status = $2, current_position = $3, next_run_at = $4,
+				paused_at = $5, last_error = $6, started_at = $7, finished_at = $8
+			WHERE id = $9`
+
+	_, err := s.Pool.Exec(ctx, query, series.RevealResults, series.Status, series.CurrentPosition, series.NextRunAt,
+		series.PausedAt, series.LastError, series.StartedAt, series.FinishedAt, series.ID)
+	return err
+}
+
+func (s *seriesRepo) UpdateSeriesQuestion(ctx context.Context, seriesID int, question *entity.SeriesQuestion) error {
+	query := `UPDATE series_question SET published_at = $1, closed_at = $2 WHERE series_id = $3 AND position = $4`
+
+	_, err := s.Pool.Exec(ctx, query, question.PublishedAt, question.ClosedAt, seriesID, question.Position)
+	return err
+}
+
+func (s *seriesRepo) DeleteSeries(ctx context.Context, id int) error {
+	query := `DELETE FROM series WHERE id = $1`
+
+	_, err := s.Pool.Exec(ctx, query, id)
+	return err
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	"runtime/debug"
+	"time"
+)
+
+// Job does the work that is due at now. Jobs keep their state in the
+// database, so a tick missed while the bot was down is caught up on the next
+// one.
+type Job func(ctx context.Context, now time.Time) error
+
+type namedJob struct {
+	name string
+	run  Job
+}
+
+// Scheduler runs its jobs one after another on every tick.
+type Scheduler struct {
+	tick    time.Duration
+	timeout time.Duration
+	jobs    []namedJob
+	log     *logger.Logger
+}
+
+func New(tick time.Duration, timeout time.Duration, log *logger.Logger) *Scheduler {
+	return &Scheduler{
+		tick:    tick,
+		timeout: timeout,
+		log:     log,
+	}
+}
+
+// Add registers a job, jobs are added before Run.
+func (s *Scheduler) Add(name string, job Job) {
+	s.jobs = append(s.jobs, namedJob{name: name, run: job})
+}
+
+// Run ticks until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, job := range s.jobs {
+				s.runJob(ctx, job, now)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job namedJob, now time.Time) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.log.Error("panic recovered in job %s: %v, %s", job.name, p, string(debug.Stack()))
+		}
+	}()
+
+	jobCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := job.run(jobCtx, now); err != nil {
+		s.log.Error("job %s failed: %v", job.name, err)
+	}
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/cron"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	customMsg "github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api"
+	"html"
+	"sync"
+	"time"
+)
+
+// lowBankThresholds are the values the admin cycles the warning threshold
+// through, 0 warns only when the bank is empty.
+var lowBankThresholds = []int{0, 3, 5, 10, 20}
+
+type AutopilotService interface {
+	GetAutopilot(ctx context.Context, channelTgID int64) (*entity.Autopilot, error)
+	CountUnsentQuestions(ctx context.Context, autopilot *entity.Autopilot) (int, error)
+	Toggle(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.Autopilot, error)
+	SetSchedule(ctx context.Context, channelTgID int64, updatedBy int64, expr string) (*entity.Autopilot, error)
+	SetTag(ctx context.Context, channelTgID int64, updatedBy int64, tag *string) (*entity.Autopilot, error)
+	CycleDifficulty(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.Autopilot, error)
+	CycleThreshold(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.Autopilot, error)
+	Reschedule(ctx context.Context, channelTgID int64) error
+	RunDue(ctx context.Context, now time.Time) error
+}
+
+type autopilotService struct {
+	autopilotRepo  repo.AutopilotRepo
+	channelRepo    repo.ChannelRepo
+	userRepo       repo.UserRepo
+	publishService PublishService
+	tgMsg          customMsg.Message
+	log            *logger.Logger
+
+	// mu keeps a slot from running while an admin changes the settings
+	mu sync.Mutex
+}
+
+func NewAutopilotService(autopilotRepo repo.AutopilotRepo, channelRepo repo.ChannelRepo, userRepo repo.UserRepo,
+	publishService PublishService, tgMsg customMsg.Message, log *logger.Logger) (AutopilotService, error) {
+	if autopilotRepo == nil {
+		return nil, errors.New("nil autopilotRepo")
+	}
+	if channelRepo == nil {
+		return nil, errors.New("nil channelRepo")
+	}
+	if userRepo == nil {
+		return nil, errors.New("nil userRepo")
+	}
+	if publishService == nil {
+		return nil, errors.New("nil publishService")
+	}
+	if tgMsg == nil {
+		return nil, errors.New("nil tgMsg")
+	}
+	if log == nil {
+		return nil, errors.New("nil logger")
+	}
+
+	return &autopilotService{
+		autopilotRepo:  autopilotRepo,
+		channelRepo:    channelRepo,
+		userRepo:       userRepo,
+		publishService: publishService,
+		tgMsg:          tgMsg,
+		log:            log,
+	}, nil
+}
+
+// GetAutopilot returns the settings of the channel, the defaults when the
+// autopilot was never set up.
+func (a *autopilotService) GetAutopilot(ctx context.Context, channelTgID int64) (*entity.Autopilot, error) {
+	autopilot, err := a.autopilotRepo.GetAutopilot(ctx, channelTgID)
+	if errors.Is(err, customErr.ErrNoRows) {
+		channel, err := a.channelRepo.GetByChannelID(ctx, channelTgID)
+		if err != nil {
+			return nil, err
+		}
+		return entity.NewAutopilot(channelTgID, channel.Timezone), nil
+	}
+	return autopilot, err
+}
+
+func (a *autopilotService) CountUnsentQuestions(ctx context.Context, autopilot *entity.Autopilot) (int, error) {
+	return a.autopilotRepo.CountUnsentQuestions(ctx, autopilot)
+}
+
+func (a *autopilotService) Toggle(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.Autopilot, error) {
+	return a.update(ctx, channelTgID, updatedBy, func(autopilot *entity.Autopilot) error {
+		autopilot.Enabled = !autopilot.Enabled
+		return nil
+	})
+}
+
+// SetSchedule checks the cron expression before saving it.
+func (a *autopilotService) SetSchedule(ctx context.Context, channelTgID int64, updatedBy int64, expr string) (*entity.Autopilot, error) {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.update(ctx, channelTgID, updatedBy, func(autopilot *entity.Autopilot) error {
+		autopilot.Schedule = schedule.String()
+		return nil
+	})
+}
+
+func (a *autopilotService) SetTag(ctx context.Context, channelTgID int64, updatedBy int64, tag *string) (*entity.Autopilot, error) {
+	return a.update(ctx, channelTgID, updatedBy, func(autopilot *entity.Autopilot) error {
+		autopilot.Tag = tag
+		return nil
+	})
+}
+
+func (a *autopilotService) CycleDifficulty(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.Autopilot, error) {
+	return a.update(ctx, channelTgID, updatedBy, func(autopilot *entity.Autopilot) error {
+		autopilot.Difficulty = entity.NextDifficulty(autopilot.Difficulty)
+		return nil
+	})
+}
+
+func (a *autopilotService) CycleThreshold(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.Autopilot, error) {
+	return a.update(ctx, channelTgID, updatedBy, func(autopilot *entity.Autopilot) error {
+		next := lowBankThresholds[0]
+		for key, value := range lowBankThresholds {
+			if value == autopilot.LowBankThreshold && key+1 < len(lowBankThresholds) {
+				next = lowBankThresholds[key+1]
+			}
+		}
+		autopilot.LowBankThreshold = next
+		return nil
+	})
+}
+
+// update applies fn to the settings and saves them with the next slot.
+func (a *autopilotService) update(ctx context.Context, channelTgID int64, updatedBy int64, fn func(autopilot *entity.Autopilot) error) (*entity.Autopilot, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	autopilot, err := a.GetAutopilot(ctx, channelTgID)
+	if err != nil {
+		a.log.Error("autopilotRepo.GetAutopilot: %v", err)
+		return nil, err
+	}
+
+	if err = fn(autopilot); err != nil {
+		return nil, err
+	}
+	autopilot.UpdatedBy = updatedBy
+
+	autopilot.NextRunAt = nil
+	if autopilot.Enabled {
+		if autopilot.NextRunAt, err = nextAutopilotRun(autopilot, time.Now()); err != nil {
+			a.log.Error("failed to schedule autopilot of %d: %v", channelTgID, err)
+			return nil, err
+		}
+	}
+
+	if err = a.autopilotRepo.SaveAutopilotSettings(ctx, autopilot); err != nil {
+		a.log.Error("autopilotRepo.SaveAutopilotSettings: %v", err)
+		return nil, err
+	}
+
+	return autopilot, nil
+}
+
+// Reschedule moves the next slot after the timezone of the channel changed.
+func (a *autopilotService) Reschedule(ctx context.Context, channelTgID int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	autopilot, err := a.autopilotRepo.GetAutopilot(ctx, channelTgID)
+	if errors.Is(err, customErr.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		a.log.Error("autopilotRepo.GetAutopilot: %v", err)
+		return err
+	}
+	if !autopilot.Enabled {
+		return nil
+	}
+
+	if autopilot.NextRunAt, err = nextAutopilotRun(autopilot, time.Now()); err != nil {
+		a.log.Error("failed to schedule autopilot of %d: %v", channelTgID, err)
+		return err
+	}
+	if err = a.autopilotRepo.SaveAutopilotSettings(ctx, autopilot); err != nil {
+		a.log.Error("autopilotRepo.SaveAutopilotSettings: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// RunDue posts a question for every autopilot whose slot has come. Slots
+// missed while the bot was down are run once, not once per slot.
+func (a *autopilotService) RunDue(ctx context.Context, now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	list, err := a.autopilotRepo.GetDueAutopilots(ctx, now)
+	if err != nil {
+		a.log.Error("autopilotRepo.GetDueAutopilots: %v", err)
+		return err
+	}
+
+	var firstErr error
+	for key := range list {
+		if err = a.run(ctx, &list[key], now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (a *autopilotService) run(ctx context.Context, autopilot *entity.Autopilot, now time.Time) error {
+	autopilot.LastRunAt = &now
+	autopilot.LastError = nil
+
+	id, err := a.autopilotRepo.PickUnsentQuestion(ctx, autopilot)
+	switch {
+	case errors.Is(err, customErr.ErrNoRows):
+		text := "нет неотправленных вопросов"
+		autopilot.LastError = &text
+		a.checkBank(ctx, autopilot, 0, now)
+	case err != nil:
+		a.log.Error("autopilotRepo.PickUnsentQuestion: %v", err)
+		return err
+	default:
+		if _, err = a.publishService.PublishScheduled(ctx, &entity.Publication{
+			QuestionID: id,
+			Scoring:    entity.ScoringSeparate,
+			ChannelIDs: []int64{autopilot.ChannelTgID},
+			CreatedBy:  autopilot.UpdatedBy,
+		}); err != nil {
+			a.log.Error("autopilot failed to post question %d to %d: %v", id, autopilot.ChannelTgID, err)
+			text := err.Error()
+			autopilot.LastError = &text
+			// the same question would be picked again, so the autopilot waits
+			// for the admin to fix it
+			var preflightErr *entity.PreflightError
+			if errors.As(err, &preflightErr) {
+				autopilot.Enabled = false
+			}
+			break
+		}
+
+		remaining, err := a.autopilotRepo.CountUnsentQuestions(ctx, autopilot)
+		if err != nil {
+			a.log.Error("autopilotRepo.CountUnsentQuestions: %v", err)
+			break
+		}
+		a.checkBank(ctx, autopilot, remaining, now)
+	}
+
+	autopilot.NextRunAt = nil
+	if autopilot.Enabled {
+		if autopilot.NextRunAt, err = nextAutopilotRun(autopilot, now); err != nil {
+			a.log.Error("failed to schedule autopilot of %d: %v", autopilot.ChannelTgID, err)
+			text := err.Error()
+			autopilot.LastError = &text
+		}
+	}
+
+	if err = a.autopilotRepo.UpdateAutopilotRun(ctx, autopilot); err != nil {
+		a.log.Error("autopilotRepo.UpdateAutopilotRun: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// checkBank warns the admins once when the bank drops to the threshold. The
+// warning is armed again when the bank grows above it.
+func (a *autopilotService) checkBank(ctx context.Context, autopilot *entity.Autopilot, remaining int, now time.Time) {
+	if remaining > autopilot.LowBankThreshold {
+		autopilot.LowBankWarnedAt = nil
+		return
+	}
+	if autopilot.LowBankWarnedAt != nil {
+		return
+	}
+	autopilot.LowBankWarnedAt = &now
+
+	name := fmt.Sprint(autopilot.ChannelTgID)
+	if channel, err := a.channelRepo.GetByChannelID(ctx, autopilot.ChannelTgID); err == nil {
+		name = channel.ChannelName
+	}
+
+	text := fmt.Sprintf("⚠️ Автопилот канала «%s»: осталось неотправленных вопросов: %d (%s). Пополните банк вопросов",
+		html.EscapeString(name), remaining, html.EscapeString(autopilot.FilterTitle()))
+	if remaining == 0 {
+		text = fmt.Sprintf("⚠️ Автопилот канала «%s»: неотправленные вопросы закончились (%s), публикации пропускаются",
+			html.EscapeString(name), html.EscapeString(autopilot.FilterTitle()))
+	}
+
+	admins, err := a.userRepo.GetAllAdmin(ctx)
+	if err != nil {
+		a.log.Error("userRepo.GetAllAdmin: %v", err)
+		return
+	}
+	for _, admin := range admins {
+		if _, err = a.tgMsg.SendNewMessage(admin.ID, nil, text); err != nil {
+			a.log.Error("failed to warn admin %d about the bank: %v", admin.ID, err)
+		}
+	}
+}
+
+func nextAutopilotRun(autopilot *entity.Autopilot, now time.Time) (*time.Time, error) {
+	schedule, err := cron.Parse(autopilot.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	next := schedule.Next(now.In(entity.LoadLocation(autopilot.Timezone)))
+	if next.IsZero() {
+		return nil, cron.ErrNeverFires
+	}
+	return &next, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/query"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/button"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -19,32 +20,77 @@ type ChannelService interface {
 	GetByID(ctx context.Context, id int) (*entity.Channel, error)
 	GetAll(ctx context.Context) ([]entity.Channel, error)
 	GetAllAdminChannel(ctx context.Context, questionID ...any) (*tgbotapi.InlineKeyboardMarkup, error)
+	GetAdminChannels(ctx context.Context) ([]entity.Channel, error)
+	GetAdminChannelMarkup(ctx context.Context, command string) (*tgbotapi.InlineKeyboardMarkup, error)
 	GetByChannelName(ctx context.Context, channelName string) (*entity.Channel, error)
 	GetByChannelID(ctx context.Context, channelID int64) (*entity.Channel, error)
 
 	DeleteByID(ctx context.Context, id int) error
 	ChatMember(ctx context.Context, channel *entity.Channel) error
+	SetTimezone(ctx context.Context, channelTgID int64, timezone string) error
 }
 
 type channelService struct {
-	channelRepo repo.ChannelRepo
-	log         *logger.Logger
+	channelRepo      repo.ChannelRepo
+	autopilotService AutopilotService
+	cronJobService   CronJobService
+	log              *logger.Logger
+
+	// mu keeps the channel and its schedules in one timezone
+	mu sync.Mutex
 }
 
-func NewChannelService(channelRepo repo.ChannelRepo, log *logger.Logger) (ChannelService, error) {
+func NewChannelService(channelRepo repo.ChannelRepo, autopilotService AutopilotService, cronJobService CronJobService,
+	log *logger.Logger) (ChannelService, error) {
 	if log == nil {
 		return nil, errors.New("log is nil")
 	}
 	if channelRepo == nil {
 		return nil, errors.New("channelRepo is nil")
 	}
+	if autopilotService == nil {
+		return nil, errors.New("autopilotService is nil")
+	}
+	if cronJobService == nil {
+		return nil, errors.New("cronJobService is nil")
+	}
 
 	return &channelService{
-		channelRepo: channelRepo,
-		log:         log,
+		channelRepo:      channelRepo,
+		autopilotService: autopilotService,
+		cronJobService:   cronJobService,
+		log:              log,
 	}, nil
 }
 
+// SetTimezone changes the timezone of the channel and moves the next runs of
+// its autopilot and cron jobs to it.
+func (c *channelService) SetTimezone(ctx context.Context, channelTgID int64, timezone string) error {
+	location, err := entity.ParseLocation(timezone)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err = c.channelRepo.UpdateTimezone(ctx, channelTgID, location.String()); err != nil {
+		c.log.Error("channelRepo.UpdateTimezone: %v", err)
+		return err
+	}
+
+	if err = c.autopilotService.Reschedule(ctx, channelTgID); err != nil {
+		c.log.Error("autopilotService.Reschedule: %v", err)
+		return err
+	}
+	if err = c.cronJobService.Reschedule(ctx, channelTgID); err != nil {
+		c.log.Error("cronJobService.Reschedule: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 func (c *channelService) GetByChannelID(ctx context.Context, channelID int64) (*entity.Channel, error) {
 	return c.channelRepo.GetByChannelID(ctx, channelID)
 }
@@ -118,6 +164,20 @@ func (c *channelService) GetAllAdminChannel(ctx context.Context, questionID ...a
 	return markup, err
 }
 
+func (c *channelService) GetAdminChannels(ctx context.Context) ([]entity.Channel, error) {
+	return c.channelRepo.GetAllAdminChannel(ctx)
+}
+
+// GetAdminChannelMarkup - buttons channel_{command}_{channel_tg_id} for every channel where the bot is admin
+func (c *channelService) GetAdminChannelMarkup(ctx context.Context, command string) (*tgbotapi.InlineKeyboardMarkup, error) {
+	channel, err := c.channelRepo.GetAllAdminChannel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.createChannelMarkupV2(channel, command)
+}
+
 func (c *channelService) createChannelMarkupV2(channel []entity.Channel, command string) (*tgbotapi.InlineKeyboardMarkup, error) {
 	var rows [][]tgbotapi.InlineKeyboardButton
 	var row []tgbotapi.InlineKeyboardButton
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	"sync"
+)
+
+type ChannelSettingsService interface {
+	GetSettings(ctx context.Context, channelTgID int64) (*entity.ChannelSettings, error)
+	CycleButtonsPerRow(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error)
+	ToggleShuffle(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error)
+	TogglePoints(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error)
+	ToggleMembership(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error)
+	CycleAnswerChange(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error)
+	CycleDefaultDeadline(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error)
+	SetFeedbackText(ctx context.Context, channelTgID int64, updatedBy int64, text *string) (*entity.ChannelSettings, error)
+	SetSignature(ctx context.Context, channelTgID int64, updatedBy int64, signature *string) (*entity.ChannelSettings, error)
+}
+
+type channelSettingsService struct {
+	channelSettingsRepo repo.ChannelSettingsRepo
+	log                 *logger.Logger
+
+	// mu keeps two admins from overwriting each other's changes
+	mu sync.Mutex
+}
+
+func NewChannelSettingsService(channelSettingsRepo repo.ChannelSettingsRepo, log *logger.Logger) (ChannelSettingsService, error) {
+	if channelSettingsRepo == nil {
+		return nil, errors.New("nil channelSettingsRepo")
+	}
+	if log == nil {
+		return nil, errors.New("nil logger")
+	}
+
+	return &channelSettingsService{
+		channelSettingsRepo: channelSettingsRepo,
+		log:                 log,
+	}, nil
+}
+
+// GetSettings returns the settings of the channel, the defaults when they
+// were never changed.
+func (c *channelSettingsService) GetSettings(ctx context.Context, channelTgID int64) (*entity.ChannelSettings, error) {
+	return getChannelSettings(ctx, c.channelSettingsRepo, channelTgID)
+}
+
+func (c *channelSettingsService) CycleButtonsPerRow(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.ButtonsPerRow = entity.NextButtonsPerRow(settings.ButtonsPerRow)
+	})
+}
+
+func (c *channelSettingsService) ToggleShuffle(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.ShuffleAnswers = !settings.ShuffleAnswers
+	})
+}
+
+func (c *channelSettingsService) TogglePoints(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.ShowPoints = !settings.ShowPoints
+	})
+}
+
+func (c *channelSettingsService) ToggleMembership(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.RequireMembership = !settings.RequireMembership
+	})
+}
+
+func (c *channelSettingsService) CycleAnswerChange(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.AnswerChange = entity.NextDuration(entity.AnswerChangeWindows, settings.AnswerChange)
+	})
+}
+
+func (c *channelSettingsService) CycleDefaultDeadline(ctx context.Context, channelTgID int64, updatedBy int64) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.DefaultDeadline = entity.NextDuration(entity.DefaultDeadlines, settings.DefaultDeadline)
+	})
+}
+
+// SetFeedbackText changes the alert after an answer, nil brings back the
+// default one.
+func (c *channelSettingsService) SetFeedbackText(ctx context.Context, channelTgID int64, updatedBy int64, text *string) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.FeedbackText = text
+	})
+}
+
+// SetSignature changes the signature of new posts, nil removes it.
+func (c *channelSettingsService) SetSignature(ctx context.Context, channelTgID int64, updatedBy int64, signature *string) (*entity.ChannelSettings, error) {
+	return c.update(ctx, channelTgID, updatedBy, func(settings *entity.ChannelSettings) {
+		settings.Signature = signature
+	})
+}
+
+// update applies fn to the settings and saves them.
+func (c *channelSettingsService) update(ctx context.Context, channelTgID int64, updatedBy int64, fn func(settings *entity.ChannelSettings)) (*entity.ChannelSettings, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	settings, err := c.GetSettings(ctx, channelTgID)
+	if err != nil {
+		c.log.Error("channelSettingsRepo.GetChannelSettings: %v", err)
+		return nil, err
+	}
+
+	fn(settings)
+	settings.UpdatedBy = updatedBy
+
+	if err = c.channelSettingsRepo.SaveChannelSettings(ctx, settings); err != nil {
+		c.log.Error("channelSettingsRepo.SaveChannelSettings: %v", err)
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// getChannelSettings reads the settings of the channel for the services that
+// follow them, the defaults when they were never changed.
+func getChannelSettings(ctx context.Context, channelSettingsRepo repo.ChannelSettingsRepo, channelTgID int64) (*entity.ChannelSettings, error) {
+	settings, err := channelSettingsRepo.GetChannelSettings(ctx, channelTgID)
+	if errors.Is(err, customErr.ErrNoRows) {
+		return entity.NewChannelSettings(channelTgID), nil
+	}
+	return settings, err
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/cron"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/excel"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/export"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	customMsg "github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api"
+	"html"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// cronLeaderboardTop is how many places the leaderboard job posts.
+	cronLeaderboardTop = 10
+	// cronReportDays is how many whole days before the run the weekly
+	// report covers.
+	cronReportDays = 7
+)
+
+type CronJobService interface {
+	CreateCronJob(ctx context.Context, job *entity.CronJob) error
+	GetCronJobByID(ctx context.Context, id int) (*entity.CronJob, error)
+	GetCronJobsByChannelID(ctx context.Context, channelTgID int64) ([]entity.CronJob, error)
+	UpcomingRuns(jobs []entity.CronJob, from time.Time, limit int) []entity.CronJobRun
+	SetSchedule(ctx context.Context, id int, expr string) (*entity.CronJob, error)
+	TogglePause(ctx context.Context, id int) (*entity.CronJob, error)
+	DeleteCronJob(ctx context.Context, id int) error
+	Reschedule(ctx context.Context, channelTgID int64) error
+	RunDue(ctx context.Context, now time.Time) error
+}
+
+type cronJobService struct {
+	cronJobRepo    repo.CronJobRepo
+	channelRepo    repo.ChannelRepo
+	userRepo       repo.UserRepo
+	quizRepo       repo.QuizRepo
+	publishService PublishService
+	excel          *excel.Excel
+	tgMsg          customMsg.Message
+	log            *logger.Logger
+
+	// mu keeps a job from running while an admin changes it
+	mu sync.Mutex
+}
+
+func NewCronJobService(cronJobRepo repo.CronJobRepo, channelRepo repo.ChannelRepo, userRepo repo.UserRepo, quizRepo repo.QuizRepo,
+	publishService PublishService, excel *excel.Excel, tgMsg customMsg.Message, log *logger.Logger) (CronJobService, error) {
+	if cronJobRepo == nil {
+		return nil, errors.New("nil cronJobRepo")
+	}
+	if channelRepo == nil {
+		return nil, errors.New("nil channelRepo")
+	}
+	if userRepo == nil {
+		return nil, errors.New("nil userRepo")
+	}
+	if quizRepo == nil {
+		return nil, errors.New("nil quizRepo")
+	}
+	if publishService == nil {
+		return nil, errors.New("nil publishService")
+	}
+	if excel == nil {
+		return nil, errors.New("nil excel")
+	}
+	if tgMsg == nil {
+		return nil, errors.New("nil tgMsg")
+	}
+	if log == nil {
+		return nil, errors.New("nil logger")
+	}
+
+	return &cronJobService{
+		cronJobRepo:    cronJobRepo,
+		channelRepo:    channelRepo,
+		userRepo:       userRepo,
+		quizRepo:       quizRepo,
+		publishService: publishService,
+		excel:          excel,
+		tgMsg:          tgMsg,
+		log:            log,
+	}, nil
+}
+
+func (c *cronJobService) CreateCronJob(ctx context.Context, job *entity.CronJob) error {
+	channel, err := c.channelRepo.GetByChannelID(ctx, job.ChannelTgID)
+	if err != nil {
+		c.log.Error("channelRepo.GetByChannelID: %v", err)
+		return err
+	}
+	job.Timezone = channel.Timezone
+
+	if job.NextRunAt, err = nextCronJobRun(job, time.Now()); err != nil {
+		return err
+	}
+
+	if err = c.cronJobRepo.CreateCronJob(ctx, job); err != nil {
+		c.log.Error("cronJobRepo.CreateCronJob: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *cronJobService) GetCronJobByID(ctx context.Context, id int) (*entity.CronJob, error) {
+	return c.cronJobRepo.GetCronJobByID(ctx, id)
+}
+
+func (c *cronJobService) GetCronJobsByChannelID(ctx context.Context, channelTgID int64) ([]entity.CronJob, error) {
+	return c.cronJobRepo.GetCronJobsByChannelID(ctx, channelTgID)
+}
+
+// UpcomingRuns returns the first runs of the active jobs after from, the
+// earliest first.
+func (c *cronJobService) UpcomingRuns(jobs []entity.CronJob, from time.Time, limit int) []entity.CronJobRun {
+	var runs []entity.CronJobRun
+	for _, job := range jobs {
+		if job.Paused {
+			continue
+		}
+		schedule, err := cron.Parse(job.Schedule)
+		if err != nil {
+			continue
+		}
+		at := from.In(entity.LoadLocation(job.Timezone))
+		for i := 0; i < limit; i++ {
+			if at = schedule.Next(at); at.IsZero() {
+				break
+			}
+			runs = append(runs, entity.CronJobRun{Job: job, At: at})
+		}
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].At.Before(runs[j].At)
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
+
+// SetSchedule checks the cron expression before saving it.
+func (c *cronJobService) SetSchedule(ctx context.Context, id int, expr string) (*entity.CronJob, error) {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.update(ctx, id, func(job *entity.CronJob) {
+		job.Schedule = schedule.String()
+	})
+}
+
+func (c *cronJobService) TogglePause(ctx context.Context, id int) (*entity.CronJob, error) {
+	return c.update(ctx, id, func(job *entity.CronJob) {
+		job.Paused = !job.Paused
+	})
+}
+
+func (c *cronJobService) DeleteCronJob(ctx context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cronJobRepo.DeleteCronJob(ctx, id)
+}
+
+// Reschedule moves the next runs of the jobs of the channel after its timezone
+// changed.
+func (c *cronJobService) Reschedule(ctx context.Context, channelTgID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jobs, err := c.cronJobRepo.GetCronJobsByChannelID(ctx, channelTgID)
+	if err != nil {
+		c.log.Error("cronJobRepo.GetCronJobsByChannelID: %v", err)
+		return err
+	}
+	for key := range jobs {
+		if err = c.reschedule(ctx, &jobs[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// update applies fn to the job and saves it with the next run.
+func (c *cronJobService) update(ctx context.Context, id int, fn func(job *entity.CronJob)) (*entity.CronJob, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	job, err := c.cronJobRepo.GetCronJobByID(ctx, id)
+	if err != nil {
+		c.log.Error("cronJobRepo.GetCronJobByID: %v", err)
+		return nil, err
+	}
+
+	fn(job)
+	if err = c.reschedule(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (c *cronJobService) reschedule(ctx context.Context, job *entity.CronJob) error {
+	job.NextRunAt = nil
+	if !job.Paused {
+		var err error
+		if job.NextRunAt, err = nextCronJobRun(job, time.Now()); err != nil {
+			c.log.Error("failed to schedule cron job %d: %v", job.ID, err)
+			return err
+		}
+	}
+
+	if err := c.cronJobRepo.UpdateCronJobSchedule(ctx, job); err != nil {
+		c.log.Error("cronJobRepo.UpdateCronJobSchedule: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RunDue runs every job whose time has come. Runs missed while the bot was
+// down are made once, not once per missed time.
+func (c *cronJobService) RunDue(ctx context.Context, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jobs, err := c.cronJobRepo.GetDueCronJobs(ctx, now)
+	if err != nil {
+		c.log.Error("cronJobRepo.GetDueCronJobs: %v", err)
+		return err
+	}
+
+	var firstErr error
+	for key := range jobs {
+		if err = c.run(ctx, &jobs[key], now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (c *cronJobService) run(ctx context.Context, job *entity.CronJob, now time.Time) error {
+	result, err := c.execute(ctx, job, now)
+	if err != nil {
+		c.log.Error("cron job %d (%s) of %d failed: %v", job.ID, job.Kind, job.ChannelTgID, err)
+		result = err.Error()
+		// the same question would be taken again, so the job waits for the
+		// admin to fix it
+		var preflightErr *entity.PreflightError
+		if errors.As(err, &preflightErr) {
+			job.Paused = true
+		}
+	}
+	job.LastRunAt = &now
+	job.LastResult = &result
+	job.LastFailed = err != nil
+
+	job.NextRunAt = nil
+	if !job.Paused {
+		if job.NextRunAt, err = nextCronJobRun(job, now); err != nil {
+			c.log.Error("failed to schedule cron job %d: %v", job.ID, err)
+			text := err.Error()
+			job.LastResult = &text
+			job.LastFailed = true
+		}
+	}
+
+	if err = c.cronJobRepo.UpdateCronJobRun(ctx, job); err != nil {
+		c.log.Error("cronJobRepo.UpdateCronJobRun: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// execute does the work of the job and describes the outcome for the admins.
+func (c *cronJobService) execute(ctx context.Context, job *entity.CronJob, now time.Time) (string, error) {
+	switch job.Kind {
+	case entity.CronJobPostQuestion:
+		id, err := c.cronJobRepo.NextUnsentQuestion(ctx, job.ChannelTgID)
+		if errors.Is(err, customErr.ErrNoRows) {
+			return "", errors.New("нет неотправленных вопросов")
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if _, err = c.publishService.PublishScheduled(ctx, &entity.Publication{
+			QuestionID: id,
+			Scoring:    entity.ScoringSeparate,
+			ChannelIDs: []int64{job.ChannelTgID},
+			CreatedBy:  job.CreatedBy,
+		}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("опубликован вопрос #%d", id), nil
+
+	case entity.CronJobCloseQuestions:
+		ids, err := c.cronJobRepo.GetOpenQuestionIDs(ctx, job.ChannelTgID)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range ids {
+			if err = c.publishService.Close(ctx, id); err != nil {
+				return "", err
+			}
+		}
+		if len(ids) == 0 {
+			return "открытых вопросов нет", nil
+		}
+		return fmt.Sprintf("закрыто вопросов: %d", len(ids)), nil
+
+	case entity.CronJobLeaderboard:
+		rated, err := c.publishService.PostLeaderboard(ctx, job.ChannelTgID, "Рейтинг текущего сезона:",
+			&entity.ExportFilter{CurrentSeason: true}, cronLeaderboardTop)
+		if err != nil {
+			return "", err
+		}
+		if rated == 0 {
+			return "в рейтинге пока никого, публикация пропущена", nil
+		}
+		return fmt.Sprintf("опубликован рейтинг, участников: %d", rated), nil
+
+	case entity.CronJobWeeklyReport:
+		sent, err := c.sendReport(ctx, job, now)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("отчёт отправлен администраторам: %d", sent), nil
+	}
+
+	return "", fmt.Errorf("unknown job kind %q", job.Kind)
+}
+
+// sendReport sends the results of the last week to every admin as a workbook.
+// The week ends at the midnight of the run day in the channel timezone.
+func (c *cronJobService) sendReport(ctx context.Context, job *entity.CronJob, now time.Time) (int, error) {
+	location := entity.LoadLocation(job.Timezone)
+	local := now.In(location)
+	to := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	from := to.AddDate(0, 0, -cronReportDays)
+	filter := &entity.ExportFilter{DateField: entity.ExportByAnsweredAt, From: &from, To: &to, Timezone: job.Timezone}
+	channelID := int(job.ChannelTgID)
+
+	exporter, err := export.NewExporter(export.FormatXLSX, c.excel, "cron")
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	if err = exporter.Export(&buf, export.Dataset{
+		Results: func(yield func(*entity.UserResult) error) error {
+			return c.quizRepo.StreamUserResultsByChannelID(ctx, channelID, filter, yield)
+		},
+		Leaderboard: func(yield func(*entity.LeaderboardRow) error) error {
+			return c.quizRepo.StreamLeaderboardByChannelID(ctx, channelID, filter, yield)
+		},
+		Statistics: func(yield func(*entity.QuestionStatistics) error) error {
+			return c.quizRepo.StreamQuestionStatisticsByChannelID(ctx, channelID, filter, yield)
+		},
+		Location: location,
+	}); err != nil {
+		return 0, err
+	}
+
+	name := fmt.Sprint(job.ChannelTgID)
+	if channel, err := c.channelRepo.GetByChannelID(ctx, job.ChannelTgID); err == nil {
+		name = channel.ChannelName
+	}
+
+	admins, err := c.userRepo.GetAllAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int
+	for _, admin := range admins {
+		if _, err = c.tgMsg.SendDocument(admin.ID,
+			fmt.Sprintf("weekly_report_%d_%s.%s", job.ChannelTgID, local.Format("20060102"), exporter.Ext()),
+			bytes.NewReader(buf.Bytes()),
+			fmt.Sprintf("Отчёт канала «%s» за неделю, %s", html.EscapeString(name), filter.String())); err != nil {
+			c.log.Error("failed to send weekly report to admin %d: %v", admin.ID, err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 && len(admins) > 0 {
+		return 0, errors.New("не удалось отправить отчёт ни одному администратору")
+	}
+
+	return sent, nil
+}
+
+func nextCronJobRun(job *entity.CronJob, now time.Time) (*time.Time, error) {
+	schedule, err := cron.Parse(job.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	next := schedule.Next(now.In(entity.LoadLocation(job.Timezone)))
+	if next.IsZero() {
+		return nil, cron.ErrNeverFires
+	}
+	return &next, nil
+}
@@ -8,6 +8,10 @@ func updateArgsToModel(args entity.Args) []entity.Answer {
 		answer[key] = entity.Answer{
 			Answer:         value.Answer,
 			CostOfResponse: value.Cost,
+			IsCorrect:      value.Cost > 0,
+		}
+		if value.Correct != nil {
+			answer[key].IsCorrect = *value.Correct
 		}
 	}
 
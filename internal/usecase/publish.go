@@ -0,0 +1,481 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	customMsg "github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	checkBotAdmin     = "Бот — администратор канала с правом публикации"
+	checkChannelState = "Статус канала в базе — administrator"
+	checkAnswers      = "У вопроса есть варианты ответа"
+	checkMedia        = "Медиафайл доступен"
+	checkTrash        = "Вопрос не в корзине"
+	checkTargets      = "Выбраны каналы для публикации"
+	checkChannel      = "Канал найден"
+)
+
+type PublishService interface {
+	Preflight(ctx context.Context, publication *entity.Publication) (*entity.Preflight, error)
+	Publish(ctx context.Context, publication *entity.Publication) (*entity.PublishResult, error)
+	PublishScheduled(ctx context.Context, publication *entity.Publication) (*entity.PublishResult, error)
+	RefreshPostKeyboards(ctx context.Context, questionID int) error
+	SyncPosts(ctx context.Context, questionID int) (*entity.PostSync, error)
+	Close(ctx context.Context, questionID int) error
+	CloseOverdue(ctx context.Context, now time.Time) error
+	RevealResults(ctx context.Context, questionID int, channelTgID int64, since *time.Time) error
+	PostLeaderboard(ctx context.Context, channelTgID int64, title string, filter *entity.ExportFilter, top int) (int, error)
+}
+
+type publishService struct {
+	quizRepo            repo.QuizRepo
+	channelRepo         repo.ChannelRepo
+	channelSettingsRepo repo.ChannelSettingsRepo
+	tgMsg               customMsg.Message
+	log                 *logger.Logger
+}
+
+func NewPublishService(quizRepo repo.QuizRepo, channelRepo repo.ChannelRepo, channelSettingsRepo repo.ChannelSettingsRepo,
+	tgMsg customMsg.Message, log *logger.Logger) (PublishService, error) {
+	if quizRepo == nil {
+		return nil, errors.New("nil quizRepo")
+	}
+	if channelRepo == nil {
+		return nil, errors.New("nil channelRepo")
+	}
+	if channelSettingsRepo == nil {
+		return nil, errors.New("nil channelSettingsRepo")
+	}
+	if tgMsg == nil {
+		return nil, errors.New("nil tgMsg")
+	}
+	if log == nil {
+		return nil, errors.New("nil logger")
+	}
+
+	return &publishService{
+		quizRepo:            quizRepo,
+		channelRepo:         channelRepo,
+		channelSettingsRepo: channelSettingsRepo,
+		tgMsg:               tgMsg,
+		log:                 log,
+	}, nil
+}
+
+// Preflight checks that the question can be posted to the channels of the
+// publication. Errors block publishing, warnings can be skipped by the admin.
+func (p *publishService) Preflight(ctx context.Context, publication *entity.Publication) (*entity.Preflight, error) {
+	quiz, err := p.quizRepo.GetQuizByQuestionID(ctx, publication.QuestionID)
+	if err != nil {
+		p.log.Error("failed to get quiz by id: %v", err)
+		return nil, err
+	}
+
+	preflight := &entity.Preflight{
+		QuestionID:   publication.QuestionID,
+		ChannelTgIDs: publication.ChannelIDs,
+	}
+
+	if quiz.Question.DeletedAt != nil {
+		preflight.Add(checkTrash, entity.CheckError, "восстановите вопрос из корзины")
+	}
+
+	if len(publication.ChannelIDs) == 0 {
+		preflight.Add(checkTargets, entity.CheckError, "не выбран ни один канал")
+	}
+
+	for _, channelID := range publication.ChannelIDs {
+		channel, err := p.channelRepo.GetByChannelID(ctx, channelID)
+		if err != nil {
+			p.log.Error("channelRepo.GetByChannelID: %v", err)
+			detail := err.Error()
+			if errors.Is(err, customErr.ErrNoRows) {
+				detail = "канала нет в базе"
+			}
+			preflight.Add(checkName(checkChannel, &entity.Channel{ChannelName: fmt.Sprint(channelID)}, true),
+				entity.CheckError, detail)
+			continue
+		}
+
+		p.checkBotMember(preflight, channel, len(publication.ChannelIDs) > 1)
+		p.checkChannelStatus(preflight, channel, len(publication.ChannelIDs) > 1)
+	}
+
+	if len(quiz.Answer) == 0 {
+		preflight.Add(checkAnswers, entity.CheckError, "на вопрос без вариантов нельзя ответить")
+	} else {
+		preflight.Add(checkAnswers, entity.CheckOK, fmt.Sprintf("вариантов: %d", len(quiz.Answer)))
+	}
+
+	if quiz.Question.FileID != nil {
+		p.checkMedia(preflight, *quiz.Question.FileID)
+	}
+
+	return preflight, nil
+}
+
+// checkName adds the channel to the name of a check when several channels are
+// checked.
+func checkName(name string, channel *entity.Channel, several bool) string {
+	if !several {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, channel.ChannelName)
+}
+
+func (p *publishService) checkBotMember(preflight *entity.Preflight, channel *entity.Channel, several bool) {
+	name := checkName(checkBotAdmin, channel, several)
+
+	member, err := p.tgMsg.GetBotChatMember(channel.TgID)
+	switch {
+	case err != nil:
+		preflight.Add(name, entity.CheckError, err.Error())
+	case !member.IsAdministrator() && !member.IsCreator():
+		preflight.Add(name, entity.CheckError, "статус бота в канале: "+member.Status)
+	case !member.IsCreator() && !member.CanPostMessages:
+		preflight.Add(name, entity.CheckError, "у бота нет права публиковать сообщения")
+	default:
+		preflight.Add(name, entity.CheckOK, "")
+	}
+}
+
+func (p *publishService) checkChannelStatus(preflight *entity.Preflight, channel *entity.Channel, several bool) {
+	name := checkName(checkChannelState, channel, several)
+
+	if channel.ChannelStatus != entity.StatusAdministrator {
+		preflight.Add(name, entity.CheckWarning, "в базе записан статус "+string(channel.ChannelStatus))
+		return
+	}
+	preflight.Add(name, entity.CheckOK, "")
+}
+
+// fileTooBig is the getFile error for files larger than 20 MB
+const fileTooBig = "file is too big"
+
+func (p *publishService) checkMedia(preflight *entity.Preflight, fileID string) {
+	if _, err := p.tgMsg.GetFile(fileID); err != nil {
+		// getFile refuses files larger than 20 MB, but they can still be sent by
+		// file_id, so the admin decides. Any other error means the file is gone
+		var tgErr *tgbotapi.Error
+		if errors.As(err, &tgErr) && tgErr.Code == http.StatusBadRequest && strings.Contains(tgErr.Message, fileTooBig) {
+			preflight.Add(checkMedia, entity.CheckWarning, "Telegram не отдал файл: "+tgErr.Message+
+				". Файлы больше 20 МБ публикуются, проверьте остальные")
+			return
+		}
+		preflight.Add(checkMedia, entity.CheckError, err.Error())
+		return
+	}
+	preflight.Add(checkMedia, entity.CheckOK, "")
+}
+
+// Publish posts the question to every channel of the publication laid out by
+// the settings of that channel. A channel that fails doesn't stop the others,
+// an error is returned only when the question wasn't posted anywhere.
+func (p *publishService) Publish(ctx context.Context, publication *entity.Publication) (*entity.PublishResult, error) {
+	quiz, err := p.quizRepo.GetQuizByQuestionID(ctx, publication.QuestionID)
+	if err != nil {
+		p.log.Error("failed to get quiz by id: %v", err)
+		return nil, err
+	}
+
+	var (
+		result   = &entity.PublishResult{}
+		firstErr error
+	)
+	for _, channelID := range publication.ChannelIDs {
+		name := fmt.Sprint(channelID)
+		if channel, err := p.channelRepo.GetByChannelID(ctx, channelID); err == nil {
+			name = channel.ChannelName
+		}
+
+		settings, err := getChannelSettings(ctx, p.channelSettingsRepo, channelID)
+		if err != nil {
+			p.log.Error("failed to get settings of %d: %v", channelID, err)
+			return nil, err
+		}
+		layout := settings.NewLayout(quiz.Question, quiz.Answer)
+
+		messageID, err := p.tgMsg.SendMessageToUser(channelID, quiz, layout)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", name, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		// the publication is saved with the first post, so a publication that
+		// failed everywhere leaves nothing behind
+		if len(result.Published) == 0 {
+			if err = p.quizRepo.CreatePublication(ctx, publication); err != nil {
+				p.log.Error("failed to create publication: %v", err)
+				return nil, err
+			}
+		}
+
+		if err = p.quizRepo.CreateQuestionPost(ctx, &entity.QuestionPost{
+			QuestionID:    publication.QuestionID,
+			ChatID:        channelID,
+			MessageID:     messageID,
+			FileID:        quiz.Question.FileID,
+			PublicationID: &publication.ID,
+			Layout:        *layout,
+		}); err != nil {
+			p.log.Error("failed to save question post: %v", err)
+			return nil, err
+		}
+		result.Published = append(result.Published, name)
+	}
+
+	if len(result.Published) == 0 {
+		return nil, firstErr
+	}
+
+	if err = p.quizRepo.SetSendStatus(ctx, publication.QuestionID); err != nil {
+		p.log.Error("failed to set quiz status: %v", err)
+		return nil, err
+	}
+
+	if err = p.setDefaultDeadline(ctx, publication.QuestionID, quiz.Question.ChannelID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// PublishScheduled publishes a question no admin is watching over, as series,
+// autopilot and cron jobs do. A publication that fails the preflight isn't
+// posted, *entity.PreflightError is returned instead. Warnings don't stop it.
+func (p *publishService) PublishScheduled(ctx context.Context, publication *entity.Publication) (*entity.PublishResult, error) {
+	preflight, err := p.Preflight(ctx, publication)
+	if err != nil {
+		return nil, err
+	}
+	if preflight.HasErrors() {
+		return nil, &entity.PreflightError{Preflight: preflight}
+	}
+
+	return p.Publish(ctx, publication)
+}
+
+// setDefaultDeadline gives a question without a deadline the default one of
+// its channel, counted from now.
+func (p *publishService) setDefaultDeadline(ctx context.Context, questionID int, channelTgID int64) error {
+	settings, err := getChannelSettings(ctx, p.channelSettingsRepo, channelTgID)
+	if err != nil {
+		p.log.Error("failed to get settings of %d: %v", channelTgID, err)
+		return err
+	}
+	if settings.DefaultDeadline <= 0 {
+		return nil
+	}
+
+	if err = p.quizRepo.SetDefaultDeadline(ctx, questionID, time.Now().Add(settings.DefaultDeadline)); err != nil {
+		p.log.Error("failed to set deadline of question %d: %v", questionID, err)
+		return err
+	}
+	return nil
+}
+
+// RefreshPostKeyboards puts the current answers of the question on all its
+// published posts. Every post is tried, the first error is returned.
+func (p *publishService) RefreshPostKeyboards(ctx context.Context, questionID int) error {
+	quiz, err := p.quizRepo.GetQuizByQuestionID(ctx, questionID)
+	if err != nil {
+		p.log.Error("failed to get quiz by id: %v", err)
+		return err
+	}
+
+	posts, err := p.quizRepo.GetQuestionPosts(ctx, questionID)
+	if err != nil {
+		p.log.Error("failed to get question posts: %v", err)
+		return err
+	}
+
+	var firstErr error
+	for _, post := range posts {
+		if err = p.tgMsg.EditQuizButtons(&post, postAnswers(quiz)); err != nil {
+			p.log.Error("failed to refresh buttons of post %d in %d: %v", post.MessageID, post.ChatID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// SyncPosts edits all published posts of the question to its current text,
+// media and answers. A post that can't be edited doesn't stop the others.
+func (p *publishService) SyncPosts(ctx context.Context, questionID int) (*entity.PostSync, error) {
+	quiz, err := p.quizRepo.GetQuizByQuestionID(ctx, questionID)
+	if err != nil {
+		p.log.Error("failed to get quiz by id: %v", err)
+		return nil, err
+	}
+
+	posts, err := p.quizRepo.GetQuestionPosts(ctx, questionID)
+	if err != nil {
+		p.log.Error("failed to get question posts: %v", err)
+		return nil, err
+	}
+
+	quiz.Answer = postAnswers(quiz)
+	sync := &entity.PostSync{Total: len(posts)}
+	for key := range posts {
+		post := &posts[key]
+		if err = p.tgMsg.EditQuizPost(post, quiz); err != nil {
+			p.log.Error("failed to sync post %d in %d: %v", post.MessageID, post.ChatID, err)
+			sync.Errors = append(sync.Errors, err.Error())
+			continue
+		}
+		sync.Updated++
+
+		if sameFile(post.FileID, quiz.Question.FileID) {
+			continue
+		}
+		if err = p.quizRepo.UpdateQuestionPostFile(ctx, post.ID, quiz.Question.FileID); err != nil {
+			p.log.Error("failed to update post file: %v", err)
+			return nil, err
+		}
+	}
+
+	return sync, nil
+}
+
+func sameFile(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// postAnswers is the buttons of the posts of the question, a closed question
+// has none.
+func postAnswers(quiz *entity.Quiz) []entity.Answer {
+	if quiz.Question.ClosedAt != nil {
+		return nil
+	}
+	return quiz.Answer
+}
+
+// Close stops the question from taking answers and removes the buttons from
+// its posts. Every post is tried, the first error is returned.
+func (p *publishService) Close(ctx context.Context, questionID int) error {
+	if err := p.quizRepo.CloseQuestion(ctx, questionID); err != nil {
+		p.log.Error("failed to close question: %v", err)
+		return err
+	}
+
+	return p.RefreshPostKeyboards(ctx, questionID)
+}
+
+// CloseOverdue closes the questions whose deadline has passed. Every question
+// is tried, the first error is returned.
+func (p *publishService) CloseOverdue(ctx context.Context, now time.Time) error {
+	ids, err := p.quizRepo.GetOverdueQuestionIDs(ctx, now)
+	if err != nil {
+		p.log.Error("failed to get overdue questions: %v", err)
+		return err
+	}
+
+	var firstErr error
+	for _, id := range ids {
+		if err = p.Close(ctx, id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// RevealResults posts the correct answer and how the answers of the channel
+// split since the given time.
+func (p *publishService) RevealResults(ctx context.Context, questionID int, channelTgID int64, since *time.Time) error {
+	var stat *entity.QuestionStatistics
+	if err := p.quizRepo.StreamQuestionStatisticsByChannelID(ctx, int(channelTgID),
+		&entity.ExportFilter{QuestionIDs: []int{questionID}, From: since},
+		func(s *entity.QuestionStatistics) error {
+			stat = s
+			return nil
+		}); err != nil {
+		p.log.Error("failed to get question statistics: %v", err)
+		return err
+	}
+	if stat == nil {
+		return nil
+	}
+
+	if _, err := p.tgMsg.SendNewMessage(channelTgID, nil, revealText(stat)); err != nil {
+		p.log.Error("failed to reveal results of question %d: %v", questionID, err)
+		return err
+	}
+
+	return nil
+}
+
+// PostLeaderboard posts the first top places of the channel under the title
+// and returns how many users are rated. Nothing is posted when there are none.
+func (p *publishService) PostLeaderboard(ctx context.Context, channelTgID int64, title string, filter *entity.ExportFilter, top int) (int, error) {
+	var sb strings.Builder
+	sb.WriteString(title + "\n")
+
+	var places int
+	if err := p.quizRepo.StreamLeaderboardByChannelID(ctx, int(channelTgID), filter,
+		func(row *entity.LeaderboardRow) error {
+			if places < top {
+				sb.WriteString(fmt.Sprintf("\n%d. %s — %d", row.Rank, html.EscapeString(row.Name()), row.TotalPoints))
+			}
+			places++
+			return nil
+		}); err != nil {
+		p.log.Error("failed to get leaderboard of %d: %v", channelTgID, err)
+		return 0, err
+	}
+	if places == 0 {
+		return 0, nil
+	}
+
+	if _, err := p.tgMsg.SendNewMessage(channelTgID, nil, sb.String()); err != nil {
+		p.log.Error("failed to post leaderboard to %d: %v", channelTgID, err)
+		return 0, err
+	}
+
+	return places, nil
+}
+
+func revealText(stat *entity.QuestionStatistics) string {
+	var total int
+	for _, answer := range stat.Answers {
+		total += answer.Picked
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Ответы приняты, итоги вопроса:\n")
+	sb.WriteString(stat.Question.HTML() + "\n\n")
+	for _, answer := range stat.Answers {
+		mark := "▫️"
+		if answer.IsCorrect {
+			mark = "✅"
+		}
+		percent := 0
+		if total > 0 {
+			percent = answer.Picked * 100 / total
+		}
+		sb.WriteString(fmt.Sprintf("%s %s — %d%%\n", mark, html.EscapeString(answer.Answer), percent))
+	}
+	sb.WriteString(fmt.Sprintf("\nУчастников: %d, правильных ответов: %.0f%%", stat.Participants, stat.CorrectPercent))
+
+	return sb.String()
+}
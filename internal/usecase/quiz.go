@@ -1,42 +1,67 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
 	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/question_bank"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/serialize"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/button"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/jackc/pgx/v5"
-	"unicode/utf8"
+	"time"
 )
 
 type QuizService interface {
 	CreateQuestion(ctx context.Context, tx pgx.Tx, question *entity.Question) (int, error)
+	CreateQuiz(ctx context.Context, quiz *entity.Quiz) (int, error)
+	ParseQuestionBank(fileName string, data []byte, channelID int64, userID int64) ([]entity.Quiz, []question_bank.RowError, error)
+	ImportQuizzes(ctx context.Context, quizzes []entity.Quiz) ([]int, error)
+	ExportQuestionBank(ctx context.Context, channelID int64) ([]question_bank.Question, error)
 	GetQuestionByID(ctx context.Context, id int) (*entity.Question, error)
-	UpdateQuestion(ctx context.Context, questionID int, question string) error
+	GetAllQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error)
+	UpdateQuestion(ctx context.Context, questionID int, changedBy int64, question string, entities []coverter.MessageEntity) error
 	DeleteQuestion(ctx context.Context, id int) error
+	GetDeletedQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error)
+	RestoreQuestion(ctx context.Context, id int) error
+	SetCountPoints(ctx context.Context, id int, countPoints bool) error
+	UpdateQuestionTags(ctx context.Context, id int, tags []string) error
+	UpdateQuestionDifficulty(ctx context.Context, id int, difficulty *int) error
+	UpdateQuestionButtonsPerRow(ctx context.Context, id int, buttonsPerRow *int) error
+	UpdateQuestionShuffle(ctx context.Context, id int, shuffle *bool) error
+	PurgeQuestion(ctx context.Context, id int) (*entity.QuestionSnapshot, error)
+	RestorePurgedQuestion(ctx context.Context, snapshot *entity.QuestionSnapshot) error
 	GetQuestionMarkup(ctx context.Context, method string, channelID int) (*tgbotapi.InlineKeyboardMarkup, error)
-	UpdateImage(ctx context.Context, questionID int, image string) error
+	UpdateImage(ctx context.Context, questionID int, changedBy int64, fileID string, mediaType entity.MediaType) error
+	DeleteImage(ctx context.Context, questionID int, changedBy int64) error
 	SetSendStatus(ctx context.Context, id int) error
 	GetChannelTgIDByQuestionID(ctx context.Context, questionID int) (int, error)
 
 	GetQuizByQuestionID(ctx context.Context, id int) (*entity.Quiz, error)
 	IsAnswerExists(ctx context.Context, questionID int) (bool, error)
-	QuizUpdateOldAnswer(ctx context.Context, text string, questionID int) error
-
-	UpdateUserResult(ctx context.Context, answerID int, userID int64) (int, error)
+	QuizUpdateOldAnswer(ctx context.Context, text string, questionID int, changedBy int64) error
+	GetQuestionVersions(ctx context.Context, questionID int) ([]entity.QuestionVersion, error)
+	RollbackQuestion(ctx context.Context, questionID int, version int, changedBy int64) error
+
+	GetAnswerChannel(ctx context.Context, answerID int, chatID int64, messageID int) (int64, error)
+	CloneQuestions(ctx context.Context, questionIDs []int, channelID int64, createdBy int64) ([]int, error)
+	UpdateUserResult(ctx context.Context, answerID int, userID int64, channelTgID int64, chatID int64) (int, error)
+	ChangeUserResult(ctx context.Context, answerID int, userID int64, channelTgID int64, window time.Duration) (int, bool, error)
 	CreateUserResult(ctx context.Context, userResult *entity.UserResult) error
-	GetAllUserResultsByChannelID(ctx context.Context, channelID int) ([]entity.UserResult, error)
-	ResetAllUserResult(ctx context.Context, channelTgID int) error
+	StreamUserResultsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(result *entity.UserResult) error) error
+	StreamLeaderboardByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(row *entity.LeaderboardRow) error) error
+	StreamQuestionStatisticsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(stat *entity.QuestionStatistics) error) error
 
 	CreateBooleanUserAnswer(ctx context.Context, answer *entity.IsUserAnswer) error
 	IsUserAnswerExists(ctx context.Context, userAnswer *entity.IsUserAnswer) (bool, error)
 
-	QuizUpdateAnswer(ctx context.Context, text string, questionID int) error
+	QuizUpdateAnswer(ctx context.Context, text string, questionID int, changedBy int64) error
 }
 
 type quizService struct {
@@ -70,22 +95,136 @@ func (q *quizService) IsAnswerExists(ctx context.Context, questionID int) (bool,
 	return q.quizRepo.IsAnswerExists(ctx, questionID)
 }
 
-func (q *quizService) UpdateImage(ctx context.Context, questionID int, image string) error {
-	return q.quizRepo.UpdateImage(ctx, questionID, image)
+func (q *quizService) UpdateImage(ctx context.Context, questionID int, changedBy int64, fileID string, mediaType entity.MediaType) error {
+	return q.versioned(ctx, questionID, changedBy, entity.VersionMedia, 0, func(tx pgx.Tx) error {
+		return q.quizRepo.UpdateImage(ctx, tx, questionID, fileID, mediaType)
+	})
+}
+
+func (q *quizService) DeleteImage(ctx context.Context, questionID int, changedBy int64) error {
+	return q.versioned(ctx, questionID, changedBy, entity.VersionMedia, 0, func(tx pgx.Tx) error {
+		return q.quizRepo.DeleteImage(ctx, tx, questionID)
+	})
 }
 
 func (q *quizService) GetQuestionByID(ctx context.Context, id int) (*entity.Question, error) {
 	return q.quizRepo.GetQuestionByID(ctx, id)
 }
 
-func (q *quizService) UpdateQuestion(ctx context.Context, questionID int, question string) error {
-	return q.quizRepo.UpdateQuestion(ctx, questionID, question)
+func (q *quizService) GetAllQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error) {
+	return q.quizRepo.GetAllQuestionsByChannelID(ctx, channelID)
+}
+
+func (q *quizService) UpdateQuestion(ctx context.Context, questionID int, changedBy int64, question string, entities []coverter.MessageEntity) error {
+	return q.versioned(ctx, questionID, changedBy, entity.VersionText, 0, func(tx pgx.Tx) error {
+		return q.quizRepo.UpdateQuestion(ctx, tx, questionID, question, entities)
+	})
+}
+
+func (q *quizService) GetQuestionVersions(ctx context.Context, questionID int) ([]entity.QuestionVersion, error) {
+	return q.quizRepo.GetQuestionVersions(ctx, questionID)
+}
+
+func (q *quizService) RollbackQuestion(ctx context.Context, questionID int, version int, changedBy int64) error {
+	versions, err := q.quizRepo.GetQuestionVersions(ctx, questionID)
+	if err != nil {
+		q.log.Error("quizRepo.GetQuestionVersions: %v", err)
+		return err
+	}
+
+	for key := range versions {
+		if versions[key].Version != version {
+			continue
+		}
+		content := versions[key].Content
+		return q.versioned(ctx, questionID, changedBy, entity.VersionRollback, version, func(tx pgx.Tx) error {
+			return q.quizRepo.RollbackQuestion(ctx, tx, questionID, &content)
+		})
+	}
+
+	return customErr.ErrNotFound
+}
+
+// versioned saves the content before the first tracked change of the question
+// and after every change, so each version holds the whole question. The
+// change and its versions are saved in one transaction under the question lock.
+func (q *quizService) versioned(ctx context.Context, questionID int, changedBy int64, change entity.VersionChange, rolledBackTo int, apply func(tx pgx.Tx) error) error {
+	return q.quizRepo.VersionedTx(ctx, questionID, func(tx pgx.Tx) error {
+		if err := q.quizRepo.CreateBaselineVersion(ctx, tx, questionID); err != nil {
+			q.log.Error("quizRepo.CreateBaselineVersion: %v", err)
+			return err
+		}
+
+		if err := apply(tx); err != nil {
+			return err
+		}
+
+		if err := q.quizRepo.CreateQuestionVersion(ctx, tx, questionID, changedBy, change, rolledBackTo); err != nil {
+			q.log.Error("quizRepo.CreateQuestionVersion: %v", err)
+			return err
+		}
+
+		return nil
+	})
 }
 
 func (q *quizService) DeleteQuestion(ctx context.Context, id int) error {
 	return q.quizRepo.DeleteQuestion(ctx, id)
 }
 
+func (q *quizService) GetDeletedQuestionsByChannelID(ctx context.Context, channelID int64) ([]entity.Question, error) {
+	return q.quizRepo.GetDeletedQuestionsByChannelID(ctx, channelID)
+}
+
+func (q *quizService) RestoreQuestion(ctx context.Context, id int) error {
+	return q.quizRepo.RestoreQuestion(ctx, id)
+}
+
+func (q *quizService) SetCountPoints(ctx context.Context, id int, countPoints bool) error {
+	return q.quizRepo.SetCountPoints(ctx, id, countPoints)
+}
+
+func (q *quizService) UpdateQuestionTags(ctx context.Context, id int, tags []string) error {
+	return q.quizRepo.UpdateQuestionTags(ctx, id, tags)
+}
+
+func (q *quizService) UpdateQuestionDifficulty(ctx context.Context, id int, difficulty *int) error {
+	return q.quizRepo.UpdateQuestionDifficulty(ctx, id, difficulty)
+}
+
+// UpdateQuestionButtonsPerRow changes the layout of new posts of the question,
+// nil follows the channel. Published posts keep their layout.
+func (q *quizService) UpdateQuestionButtonsPerRow(ctx context.Context, id int, buttonsPerRow *int) error {
+	return q.quizRepo.UpdateQuestionButtonsPerRow(ctx, id, buttonsPerRow)
+}
+
+// UpdateQuestionShuffle changes whether new posts of the question shuffle the
+// answers, nil follows the channel.
+func (q *quizService) UpdateQuestionShuffle(ctx context.Context, id int, shuffle *bool) error {
+	return q.quizRepo.UpdateQuestionShuffle(ctx, id, shuffle)
+}
+
+func (q *quizService) PurgeQuestion(ctx context.Context, id int) (*entity.QuestionSnapshot, error) {
+	snapshot, err := q.quizRepo.PurgeQuestion(ctx, id)
+	if err != nil {
+		q.log.Error("quizRepo.PurgeQuestion: %v", err)
+		return nil, err
+	}
+
+	q.log.Info("question %d of channel %d purged", id, snapshot.ChannelTgID)
+	return snapshot, nil
+}
+
+func (q *quizService) RestorePurgedQuestion(ctx context.Context, snapshot *entity.QuestionSnapshot) error {
+	if err := q.quizRepo.RestorePurgedQuestion(ctx, snapshot); err != nil {
+		q.log.Error("quizRepo.RestorePurgedQuestion: %v", err)
+		return err
+	}
+
+	q.log.Info("question %d of channel %d restored", snapshot.QuestionID, snapshot.ChannelTgID)
+	return nil
+}
+
 func (q *quizService) GetQuizByQuestionID(ctx context.Context, id int) (*entity.Quiz, error) {
 	return q.quizRepo.GetQuizByQuestionID(ctx, id)
 }
@@ -94,18 +233,60 @@ func (q *quizService) CreateUserResult(ctx context.Context, userResult *entity.U
 	return q.quizRepo.CreateUserResult(ctx, userResult)
 }
 
-func (q *quizService) GetAllUserResultsByChannelID(ctx context.Context, channelID int) ([]entity.UserResult, error) {
-	return q.quizRepo.GetAllUserResultsByChannelID(ctx, channelID)
+func (q *quizService) StreamUserResultsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(result *entity.UserResult) error) error {
+	return q.quizRepo.StreamUserResultsByChannelID(ctx, channelID, filter, fn)
+}
+
+func (q *quizService) StreamLeaderboardByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(row *entity.LeaderboardRow) error) error {
+	return q.quizRepo.StreamLeaderboardByChannelID(ctx, channelID, filter, fn)
 }
 
-func (q *quizService) ResetAllUserResult(ctx context.Context, channelTgID int) error {
-	return q.quizRepo.ResetAllUserResult(ctx, channelTgID)
+func (q *quizService) StreamQuestionStatisticsByChannelID(ctx context.Context, channelID int, filter *entity.ExportFilter, fn func(stat *entity.QuestionStatistics) error) error {
+	return q.quizRepo.StreamQuestionStatisticsByChannelID(ctx, channelID, filter, fn)
 }
 
 func (q *quizService) CreateQuestion(ctx context.Context, tx pgx.Tx, question *entity.Question) (int, error) {
 	return q.quizRepo.CreateQuestion(ctx, tx, question)
 }
 
+func (q *quizService) CreateQuiz(ctx context.Context, quiz *entity.Quiz) (int, error) {
+	return q.quizRepo.CreateQuiz(ctx, quiz)
+}
+
+func (q *quizService) ParseQuestionBank(fileName string, data []byte, channelID int64, userID int64) ([]entity.Quiz, []question_bank.RowError, error) {
+	questions, rowErrors, err := question_bank.Decode(fileName, bytes.NewReader(data))
+	if err != nil {
+		q.log.Error("question_bank.Decode: %v", err)
+		return nil, nil, err
+	}
+
+	quizzes := make([]entity.Quiz, len(questions))
+	for key := range questions {
+		quizzes[key] = questions[key].ToQuiz(channelID, userID)
+	}
+
+	return quizzes, rowErrors, nil
+}
+
+func (q *quizService) ImportQuizzes(ctx context.Context, quizzes []entity.Quiz) ([]int, error) {
+	return q.quizRepo.CreateQuizzes(ctx, quizzes)
+}
+
+func (q *quizService) ExportQuestionBank(ctx context.Context, channelID int64) ([]question_bank.Question, error) {
+	quizzes, err := q.quizRepo.GetAllQuizzesByChannelID(ctx, channelID)
+	if err != nil {
+		q.log.Error("quizRepo.GetAllQuizzesByChannelID: %v", err)
+		return nil, err
+	}
+
+	questions := make([]question_bank.Question, len(quizzes))
+	for key := range quizzes {
+		questions[key] = question_bank.FromQuiz(quizzes[key])
+	}
+
+	return questions, nil
+}
+
 func (q *quizService) GetQuestionMarkup(ctx context.Context, method string, channelID int) (*tgbotapi.InlineKeyboardMarkup, error) {
 	questions, err := q.quizRepo.GetAllQuestionsByChannelID(ctx, int64(channelID))
 	if err != nil {
@@ -115,7 +296,49 @@ func (q *quizService) GetQuestionMarkup(ctx context.Context, method string, chan
 	return q.createQuestionMarkup(questions, method)
 }
 
-func (q *quizService) UpdateUserResult(ctx context.Context, answerID int, userID int64) (int, error) {
+// CloneQuestions copies the questions with their answers and media to the
+// channel as unsent drafts. Either all questions are copied or none.
+func (q *quizService) CloneQuestions(ctx context.Context, questionIDs []int, channelID int64, createdBy int64) ([]int, error) {
+	quizzes := make([]entity.Quiz, 0, len(questionIDs))
+	for _, id := range questionIDs {
+		quiz, err := q.quizRepo.GetQuizByQuestionID(ctx, id)
+		if err != nil {
+			q.log.Error("failed to get quiz by id: %v", err)
+			return nil, err
+		}
+
+		quizzes = append(quizzes, entity.Quiz{
+			Question: entity.Question{
+				CreatedByUser:    createdBy,
+				QuestionName:     quiz.Question.QuestionName,
+				QuestionEntities: quiz.Question.QuestionEntities,
+				TextFormat:       quiz.Question.TextFormat,
+				FileID:           quiz.Question.FileID,
+				MediaType:        quiz.Question.MediaType,
+				ChannelID:        channelID,
+				Tags:             quiz.Question.Tags,
+				Difficulty:       quiz.Question.Difficulty,
+				ButtonsPerRow:    quiz.Question.ButtonsPerRow,
+				ShuffleAnswers:   quiz.Question.ShuffleAnswers,
+			},
+			Answer: quiz.Answer,
+		})
+	}
+
+	ids, err := q.quizRepo.CreateQuizzes(ctx, quizzes)
+	if err != nil {
+		q.log.Error("failed to create quizzes: %v", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (q *quizService) GetAnswerChannel(ctx context.Context, answerID int, chatID int64, messageID int) (int64, error) {
+	return q.quizRepo.GetAnswerChannel(ctx, answerID, chatID, messageID)
+}
+
+func (q *quizService) UpdateUserResult(ctx context.Context, answerID int, userID int64, channelTgID int64, chatID int64) (int, error) {
 	costOfResponse, questionID, err := q.quizRepo.GetAnswerByID(ctx, answerID)
 	if err != nil {
 		q.log.Error("failed to get answer: %v", err)
@@ -123,9 +346,12 @@ func (q *quizService) UpdateUserResult(ctx context.Context, answerID int, userID
 	}
 
 	if err := q.quizRepo.CreateUserResult(ctx, &entity.UserResult{
-		UserID:     userID,
-		Points:     costOfResponse,
-		QuestionID: questionID,
+		UserID:      userID,
+		Points:      costOfResponse,
+		QuestionID:  questionID,
+		AnswerID:    answerID,
+		ChannelTgID: channelTgID,
+		ChatID:      chatID,
 	}); err != nil {
 		q.log.Error("failed to create user result: %v", err)
 		return 0, err
@@ -134,6 +360,31 @@ func (q *quizService) UpdateUserResult(ctx context.Context, answerID int, userID
 	return costOfResponse, nil
 }
 
+// ChangeUserResult moves the answer of the user to another answer of the
+// question when the first one was given within the window. changed is false
+// when the window has passed or the same answer was picked again.
+func (q *quizService) ChangeUserResult(ctx context.Context, answerID int, userID int64, channelTgID int64, window time.Duration) (int, bool, error) {
+	costOfResponse, questionID, err := q.quizRepo.GetAnswerByID(ctx, answerID)
+	if err != nil {
+		q.log.Error("failed to get answer: %v", err)
+		return 0, false, err
+	}
+
+	changed, err := q.quizRepo.ChangeUserResult(ctx, &entity.UserResult{
+		UserID:      userID,
+		Points:      costOfResponse,
+		QuestionID:  questionID,
+		AnswerID:    answerID,
+		ChannelTgID: channelTgID,
+	}, window)
+	if err != nil {
+		q.log.Error("failed to change user result: %v", err)
+		return 0, false, err
+	}
+
+	return costOfResponse, changed, nil
+}
+
 func (q *quizService) CreateBooleanUserAnswer(ctx context.Context, answer *entity.IsUserAnswer) error {
 	return q.quizRepo.CreateBooleanUserAnswer(ctx, answer)
 }
@@ -142,34 +393,36 @@ func (q *quizService) IsUserAnswerExists(ctx context.Context, userAnswer *entity
 	return q.quizRepo.IsUserAnswerExists(ctx, userAnswer)
 }
 
-func (q *quizService) QuizUpdateAnswer(ctx context.Context, text string, questionID int) error {
+func (q *quizService) QuizUpdateAnswer(ctx context.Context, text string, questionID int, changedBy int64) error {
 	args, err := serialize.ParseJSON[entity.Args](text)
 	if err != nil {
 		q.log.Error("ParseJSON: %v", err)
 		return err
 	}
 
-	if _, err := q.quizRepo.CreateAnswers(ctx, nil, updateArgsToModel(args), questionID); err != nil {
-		q.log.Error("isStoreExist::store.QuizCreate:CreateAnswers: %v", err)
-		return err
-	}
-
-	return nil
+	return q.versioned(ctx, questionID, changedBy, entity.VersionAnswers, 0, func(tx pgx.Tx) error {
+		if _, err := q.quizRepo.CreateAnswers(ctx, tx, updateArgsToModel(args), questionID); err != nil {
+			q.log.Error("isStoreExist::store.QuizCreate:CreateAnswers: %v", err)
+			return err
+		}
+		return nil
+	})
 }
 
-func (q *quizService) QuizUpdateOldAnswer(ctx context.Context, text string, questionID int) error {
+func (q *quizService) QuizUpdateOldAnswer(ctx context.Context, text string, questionID int, changedBy int64) error {
 	args, err := serialize.ParseJSON[entity.Args](text)
 	if err != nil {
 		q.log.Error("ParseJSON: %v", err)
 		return err
 	}
 
-	if err := q.quizRepo.DeleteAndInsertNewAnswers(ctx, updateArgsToModel(args), questionID); err != nil {
-		q.log.Error("isStoreExist::store.QuizCreate:CreateAnswers: %v", err)
-		return err
-	}
-
-	return nil
+	return q.versioned(ctx, questionID, changedBy, entity.VersionAnswers, 0, func(tx pgx.Tx) error {
+		if err := q.quizRepo.UpdateAnswers(ctx, tx, updateArgsToModel(args), questionID); err != nil {
+			q.log.Error("isStoreExist::store.QuizCreate:UpdateAnswers: %v", err)
+			return err
+		}
+		return nil
+	})
 }
 
 func (q *quizService) createQuestionMarkup(questions []entity.Question, method string) (*tgbotapi.InlineKeyboardMarkup, error) {
@@ -186,12 +439,12 @@ func (q *quizService) createQuestionMarkup(questions []entity.Question, method s
 			isSendStr = "Не отправлено"
 		}
 
-		name := el.QuestionName
-		if utf8.RuneCountInString(el.QuestionName) > 10 {
-			name = el.QuestionName[:10]
+		name := []rune(el.PlainText())
+		if len(name) > 10 {
+			name = name[:10]
 		}
 
-		btn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s - [%s]", name, isSendStr),
+		btn := tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s - [%s]", string(name), isSendStr),
 			fmt.Sprintf("question_%s_%d", method, el.ID))
 
 		row = append(row, btn)
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+)
+
+type SeasonService interface {
+	CloseSeason(ctx context.Context, channelTgID int64) (*entity.Season, error)
+	ReopenSeason(ctx context.Context, seasonID int) error
+	GetSeasonByID(ctx context.Context, id int) (*entity.Season, error)
+	GetSeasonsByChannelID(ctx context.Context, channelTgID int64) ([]entity.Season, error)
+	StreamStandingsByChannelID(ctx context.Context, channelTgID int64, fn func(standing *entity.SeasonStanding) error) error
+}
+
+type seasonService struct {
+	seasonRepo repo.SeasonRepo
+	log        *logger.Logger
+}
+
+func NewSeasonService(seasonRepo repo.SeasonRepo, log *logger.Logger) (SeasonService, error) {
+	if seasonRepo == nil {
+		return nil, errors.New("nil seasonRepo")
+	}
+	if log == nil {
+		return nil, errors.New("nil logger")
+	}
+
+	return &seasonService{
+		seasonRepo: seasonRepo,
+		log:        log,
+	}, nil
+}
+
+func (s *seasonService) CloseSeason(ctx context.Context, channelTgID int64) (*entity.Season, error) {
+	season, err := s.seasonRepo.CloseSeason(ctx, channelTgID)
+	if err != nil {
+		s.log.Error("seasonRepo.CloseSeason: %v", err)
+		return nil, err
+	}
+
+	s.log.Info("season %d of channel %d closed, participants: %d", season.Number, channelTgID, season.Participants)
+	return season, nil
+}
+
+func (s *seasonService) ReopenSeason(ctx context.Context, seasonID int) error {
+	if err := s.seasonRepo.ReopenSeason(ctx, seasonID); err != nil {
+		s.log.Error("seasonRepo.ReopenSeason: %v", err)
+		return err
+	}
+
+	s.log.Info("season %d reopened", seasonID)
+	return nil
+}
+
+func (s *seasonService) GetSeasonByID(ctx context.Context, id int) (*entity.Season, error) {
+	return s.seasonRepo.GetSeasonByID(ctx, id)
+}
+
+func (s *seasonService) GetSeasonsByChannelID(ctx context.Context, channelTgID int64) ([]entity.Season, error) {
+	return s.seasonRepo.GetSeasonsByChannelID(ctx, channelTgID)
+}
+
+func (s *seasonService) StreamStandingsByChannelID(ctx context.Context, channelTgID int64, fn func(standing *entity.SeasonStanding) error) error {
+	return s.seasonRepo.StreamStandingsByChannelID(ctx, channelTgID, fn)
+}
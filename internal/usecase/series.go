@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/internal/repo"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
+	"html"
+	"sync"
+	"time"
+)
+
+// seriesFinalTop is how many places the final rating of a series posts to the
+// channel.
+const seriesFinalTop = 10
+
+type SeriesService interface {
+	CreateSeries(ctx context.Context, series *entity.Series) error
+	GetSeriesByID(ctx context.Context, id int) (*entity.Series, error)
+	GetSeriesByChannelID(ctx context.Context, channelTgID int64) ([]entity.Series, error)
+	DeleteSeries(ctx context.Context, id int) error
+
+	Start(ctx context.Context, id int) (*entity.Series, error)
+	Pause(ctx context.Context, id int) (*entity.Series, error)
+	Resume(ctx context.Context, id int) (*entity.Series, error)
+	Skip(ctx context.Context, id int) (*entity.Series, error)
+	ToggleReveal(ctx context.Context, id int) (*entity.Series, error)
+	RunDue(ctx context.Context, now time.Time) error
+}
+
+type seriesService struct {
+	seriesRepo     repo.SeriesRepo
+	publishService PublishService
+	log            *logger.Logger
+
+	// mu keeps the scheduler and the admins from moving a series at once
+	mu sync.Mutex
+}
+
+func NewSeriesService(seriesRepo repo.SeriesRepo, publishService PublishService, log *logger.Logger) (SeriesService, error) {
+	if seriesRepo == nil {
+		return nil, errors.New("nil seriesRepo")
+	}
+	if publishService == nil {
+		return nil, errors.New("nil publishService")
+	}
+	if log == nil {
+		return nil, errors.New("nil logger")
+	}
+
+	return &seriesService{
+		seriesRepo:     seriesRepo,
+		publishService: publishService,
+		log:            log,
+	}, nil
+}
+
+func (s *seriesService) CreateSeries(ctx context.Context, series *entity.Series) error {
+	if len(series.QuestionIDs) == 0 {
+		return customErr.ErrInvalidRequest
+	}
+	return s.seriesRepo.CreateSeries(ctx, series)
+}
+
+func (s *seriesService) GetSeriesByID(ctx context.Context, id int) (*entity.Series, error) {
+	return s.seriesRepo.GetSeriesByID(ctx, id)
+}
+
+func (s *seriesService) GetSeriesByChannelID(ctx context.Context, channelTgID int64) ([]entity.Series, error) {
+	return s.seriesRepo.GetSeriesByChannelID(ctx, channelTgID)
+}
+
+func (s *seriesService) DeleteSeries(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.seriesRepo.DeleteSeries(ctx, id)
+}
+
+// Start posts the first question of a draft series.
+func (s *seriesService) Start(ctx context.Context, id int) (*entity.Series, error) {
+	return s.change(ctx, id, func(series *entity.Series, now time.Time) error {
+		if series.Status != entity.SeriesDraft {
+			return customErr.ErrSeriesState
+		}
+
+		series.Status = entity.SeriesRunning
+		series.StartedAt = &now
+		return s.advance(ctx, series, now)
+	})
+}
+
+// Pause stops the clock of the current question, its post keeps taking answers.
+func (s *seriesService) Pause(ctx context.Context, id int) (*entity.Series, error) {
+	return s.change(ctx, id, func(series *entity.Series, now time.Time) error {
+		if series.Status != entity.SeriesRunning {
+			return customErr.ErrSeriesState
+		}
+
+		series.Status = entity.SeriesPaused
+		series.PausedAt = &now
+		return s.seriesRepo.UpdateSeriesState(ctx, series)
+	})
+}
+
+// Resume gives the current question the time it had left at the pause. After
+// a failed post the next question is tried right away.
+func (s *seriesService) Resume(ctx context.Context, id int) (*entity.Series, error) {
+	return s.change(ctx, id, func(series *entity.Series, now time.Time) error {
+		if series.Status != entity.SeriesPaused {
+			return customErr.ErrSeriesState
+		}
+
+		next := now
+		if series.LastError == nil && series.NextRunAt != nil && series.PausedAt != nil {
+			next = series.NextRunAt.Add(now.Sub(*series.PausedAt))
+		}
+		series.Status = entity.SeriesRunning
+		series.NextRunAt = &next
+		series.PausedAt = nil
+		series.LastError = nil
+
+		if !next.After(now) {
+			return s.advance(ctx, series, now)
+		}
+		return s.seriesRepo.UpdateSeriesState(ctx, series)
+	})
+}
+
+// Skip closes the current question and posts the next one now. A paused
+// series stays paused with the full interval for the new question.
+func (s *seriesService) Skip(ctx context.Context, id int) (*entity.Series, error) {
+	return s.change(ctx, id, func(series *entity.Series, now time.Time) error {
+		if series.Status != entity.SeriesRunning && series.Status != entity.SeriesPaused {
+			return customErr.ErrSeriesState
+		}
+
+		series.LastError = nil
+		return s.advance(ctx, series, now)
+	})
+}
+
+func (s *seriesService) ToggleReveal(ctx context.Context, id int) (*entity.Series, error) {
+	return s.change(ctx, id, func(series *entity.Series, now time.Time) error {
+		if series.Status == entity.SeriesFinished {
+			return customErr.ErrSeriesState
+		}
+
+		series.RevealResults = !series.RevealResults
+		return s.seriesRepo.UpdateSeriesState(ctx, series)
+	})
+}
+
+// RunDue moves every running series whose question is over. A series that
+// fails doesn't stop the others, the first error is returned.
+func (s *seriesService) RunDue(ctx context.Context, now time.Time) error {
+	ids, err := s.seriesRepo.GetDueSeriesIDs(ctx, now)
+	if err != nil {
+		s.log.Error("seriesRepo.GetDueSeriesIDs: %v", err)
+		return err
+	}
+
+	var firstErr error
+	for _, id := range ids {
+		if _, err = s.change(ctx, id, func(series *entity.Series, now time.Time) error {
+			// an admin could have paused or skipped it since the query
+			if series.Status != entity.SeriesRunning || series.NextRunAt == nil || series.NextRunAt.After(now) {
+				return nil
+			}
+			return s.advance(ctx, series, now)
+		}); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// change reloads the series and applies fn to it while no one else moves it.
+func (s *seriesService) change(ctx context.Context, id int, fn func(series *entity.Series, now time.Time) error) (*entity.Series, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series, err := s.seriesRepo.GetSeriesByID(ctx, id)
+	if err != nil {
+		s.log.Error("seriesRepo.GetSeriesByID: %v", err)
+		return nil, err
+	}
+
+	if err = fn(series, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// advance closes the current question, reveals its results and posts the next
+// question. Questions moved to the trash are passed over. When the question
+// fails the preflight or the post fails the series is paused with the error.
+func (s *seriesService) advance(ctx context.Context, series *entity.Series, now time.Time) error {
+	if current := series.Current(); current != nil && current.ClosedAt == nil {
+		if err := s.publishService.Close(ctx, current.Question.ID); err != nil {
+			s.log.Error("failed to close question %d of series %d: %v", current.Question.ID, series.ID, err)
+		}
+
+		current.ClosedAt = &now
+		if err := s.seriesRepo.UpdateSeriesQuestion(ctx, series.ID, current); err != nil {
+			s.log.Error("seriesRepo.UpdateSeriesQuestion: %v", err)
+			return err
+		}
+
+		if series.RevealResults {
+			if err := s.publishService.RevealResults(ctx, current.Question.ID, series.ChannelTgID, current.PublishedAt); err != nil {
+				s.log.Error("failed to reveal results of series %d: %v", series.ID, err)
+			}
+		}
+	}
+
+	next := series.Next()
+	for next != nil && next.Question.DeletedAt != nil {
+		series.CurrentPosition = next.Position
+		next = series.Next()
+	}
+
+	if next == nil {
+		return s.finish(ctx, series, now)
+	}
+
+	if _, err := s.publishService.PublishScheduled(ctx, &entity.Publication{
+		QuestionID: next.Question.ID,
+		Scoring:    entity.ScoringSeparate,
+		ChannelIDs: []int64{series.ChannelTgID},
+		CreatedBy:  series.CreatedBy,
+	}); err != nil {
+		s.log.Error("failed to post question %d of series %d: %v", next.Question.ID, series.ID, err)
+
+		text := err.Error()
+		series.Status = entity.SeriesPaused
+		series.PausedAt = &now
+		series.LastError = &text
+		if err := s.seriesRepo.UpdateSeriesState(ctx, series); err != nil {
+			s.log.Error("seriesRepo.UpdateSeriesState: %v", err)
+		}
+		return err
+	}
+
+	next.PublishedAt = &now
+	if err := s.seriesRepo.UpdateSeriesQuestion(ctx, series.ID, next); err != nil {
+		s.log.Error("seriesRepo.UpdateSeriesQuestion: %v", err)
+		return err
+	}
+
+	nextRun := now.Add(series.Interval)
+	series.CurrentPosition = next.Position
+	series.NextRunAt = &nextRun
+	if series.Status == entity.SeriesPaused {
+		series.PausedAt = &now
+	}
+
+	return s.seriesRepo.UpdateSeriesState(ctx, series)
+}
+
+func (s *seriesService) finish(ctx context.Context, series *entity.Series, now time.Time) error {
+	series.Status = entity.SeriesFinished
+	series.FinishedAt = &now
+	series.NextRunAt = nil
+	series.PausedAt = nil
+
+	if err := s.seriesRepo.UpdateSeriesState(ctx, series); err != nil {
+		s.log.Error("seriesRepo.UpdateSeriesState: %v", err)
+		return err
+	}
+	s.log.Info("series %d of channel %d finished", series.ID, series.ChannelTgID)
+
+	if series.RevealResults {
+		if err := s.postFinalRating(ctx, series); err != nil {
+			s.log.Error("failed to post final rating of series %d: %v", series.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *seriesService) postFinalRating(ctx context.Context, series *entity.Series) error {
+	_, err := s.publishService.PostLeaderboard(ctx, series.ChannelTgID,
+		fmt.Sprintf("Серия «%s» завершена, итоговый рейтинг:", html.EscapeString(series.Title)),
+		series.LeaderboardFilter(), seriesFinalTop)
+	return err
+}
@@ -1,5 +1,3 @@
-set timezone = 'Europe/Moscow';
-
 DO $$
     BEGIN
         IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'role') THEN
@@ -0,0 +1,5 @@
+alter table questions add column if not exists media_type varchar(20);
+
+alter table questions alter column file_id type text;
+
+update questions set media_type = 'photo' where file_id is not null and media_type is null;
@@ -0,0 +1,10 @@
+alter table questions add column if not exists question_entities jsonb;
+
+alter table questions add column if not exists text_format varchar(20);
+
+-- questions created before were stored as escaped MarkdownV2
+update questions set text_format = 'markdown_v2' where text_format is null;
+
+alter table questions alter column text_format set default 'entities';
+
+alter table questions alter column text_format set not null;
@@ -0,0 +1,4 @@
+alter table answers add column if not exists is_correct boolean not null default false;
+
+-- before the flag existed any answer that gave points was treated as correct
+update answers set is_correct = cost_of_response > 0;
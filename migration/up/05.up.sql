@@ -0,0 +1,22 @@
+alter table user_results add column if not exists answered_at timestamp with time zone;
+alter table user_results add column if not exists answer_id int;
+
+ALTER TABLE user_results
+    ADD CONSTRAINT fk_answers
+        FOREIGN KEY (answer_id) REFERENCES answers(id) on delete set null;
+
+-- the answer time was not stored before, the question creation time is the closest known value
+update user_results ur set answered_at = q.created_at
+from questions q
+where q.id = ur.questions_id and ur.answered_at is null;
+
+alter table user_results alter column answered_at set default now();
+
+-- the answer was matched by its cost before, pick the first answer with the same cost
+update user_results ur set answer_id = (
+    select min(a.id) from answers a
+    where a.question_id = ur.questions_id and a.cost_of_response = ur.points
+)
+where ur.answer_id is null;
+
+create index if not exists user_results_questions_id_idx on user_results (questions_id);
@@ -0,0 +1,9 @@
+alter table questions add column if not exists published_at timestamp with time zone;
+
+-- the publish time was not stored before, sent questions get their creation time
+update questions set published_at = created_at where is_send and published_at is null;
+
+alter table channel add column if not exists last_reset_at timestamp with time zone;
+
+create index if not exists user_results_answered_at_idx on user_results (answered_at);
+create index if not exists questions_channel_published_at_idx on questions (channel_tg_id, published_at);
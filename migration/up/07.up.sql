@@ -0,0 +1,33 @@
+alter table channel add column if not exists current_season int not null default 1;
+alter table user_results add column if not exists season int not null default 1;
+alter table is_user_answer add column if not exists season int not null default 1;
+
+create table if not exists season(
+    id int generated always as identity,
+    channel_tg_id bigint not null,
+    number int not null,
+    started_at timestamp with time zone,
+    closed_at timestamp with time zone not null default now(),
+    primary key (id),
+    unique (channel_tg_id, number),
+    foreign key (channel_tg_id)
+        references channel (tg_id) on delete cascade
+);
+
+-- final standings of closed seasons, kept even if questions are deleted later
+create table if not exists season_standing(
+    season_id int not null,
+    rank int not null,
+    user_id bigint not null,
+    tg_username text,
+    total_points int not null,
+    answers int not null,
+    correct_answers int not null,
+    last_answer_at timestamp with time zone,
+    primary key (season_id, user_id),
+    foreign key (season_id)
+        references season (id) on delete cascade
+);
+
+create index if not exists user_results_season_idx on user_results (season);
+create index if not exists is_user_answer_user_question_idx on is_user_answer (user_id, question_id, season);
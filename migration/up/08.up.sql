@@ -0,0 +1,6 @@
+alter table questions add column if not exists deleted_at timestamp with time zone;
+
+-- points earned on a question in the trash keep counting unless an admin turns it off
+alter table questions add column if not exists count_points boolean not null default true;
+
+create index if not exists questions_channel_deleted_at_idx on questions (channel_tg_id, deleted_at);
@@ -0,0 +1,16 @@
+-- content of a question after every change, version 1 is the content before
+-- the first tracked change
+create table if not exists question_version(
+    id int generated always as identity,
+    question_id int not null,
+    version int not null,
+    changed_by bigint,
+    changed_at timestamp with time zone not null default now(),
+    change varchar(20) not null,
+    rolled_back_to int,
+    content jsonb not null,
+    primary key (id),
+    unique (question_id, version),
+    foreign key (question_id)
+        references questions (id) on delete cascade
+);
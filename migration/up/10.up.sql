@@ -0,0 +1,22 @@
+-- answers removed by an edit stay in place so that results and buttons of
+-- published posts keep pointing to them
+alter table answers add column if not exists is_retired boolean not null default false;
+
+alter table answers add column if not exists position int;
+
+update answers a set position = p.position
+from (select id, row_number() over (partition by question_id order by id) as position from answers) p
+where a.id = p.id and a.position is null;
+
+-- posts of a question, used to update their buttons after an edit
+create table if not exists question_post(
+    id int generated always as identity,
+    question_id int not null,
+    chat_id bigint not null,
+    message_id int not null,
+    published_at timestamp with time zone not null default now(),
+    primary key (id),
+    unique (chat_id, message_id),
+    foreign key (question_id)
+        references questions (id) on delete cascade
+);
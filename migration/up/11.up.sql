@@ -0,0 +1,7 @@
+-- media the post was sent with, Telegram can't turn a text post into a media
+-- post and back, so sync needs to know what the post is
+alter table question_post add column if not exists file_id text;
+
+update question_post p set file_id = q.file_id
+from questions q
+where q.id = p.question_id and p.file_id is null;
@@ -0,0 +1,37 @@
+-- one send of a question to one or more channels
+create table if not exists publication(
+    id int generated always as identity,
+    question_id int not null,
+    -- separate: every channel rates its own answers, pooled: one answer per user
+    -- across all channels, counted in the channel of the question
+    scoring varchar(10) not null default 'separate',
+    created_by bigint,
+    created_at timestamp with time zone not null default now(),
+    primary key (id),
+    foreign key (question_id)
+        references questions (id) on delete cascade
+);
+
+alter table question_post add column if not exists publication_id int;
+ALTER TABLE question_post
+    ADD CONSTRAINT fk_publication
+        FOREIGN KEY (publication_id) REFERENCES publication(id) on delete cascade;
+
+-- the channel whose rating the answer counts in and the chat it was given in
+alter table user_results add column if not exists channel_tg_id bigint;
+alter table user_results add column if not exists chat_id bigint;
+alter table is_user_answer add column if not exists channel_tg_id bigint;
+
+update user_results ur set channel_tg_id = q.channel_tg_id, chat_id = q.channel_tg_id
+from questions q
+where q.id = ur.questions_id and ur.channel_tg_id is null;
+
+update is_user_answer iua set channel_tg_id = q.channel_tg_id
+from questions q
+where q.id = iua.question_id and iua.channel_tg_id is null;
+
+ALTER TABLE user_results
+    ADD CONSTRAINT fk_results_channel
+        FOREIGN KEY (channel_tg_id) REFERENCES channel(tg_id) on delete cascade;
+
+create index if not exists user_results_channel_season_idx on user_results (channel_tg_id, season);
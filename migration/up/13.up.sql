@@ -0,0 +1,41 @@
+-- a closed question keeps its posts but doesn't take answers anymore
+alter table questions add column if not exists closed_at timestamp with time zone;
+
+-- questions of a channel posted one by one, each open for interval_seconds
+create table if not exists series(
+    id int generated always as identity,
+    channel_tg_id bigint not null,
+    title varchar(255) not null,
+    interval_seconds int not null,
+    reveal_results boolean not null default true,
+    -- draft, running, paused, finished
+    status varchar(10) not null default 'draft',
+    -- position of the last posted question, 0 before the start
+    current_position int not null default 0,
+    next_run_at timestamp with time zone,
+    paused_at timestamp with time zone,
+    last_error text,
+    started_at timestamp with time zone,
+    finished_at timestamp with time zone,
+    created_by bigint,
+    created_at timestamp with time zone not null default now(),
+    primary key (id),
+    foreign key (channel_tg_id)
+        references channel (tg_id) on delete cascade
+);
+
+create table if not exists series_question(
+    series_id int not null,
+    question_id int not null,
+    position int not null,
+    published_at timestamp with time zone,
+    closed_at timestamp with time zone,
+    primary key (series_id, position),
+    unique (series_id, question_id),
+    foreign key (series_id)
+        references series (id) on delete cascade,
+    foreign key (question_id)
+        references questions (id) on delete cascade
+);
+
+create index if not exists series_due_idx on series (next_run_at) where status = 'running';
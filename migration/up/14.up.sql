@@ -0,0 +1,29 @@
+-- tags and difficulty (1-5) let the autopilot pick questions by topic or level
+alter table questions add column if not exists tags text[] not null default '{}';
+alter table questions add column if not exists difficulty smallint;
+
+-- cron schedules of the channel are read in its timezone
+alter table channel add column if not exists timezone varchar(64) not null default 'Europe/Moscow';
+
+-- a channel posts an unsent question by itself on a cron schedule
+create table if not exists autopilot(
+    channel_tg_id bigint not null,
+    is_enabled boolean not null default false,
+    schedule varchar(100) not null,
+    -- an empty filter picks any unsent question at random
+    tag varchar(64),
+    difficulty smallint,
+    -- admins are warned when this many unsent questions or fewer are left
+    low_bank_threshold int not null default 5,
+    next_run_at timestamp with time zone,
+    last_run_at timestamp with time zone,
+    last_error text,
+    low_bank_warned_at timestamp with time zone,
+    updated_by bigint,
+    updated_at timestamp with time zone not null default now(),
+    primary key (channel_tg_id),
+    foreign key (channel_tg_id)
+        references channel (tg_id) on delete cascade
+);
+
+create index if not exists questions_tags_idx on questions using gin (tags);
@@ -0,0 +1,20 @@
+-- recurring jobs of a channel, every job runs on its own cron schedule
+create table if not exists cron_job(
+    id int generated always as identity,
+    channel_tg_id bigint not null,
+    -- post_question, close_questions, leaderboard, weekly_report
+    kind varchar(32) not null,
+    schedule varchar(100) not null,
+    is_paused boolean not null default false,
+    next_run_at timestamp with time zone,
+    last_run_at timestamp with time zone,
+    last_result text,
+    is_last_failed boolean not null default false,
+    created_by bigint,
+    created_at timestamp with time zone not null default now(),
+    primary key (id),
+    foreign key (channel_tg_id)
+        references channel (tg_id) on delete cascade
+);
+
+create index if not exists cron_job_due_idx on cron_job (next_run_at) where not is_paused;
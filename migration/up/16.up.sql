@@ -0,0 +1,18 @@
+-- timestamps are stored in UTC, every channel renders them in its own timezone.
+-- The old columns without a zone ("user".created_at, questions.deadline and any
+-- added by hand) kept the Moscow wall clock, so all of them are read as Moscow time.
+DO $$
+    DECLARE
+        col record;
+    BEGIN
+        FOR col IN
+            SELECT table_name, column_name
+            FROM information_schema.columns
+            WHERE table_schema = 'public'
+              AND data_type = 'timestamp without time zone'
+        LOOP
+            EXECUTE format(
+                'alter table %I alter column %I type timestamp with time zone using %I at time zone ''Europe/Moscow''',
+                col.table_name, col.column_name, col.column_name);
+        END LOOP;
+    END $$;
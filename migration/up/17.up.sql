@@ -0,0 +1,29 @@
+-- how a channel publishes questions and takes answers, a channel without a row uses the defaults
+create table if not exists channel_settings(
+    channel_tg_id bigint not null,
+    buttons_per_row smallint not null default 1,
+    shuffle_answers boolean not null default false,
+    -- the alert after an answer, {points} is replaced by the points
+    feedback_text text,
+    show_points boolean not null default true,
+    -- only members of the channel can answer
+    require_membership boolean not null default false,
+    -- a user may pick another answer this long after the first one, 0 forbids it
+    answer_change_seconds int not null default 0,
+    -- the deadline of a question without its own, counted from the publication, 0 is none
+    default_deadline_seconds int not null default 0,
+    -- added to the end of every published question
+    signature text,
+    updated_by bigint,
+    updated_at timestamp with time zone not null default now(),
+    primary key (channel_tg_id),
+    foreign key (channel_tg_id)
+        references channel (tg_id) on delete cascade
+);
+
+-- the layout a post was published with, the post keeps it when it is edited
+alter table question_post add column if not exists buttons_per_row smallint not null default 1;
+alter table question_post add column if not exists answer_order int[] not null default '{}';
+alter table question_post add column if not exists signature text;
+
+create index if not exists questions_deadline_idx on questions (deadline) where closed_at is null and deadline is not null;
@@ -0,0 +1,8 @@
+-- the layout of the answers of a question, null follows the settings of the channel.
+-- buttons_per_row -1 chooses the buttons per row by the length of the answers
+alter table questions add column if not exists buttons_per_row smallint;
+alter table questions add column if not exists shuffle_answers boolean;
+
+alter table questions drop constraint if exists questions_buttons_per_row_check;
+alter table questions add constraint questions_buttons_per_row_check
+    check (buttons_per_row is null or buttons_per_row = -1 or buttons_per_row between 1 and 4);
@@ -12,6 +12,10 @@ const (
 	ForeignKeyViolation = "Foreign Key Violation"
 	UniqueViolation     = "Violation Must Be Unique"
 	AdminPermission     = "Permission Denied"
+	UnsupportedMedia    = "Unsupported Media"
+	UndoUnavailable     = "Undo Is No Longer Available"
+	PostNotEditable     = "Post Can't Be Edited"
+	SeriesState         = "Not Allowed In Series State"
 )
 
 var (
@@ -22,6 +26,10 @@ var (
 	ErrForeignKeyViolation = NewError(ForeignKeyViolation)
 	ErrUniqueViolation     = NewError(UniqueViolation)
 	ErrIsNotAdmin          = NewError(AdminPermission)
+	ErrUnsupportedMedia    = NewError(UnsupportedMedia)
+	ErrUndoUnavailable     = NewError(UndoUnavailable)
+	ErrPostNotEditable     = NewError(PostNotEditable)
+	ErrSeriesState         = NewError(SeriesState)
 )
 
 type ErrorCode string
@@ -50,6 +58,14 @@ func parseErrToText(err ErrorCode) string {
 		return "Поисковая сущность отсутствует"
 	case AdminPermission:
 		return "Недостаточно прав доступа"
+	case UnsupportedMedia:
+		return "Неподдерживаемый тип файла, отправьте фото, видео, GIF, документ, аудио или голосовое сообщение"
+	case UndoUnavailable:
+		return "Отменить действие уже нельзя"
+	case PostNotEditable:
+		return "Telegram не дает добавить медиа в текстовый пост или убрать его из поста с медиа, отправьте вопрос заново"
+	case SeriesState:
+		return "Действие недоступно в текущем состоянии серии, откройте серию заново"
 	case NoRows, ForeignKeyViolation, UniqueViolation:
 		return "Ошибка связанная с базой данных"
 	default:
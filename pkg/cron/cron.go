@@ -0,0 +1,280 @@
+// Package cron parses standard five-field cron expressions and finds the next
+// time they fire.
+//
+//	┌───────────── minute (0-59)
+//	│ ┌─────────── hour (0-23)
+//	│ │ ┌───────── day of month (1-31)
+//	│ │ │ ┌─────── month (1-12 or JAN-DEC)
+//	│ │ │ │ ┌───── day of week (0-7 or SUN-SAT, 0 and 7 are Sunday)
+//	│ │ │ │ │
+//	0 12 * * 1-5
+//
+// Fields take *, single values, ranges a-b, lists a,b and steps */n or a-b/n.
+// The macros @hourly, @daily, @weekly and @monthly are accepted too. As in
+// classic cron, when both days are restricted the expression fires on either.
+// See Schedule.Next for how DST changes are handled.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchLimit is how far Next looks ahead before it gives up, an expression
+// like "0 0 30 2 *" never fires.
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+var ErrNeverFires = errors.New("cron: expression never fires")
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteBounds = bounds{min: 0, max: 59}
+	hourBounds   = bounds{min: 0, max: 23}
+	domBounds    = bounds{min: 1, max: 31}
+	monthBounds  = bounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = bounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Schedule is a parsed expression, every field is a set of allowed values.
+type Schedule struct {
+	expr string
+
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar tell that the day field was *, the day then
+	// matches by the other field only
+	domStar, dowStar bool
+}
+
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	fields := strings.Fields(expr)
+	if len(fields) == 1 {
+		if macro, ok := macros[strings.ToLower(fields[0])]; ok {
+			fields = strings.Fields(macro)
+		}
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d", len(fields))
+	}
+
+	s := &Schedule{
+		expr:    strings.Join(fields, " "),
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+
+	var err error
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	// 7 is another Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	if s.Next(time.Now()).IsZero() {
+		return nil, ErrNeverFires
+	}
+
+	return s, nil
+}
+
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t the schedule fires, in the location of
+// t. A zero time is returned when it doesn't fire within a few years.
+//
+// The fields are matched against the wall clock. A time the clock jumps over
+// when DST starts fires when the jump ends, a time the clock passes twice
+// when DST ends fires the first time only.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// the search runs over the wall clock in UTC, where every day has 24 hours
+	w := wallClock(t).Add(time.Minute)
+	limit := w.Add(searchLimit)
+
+	for w.Before(limit) {
+		if !has(s.month, int(w.Month())) {
+			w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(w) {
+			w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.hour, w.Hour()) {
+			w = time.Date(w.Year(), w.Month(), w.Day(), w.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.minute, w.Minute()) {
+			w = w.Add(time.Minute)
+			continue
+		}
+		if at := resolve(w, loc); at.After(t) {
+			return at
+		}
+		w = w.Add(time.Minute)
+	}
+
+	return time.Time{}
+}
+
+// wallClock returns the date and the time of t to the minute as if t were in
+// UTC.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// resolve returns the first instant the clock of loc shows the wall time w,
+// or the end of the DST gap when the clock never shows it.
+func resolve(w time.Time, loc *time.Location) time.Time {
+	local := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), 0, 0, loc)
+
+	// the offsets in effect around w, transitions are far more than 12 hours
+	// apart
+	var first, later time.Time
+	for _, probe := range []time.Time{local.Add(-12 * time.Hour), local, local.Add(12 * time.Hour)} {
+		_, offset := probe.Zone()
+		instant := w.Add(-time.Duration(offset) * time.Second).In(loc)
+		switch wall := wallClock(instant); {
+		case wall.Equal(w):
+			if first.IsZero() || instant.Before(first) {
+				first = instant
+			}
+		case wall.After(w):
+			if later.IsZero() || instant.Before(later) {
+				later = instant
+			}
+		}
+	}
+
+	switch {
+	case !first.IsZero():
+		return first
+	case !later.IsZero():
+		start, _ := later.ZoneBounds()
+		return start
+	default:
+		return local
+	}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<uint(value)) != 0
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		bits, err := parsePart(part, b)
+		if err != nil {
+			return 0, err
+		}
+		set |= bits
+	}
+	return set, nil
+}
+
+// parsePart parses one item of a list: *, a, a-b with an optional /step.
+func parsePart(part string, b bounds) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+			return 0, fmt.Errorf("cron: invalid step %q", part)
+		}
+	}
+
+	var from, to int
+	switch {
+	case rangePart == "*":
+		from, to = b.min, b.max
+	case strings.Contains(rangePart, "-"):
+		low, high, _ := strings.Cut(rangePart, "-")
+		var err error
+		if from, err = parseValue(low, b); err != nil {
+			return 0, err
+		}
+		if to, err = parseValue(high, b); err != nil {
+			return 0, err
+		}
+		if from > to {
+			return 0, fmt.Errorf("cron: invalid range %q", part)
+		}
+	default:
+		var err error
+		if from, err = parseValue(rangePart, b); err != nil {
+			return 0, err
+		}
+		to = from
+		// "5/15" means from 5 to the end every 15
+		if hasStep {
+			to = b.max
+		}
+	}
+
+	var set uint64
+	for value := from; value <= to; value += step {
+		set |= 1 << uint(value)
+	}
+	return set, nil
+}
+
+func parseValue(value string, b bounds) (int, error) {
+	if number, ok := b.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("cron: invalid value %q", value)
+	}
+	if number < b.min || number > b.max {
+		return 0, fmt.Errorf("cron: value %d out of range %d-%d", number, b.min, b.max)
+	}
+	return number, nil
+}
@@ -0,0 +1,214 @@
+package cron
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const layout = "2006-01-02 15:04 -0700"
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"empty", ""},
+		{"four fields", "* * * *"},
+		{"six fields", "0 * * * * *"},
+		{"unknown macro", "@yearly"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "0 24 * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"reversed range", "5-1 * * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"negative step", "*/-5 * * * *"},
+		{"not a number", "a * * * *"},
+		{"unknown name", "0 0 * * fun"},
+		{"empty list item", "1,,2 * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr); err == nil {
+				t.Errorf("Parse(%q) = nil error, want an error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestParseNeverFires(t *testing.T) {
+	for _, expr := range []string{"0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		if _, err := Parse(expr); !errors.Is(err, ErrNeverFires) {
+			t.Errorf("Parse(%q) error = %v, want ErrNeverFires", expr, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"0 12 * * 1-5", "0 12 * * 1-5"},
+		{"  0   12 * * *  ", "0 12 * * *"},
+		{"@daily", "0 0 * * *"},
+		{"@HOURLY", "0 * * * *"},
+		{"0 9 * jan-mar MON,fri", "0 9 * jan-mar MON,fri"},
+		{"5/15 * * * *", "5/15 * * * *"},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got := s.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{"strictly after", "0 12 * * *", "2025-01-01 12:00 +0000", "2025-01-02 12:00 +0000"},
+		{"seconds are dropped", "*/15 * * * *", "2025-01-01 10:07 +0000", "2025-01-01 10:15 +0000"},
+		{"step from a value", "5/20 * * * *", "2025-01-01 10:26 +0000", "2025-01-01 10:45 +0000"},
+		{"weekdays skip the weekend", "0 12 * * 1-5", "2025-01-03 12:00 +0000", "2025-01-06 12:00 +0000"},
+		{"names", "0 9 * feb mon", "2025-01-01 00:00 +0000", "2025-02-03 09:00 +0000"},
+		{"macro", "@monthly", "2025-01-15 08:00 +0000", "2025-02-01 00:00 +0000"},
+
+		{"sunday as 0", "0 0 * * 0", "2025-01-01 00:00 +0000", "2025-01-05 00:00 +0000"},
+		{"sunday as 7", "0 0 * * 7", "2025-01-01 00:00 +0000", "2025-01-05 00:00 +0000"},
+		{"weekday with any day of month", "0 0 * * 5", "2025-01-01 00:00 +0000", "2025-01-03 00:00 +0000"},
+		{"day of month with any weekday", "0 0 13 * *", "2025-01-01 00:00 +0000", "2025-01-13 00:00 +0000"},
+		{"both days: weekday first", "0 0 13 * 5", "2025-01-01 00:00 +0000", "2025-01-03 00:00 +0000"},
+		{"both days: day of month first", "0 0 13 * 5", "2025-01-10 00:00 +0000", "2025-01-13 00:00 +0000"},
+		{"both days: either counts", "0 0 13 * 5", "2025-01-13 00:00 +0000", "2025-01-17 00:00 +0000"},
+
+		{"31st skips short months", "0 0 31 * *", "2025-01-31 00:00 +0000", "2025-03-31 00:00 +0000"},
+		{"30th skips february", "0 0 30 * *", "2025-01-30 00:00 +0000", "2025-03-30 00:00 +0000"},
+		{"end of year", "0 0 1 1 *", "2025-12-31 23:59 +0000", "2026-01-01 00:00 +0000"},
+		{"february 29", "0 0 29 2 *", "2025-01-01 00:00 +0000", "2028-02-29 00:00 +0000"},
+		{"february 29 in a leap year", "0 0 29 2 *", "2024-02-28 12:00 +0000", "2024-02-29 00:00 +0000"},
+
+		{"fixed offset is kept", "0 9 * * *", "2025-06-01 10:00 +0300", "2025-06-02 09:00 +0300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			from, err := time.Parse(layout, tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := s.Next(from).Format(layout); got != tt.want {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		expr     string
+		from     string
+		want     []string
+	}{
+		{
+			name:     "moscow today has no DST",
+			location: "Europe/Moscow",
+			expr:     "0 9 * * *",
+			from:     "2025-03-29 12:00",
+			want:     []string{"2025-03-30 09:00 +0300", "2025-03-31 09:00 +0300"},
+		},
+		{
+			name:     "moscow gap fires when the clock lands",
+			location: "Europe/Moscow",
+			expr:     "30 2 * * *",
+			from:     "2011-03-27 00:00",
+			want:     []string{"2011-03-27 03:00 +0400", "2011-03-28 02:30 +0400"},
+		},
+		{
+			name:     "moscow repeated hour fires once",
+			location: "Europe/Moscow",
+			expr:     "30 1 * * *",
+			from:     "2014-10-26 00:00",
+			want:     []string{"2014-10-26 01:30 +0400", "2014-10-27 01:30 +0300"},
+		},
+		{
+			name:     "new york gap fires when the clock lands",
+			location: "America/New_York",
+			expr:     "30 2 * * *",
+			from:     "2025-03-09 00:00",
+			want:     []string{"2025-03-09 03:00 -0400", "2025-03-10 02:30 -0400"},
+		},
+		{
+			name:     "new york several times in the gap fire once",
+			location: "America/New_York",
+			expr:     "*/20 2 * * *",
+			from:     "2025-03-09 01:50",
+			want:     []string{"2025-03-09 03:00 -0400", "2025-03-10 02:00 -0400"},
+		},
+		{
+			name:     "new york hours around the gap",
+			location: "America/New_York",
+			expr:     "0 * * * *",
+			from:     "2025-03-09 00:30",
+			want:     []string{"2025-03-09 01:00 -0500", "2025-03-09 03:00 -0400", "2025-03-09 04:00 -0400"},
+		},
+		{
+			name:     "new york repeated hour fires once",
+			location: "America/New_York",
+			expr:     "30 1 * * *",
+			from:     "2025-11-02 00:00",
+			want:     []string{"2025-11-02 01:30 -0400", "2025-11-03 01:30 -0500"},
+		},
+		{
+			name:     "new york hours around the repeated hour",
+			location: "America/New_York",
+			expr:     "0 * * * *",
+			from:     "2025-11-02 00:30",
+			want:     []string{"2025-11-02 01:00 -0400", "2025-11-02 02:00 -0500", "2025-11-02 03:00 -0500"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.location)
+			if err != nil {
+				t.Skipf("no tzdata for %s: %v", tt.location, err)
+			}
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+			}
+			from, err := time.ParseInLocation("2006-01-02 15:04", tt.from, loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			at := from
+			for _, want := range tt.want {
+				at = s.Next(at)
+				if got := at.Format(layout); got != want {
+					t.Fatalf("Next after %s = %s, want %s", tt.from, got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,147 +1,194 @@
 package excel
 
 import (
-	"fmt"
 	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/xuri/excelize/v2"
-	"os"
-	"sync"
+	"io"
 	"time"
 )
 
-const filename = "users.xlsx"
+// RowSource feeds rows to yield one by one, usually straight from a database
+// cursor. It must stop and return the first error returned by yield.
+type RowSource[T any] func(yield func(T) error) error
 
 type Excel struct {
 	log *logger.Logger
-	mu  sync.Mutex
 }
 
 func NewExcel(log *logger.Logger) *Excel {
 	return &Excel{log: log}
 }
 
-func (e *Excel) GenerateUserResultsExcelFile(results []entity.UserResult, username string) (string, error) {
-	start := time.Now()
+// Workbook is built in memory and belongs to a single export, so concurrent
+// exports never share files or locks.
+type Workbook struct {
+	f         *excelize.File
+	sheets    int
+	timeStyle int
+	location  *time.Location
+}
 
+// NewWorkbook creates a workbook that shows times in location, UTC when it
+// is nil.
+func (e *Excel) NewWorkbook(location *time.Location) (*Workbook, error) {
 	f := excelize.NewFile()
 
-	defer func() {
-		if err := f.Close(); err != nil {
-			e.log.Error("failed to close excel: %v", err)
-		}
-	}()
+	layout := "yyyy-mm-dd hh:mm:ss"
+	timeStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &layout})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
 
-	sheetName := "Sheet1"
-	f.NewSheet(sheetName)
+	if location == nil {
+		location = time.UTC
+	}
+	return &Workbook{f: f, timeStyle: timeStyle, location: location}, nil
+}
 
-	headers := map[string]string{
-		"A1": "TG Username",
-		"B1": "User ID",
-		"C1": "Result ID",
-		"D1": "Answer",
-		"E1": "Points",
-		"F1": "Question",
+// Time returns a cell value that is shown as a date and time in the location
+// of the workbook. Excel cells have no timezone, excelize writes the UTC wall
+// clock, so the wall clock of the location is passed as UTC.
+func (w *Workbook) Time(t time.Time) excelize.Cell {
+	local := t.In(w.location)
+	wall := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(),
+		local.Nanosecond(), time.UTC)
+	return excelize.Cell{StyleID: w.timeStyle, Value: wall}
+}
+
+// Sheet is a sheet written row by row with a StreamWriter. Flush must be
+// called before the next sheet is started.
+type Sheet struct {
+	sw  *excelize.StreamWriter
+	row int
+}
+
+// NewSheet adds a sheet with the header row. The first call renames the
+// default sheet of the new workbook.
+func (w *Workbook) NewSheet(name string, header ...interface{}) (*Sheet, error) {
+	if w.sheets == 0 {
+		if err := w.f.SetSheetName(w.f.GetSheetName(0), name); err != nil {
+			return nil, err
+		}
+	} else if _, err := w.f.NewSheet(name); err != nil {
+		return nil, err
 	}
+	w.sheets++
 
-	for cell, value := range headers {
-		f.SetCellValue(sheetName, cell, value)
+	sw, err := w.f.NewStreamWriter(name)
+	if err != nil {
+		return nil, err
 	}
 
-	for i, result := range results {
-		row := i + 2
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), result.TGUsername)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), result.UserID)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), result.ID)
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), result.Answer)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), result.Points)
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), result.QuestionName)
+	sheet := &Sheet{sw: sw}
+	if len(header) > 0 {
+		if err = sheet.WriteRow(header...); err != nil {
+			return nil, err
+		}
 	}
 
-	filename := fmt.Sprintf("contest_results.xlsx")
-	err := f.SaveAs(filename)
+	return sheet, nil
+}
+
+func (s *Sheet) WriteRow(values ...interface{}) error {
+	s.row++
+	cell, err := excelize.CoordinatesToCellName(1, s.row)
 	if err != nil {
-		e.log.Error("failed to save file: %s", filename)
-		return "", err
+		return err
 	}
+	return s.sw.SetRow(cell, values)
+}
 
-	end := time.Since(start)
-	e.log.Info("[%s] by [%s] Время генерации файла: %f", filename, username, end.Seconds())
-	return filename, nil
+func (s *Sheet) Flush() error {
+	return s.sw.Flush()
 }
 
-func (e *Excel) GenerateForUserResultsExcelFile(results []entity.UserResult, contestID int, username string) (string, error) {
-	start := time.Now()
+func (w *Workbook) Write(dst io.Writer) error {
+	return w.f.Write(dst)
+}
 
-	f := excelize.NewFile()
+func (w *Workbook) Close() error {
+	return w.f.Close()
+}
 
+// UserResultsReport holds the sources of the sheets of the results workbook.
+type UserResultsReport struct {
+	Results     RowSource[*entity.UserResult]
+	Leaderboard RowSource[*entity.LeaderboardRow]
+	Statistics  RowSource[*entity.QuestionStatistics]
+	// Location is the timezone of the channel the times are written in, UTC
+	// when it is nil
+	Location *time.Location
+}
+
+// In returns t in the location of the report.
+func (r UserResultsReport) In(t time.Time) time.Time {
+	if r.Location == nil {
+		return t.UTC()
+	}
+	return t.In(r.Location)
+}
+
+// WriteUserResults writes the per-answer results, the leaderboard and the
+// question statistics of a channel to dst.
+func (e *Excel) WriteUserResults(dst io.Writer, report UserResultsReport, username string) error {
+	start := time.Now()
+
+	wb, err := e.NewWorkbook(report.Location)
+	if err != nil {
+		return err
+	}
 	defer func() {
-		if err := f.Close(); err != nil {
+		if err := wb.Close(); err != nil {
 			e.log.Error("failed to close excel: %v", err)
 		}
 	}()
 
-	sheetName := "Sheet1"
-	f.NewSheet(sheetName)
-
-	headers := map[string]string{
-		"A1": "TG Username",
-		"B1": "Points",
+	sheet, err := wb.NewSheet("Sheet1", "TG Username", "User ID", "Result ID", "Answer", "Points", "Question", "Channel")
+	if err != nil {
+		return err
 	}
 
-	for cell, value := range headers {
-		f.SetCellValue(sheetName, cell, value)
+	rows := 0
+	if err = report.Results(func(result *entity.UserResult) error {
+		rows++
+		return sheet.WriteRow(result.TGUsername, result.UserID, result.ID, result.Answer, result.Points, result.QuestionName, result.ChatName)
+	}); err != nil {
+		return err
 	}
 
-	for i, result := range results {
-		row := i + 2
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), result.TGUsername)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), result.Points)
+	if err = sheet.Flush(); err != nil {
+		return err
 	}
 
-	filename := fmt.Sprintf("contest_%d_results.xlsx", contestID)
-	err := f.SaveAs(filename)
-	if err != nil {
-		e.log.Error("failed to save file: %s", filename)
-		return "", err
+	if err = e.writeLeaderboard(wb, report.Leaderboard); err != nil {
+		return err
 	}
 
-	end := time.Since(start)
-	e.log.Info("[%s] by [%s] Время генерации файла: %f", filename, username, end.Seconds())
-	return filename, nil
-}
-
-func (e *Excel) GetExcelFile(fileName string) (*[]byte, error) {
-
-	file, err := os.Open(fileName)
-	if err != nil {
-		e.log.Error("os.Open: failed to open file: %v", err)
-		return nil, err
+	if err = e.writeStatistics(wb, report.Statistics); err != nil {
+		return err
 	}
-	defer func() {
-		err := file.Close()
-		if err != nil {
-			e.log.Error("%v", err)
-		}
-	}()
 
-	fileInfo, err := file.Stat()
-	if err != nil {
-		e.log.Error("file.Stat: failed to get file stat: %v", err)
-		return nil, err
+	if err = wb.Write(dst); err != nil {
+		return err
 	}
 
-	fileSize := fileInfo.Size()
-	fileID := tgbotapi.FileBytes{
-		Name:  fileName,
-		Bytes: make([]byte, fileSize),
+	e.log.Info("user results by [%s]: %d rows, время генерации файла: %f", username, rows, time.Since(start).Seconds())
+	return nil
+}
+
+func (e *Excel) writeLeaderboard(wb *Workbook, leaderboard RowSource[*entity.LeaderboardRow]) error {
+	sheet, err := wb.NewSheet("Leaderboard", "Rank", "TG Username", "User ID", "Total points", "Answers", "Correct answers", "Last answer")
+	if err != nil {
+		return err
 	}
 
-	if _, err = file.Read(fileID.Bytes); err != nil {
-		e.log.Error("file.Read: failed to get read file: %v", err)
-		return nil, err
+	if err = leaderboard(func(row *entity.LeaderboardRow) error {
+		return sheet.WriteRow(row.Rank, row.TGUsername, row.UserID, row.TotalPoints, row.Answers, row.CorrectAnswers, wb.Time(row.LastAnswerAt))
+	}); err != nil {
+		return err
 	}
 
-	return &fileID.Bytes, nil
+	return sheet.Flush()
 }
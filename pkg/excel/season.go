@@ -0,0 +1,115 @@
+package excel
+
+import (
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"io"
+	"time"
+)
+
+// WriteSeasonComparison writes a summary of the closed seasons and a sheet
+// with the rank and points of every user in every season. Dates are written
+// in location.
+func (e *Excel) WriteSeasonComparison(dst io.Writer, seasons []entity.Season, standings RowSource[*entity.SeasonStanding],
+	location *time.Location) error {
+	wb, err := e.NewWorkbook(location)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := wb.Close(); err != nil {
+			e.log.Error("failed to close excel: %v", err)
+		}
+	}()
+
+	summary, err := wb.NewSheet("Seasons", "Season", "Started", "Closed", "Participants", "Winner", "Winner points")
+	if err != nil {
+		return err
+	}
+	column := make(map[int]int, len(seasons))
+	for key, season := range seasons {
+		column[season.Number] = key
+
+		var started interface{}
+		if season.StartedAt != nil {
+			started = wb.Time(*season.StartedAt)
+		}
+		if err = summary.WriteRow(season.Number, started, wb.Time(season.ClosedAt), season.Participants,
+			season.WinnerUsername, season.WinnerPoints); err != nil {
+			return err
+		}
+	}
+	if err = summary.Flush(); err != nil {
+		return err
+	}
+
+	header := []interface{}{"User ID", "TG Username"}
+	for _, season := range seasons {
+		header = append(header, fmt.Sprintf("S%d rank", season.Number), fmt.Sprintf("S%d points", season.Number))
+	}
+	header = append(header, "Seasons played", "Best rank", "Total points")
+
+	comparison, err := wb.NewSheet("Comparison", header...)
+	if err != nil {
+		return err
+	}
+
+	// standings come grouped by user, a row is written when the next user starts
+	var (
+		current *userSeasons
+		flush   = func() error {
+			if current == nil {
+				return nil
+			}
+			return comparison.WriteRow(current.row()...)
+		}
+	)
+	if err = standings(func(standing *entity.SeasonStanding) error {
+		if current == nil || current.userID != standing.UserID {
+			if err := flush(); err != nil {
+				return err
+			}
+			current = &userSeasons{
+				userID:   standing.UserID,
+				username: standing.TGUsername,
+				cells:    make([]interface{}, 2*len(seasons)),
+			}
+		}
+		key, ok := column[standing.SeasonNumber]
+		if !ok {
+			return nil
+		}
+		current.cells[2*key], current.cells[2*key+1] = standing.Rank, standing.TotalPoints
+		current.played++
+		current.total += standing.TotalPoints
+		if current.bestRank == 0 || standing.Rank < current.bestRank {
+			current.bestRank = standing.Rank
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err = flush(); err != nil {
+		return err
+	}
+
+	if err = comparison.Flush(); err != nil {
+		return err
+	}
+
+	return wb.Write(dst)
+}
+
+type userSeasons struct {
+	userID   int64
+	username string
+	cells    []interface{}
+	played   int
+	bestRank int
+	total    int
+}
+
+func (u *userSeasons) row() []interface{} {
+	row := append([]interface{}{u.userID, u.username}, u.cells...)
+	return append(row, u.played, u.bestRank, u.total)
+}
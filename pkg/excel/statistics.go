@@ -0,0 +1,159 @@
+package excel
+
+import (
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/xuri/excelize/v2"
+	"math"
+)
+
+const (
+	statisticsSheet = "Statistics"
+	// chartRows is the height of a chart in rows, blocks of questions are padded
+	// to it so the charts next to them don't overlap
+	chartRows   = 16
+	chartWidth  = 480
+	chartHeight = 300
+	chartColumn = "H"
+	maxLabelLen = 40
+)
+
+// writeStatistics writes a summary table of all questions with a chart of the
+// percentage of correct answers, followed by the distribution of answers of
+// every question with its own chart.
+func (e *Excel) writeStatistics(wb *Workbook, statistics RowSource[*entity.QuestionStatistics]) error {
+	// questions are few compared to the raw results, the summary table needs
+	// all of them before the per-question blocks
+	var stats []entity.QuestionStatistics
+	if err := statistics(func(stat *entity.QuestionStatistics) error {
+		stats = append(stats, *stat)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	sheet, err := wb.NewSheet(statisticsSheet, "#", "Question ID", "Question", "Participants", "Correct, %", "Average points")
+	if err != nil {
+		return err
+	}
+
+	for key, stat := range stats {
+		if err = sheet.WriteRow(fmt.Sprintf("Q%d", key+1), stat.Question.ID, stat.Question.PlainText(),
+			stat.Participants, round(stat.CorrectPercent), round(stat.AveragePoints)); err != nil {
+			return err
+		}
+	}
+
+	var charts []chartAt
+	if len(stats) > 0 {
+		charts = append(charts, chartAt{cell: chartColumn + "1", chart: summaryChart(len(stats))})
+	}
+
+	// the summary chart takes chartRows rows as well
+	for sheet.row < chartRows+1 {
+		if err = sheet.WriteRow(); err != nil {
+			return err
+		}
+	}
+
+	for key, stat := range stats {
+		if err = sheet.WriteRow(); err != nil {
+			return err
+		}
+		start := sheet.row + 1
+		if err = sheet.WriteRow(fmt.Sprintf("Q%d", key+1), stat.Question.PlainText()); err != nil {
+			return err
+		}
+		if err = sheet.WriteRow("Answer", "Correct", "Picked", "Share, %"); err != nil {
+			return err
+		}
+
+		picked := 0
+		for _, answer := range stat.Answers {
+			picked += answer.Picked
+		}
+		for _, answer := range stat.Answers {
+			share := 0.0
+			if picked > 0 {
+				share = 100 * float64(answer.Picked) / float64(picked)
+			}
+			if err = sheet.WriteRow(answer.Answer, answer.IsCorrect, answer.Picked, round(share)); err != nil {
+				return err
+			}
+		}
+
+		if len(stat.Answers) > 0 {
+			charts = append(charts, chartAt{
+				cell:  fmt.Sprintf("%s%d", chartColumn, start),
+				chart: questionChart(fmt.Sprintf("Q%d", key+1), stat.Question.PlainText(), start+2, start+1+len(stat.Answers)),
+			})
+		}
+
+		for sheet.row < start+chartRows-1 {
+			if err = sheet.WriteRow(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = sheet.Flush(); err != nil {
+		return err
+	}
+
+	// charts are drawings, they are added after the rows of the sheet are flushed
+	for _, c := range charts {
+		if err = wb.f.AddChart(statisticsSheet, c.cell, c.chart); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type chartAt struct {
+	cell  string
+	chart *excelize.Chart
+}
+
+func summaryChart(questions int) *excelize.Chart {
+	return &excelize.Chart{
+		Type: excelize.Col,
+		Series: []excelize.ChartSeries{{
+			Name:       fmt.Sprintf("'%s'!$E$1", statisticsSheet),
+			Categories: fmt.Sprintf("'%s'!$A$2:$A$%d", statisticsSheet, questions+1),
+			Values:     fmt.Sprintf("'%s'!$E$2:$E$%d", statisticsSheet, questions+1),
+		}},
+		Title:     []excelize.RichTextRun{{Text: "Correct answers by question, %"}},
+		Legend:    excelize.ChartLegend{Position: "none"},
+		Dimension: excelize.ChartDimension{Width: chartWidth, Height: chartHeight},
+		PlotArea:  excelize.ChartPlotArea{ShowVal: true},
+	}
+}
+
+func questionChart(label, question string, firstRow, lastRow int) *excelize.Chart {
+	return &excelize.Chart{
+		Type: excelize.Bar,
+		Series: []excelize.ChartSeries{{
+			Name:       fmt.Sprintf("'%s'!$C$%d", statisticsSheet, firstRow-1),
+			Categories: fmt.Sprintf("'%s'!$A$%d:$A$%d", statisticsSheet, firstRow, lastRow),
+			Values:     fmt.Sprintf("'%s'!$C$%d:$C$%d", statisticsSheet, firstRow, lastRow),
+		}},
+		Title:     []excelize.RichTextRun{{Text: label + ". " + truncate(question, maxLabelLen)}},
+		Legend:    excelize.ChartLegend{Position: "none"},
+		Dimension: excelize.ChartDimension{Width: chartWidth, Height: chartHeight},
+		PlotArea:  excelize.ChartPlotArea{ShowVal: true},
+		XAxis:     excelize.ChartAxis{ReverseOrder: true},
+	}
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/csv"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"io"
+	"strconv"
+	"time"
+)
+
+// utf8BOM makes Excel open the file as UTF-8 instead of the system code page.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+var csvHeader = []string{
+	"TG Username", "User ID", "Result ID", "Answer", "Points", "Question", "Question ID", "Answer ID", "Answered at",
+	"Channel", "Channel ID",
+}
+
+// csvExporter writes the per-answer results. The comma is ';' for locales
+// where Excel expects it as the list separator.
+type csvExporter struct {
+	comma rune
+}
+
+func (c *csvExporter) Ext() string {
+	return "csv"
+}
+
+func (c *csvExporter) Export(w io.Writer, data Dataset) error {
+	if _, err := w.Write(utf8BOM); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = c.comma
+	writer.UseCRLF = true
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	if err := data.Results(func(result *entity.UserResult) error {
+		return writer.Write([]string{
+			result.TGUsername,
+			strconv.FormatInt(result.UserID, 10),
+			strconv.Itoa(result.ID),
+			result.Answer,
+			strconv.Itoa(result.Points),
+			result.QuestionName,
+			strconv.Itoa(result.QuestionID),
+			strconv.Itoa(result.AnswerID),
+			data.In(result.AnsweredAt).Format(time.RFC3339),
+			result.ChatName,
+			strconv.FormatInt(result.ChatID, 10),
+		})
+	}); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"errors"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/excel"
+	"io"
+)
+
+type Format string
+
+const (
+	FormatXLSX         Format = "xlsx"
+	FormatCSV          Format = "csv"
+	FormatCSVSemicolon Format = "semicolon"
+	FormatJSONL        Format = "jsonl"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Dataset is the data of one export. Every exporter renders the same dataset,
+// so a filter applied to its sources applies to all formats.
+type Dataset = excel.UserResultsReport
+
+type Exporter interface {
+	// Ext is the file extension without the dot.
+	Ext() string
+	Export(w io.Writer, data Dataset) error
+}
+
+// NewExporter returns the exporter of the format. username is only used to
+// log who requested the export.
+func NewExporter(format Format, e *excel.Excel, username string) (Exporter, error) {
+	switch format {
+	case FormatXLSX:
+		return &xlsxExporter{excel: e, username: username}, nil
+	case FormatCSV:
+		return &csvExporter{comma: ','}, nil
+	case FormatCSVSemicolon:
+		return &csvExporter{comma: ';'}, nil
+	case FormatJSONL:
+		return &jsonlExporter{}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// xlsxExporter writes the full workbook: per-answer results, leaderboard and statistics.
+type xlsxExporter struct {
+	excel    *excel.Excel
+	username string
+}
+
+func (x *xlsxExporter) Ext() string {
+	return "xlsx"
+}
+
+func (x *xlsxExporter) Export(w io.Writer, data Dataset) error {
+	return x.excel.WriteUserResults(w, data, x.username)
+}
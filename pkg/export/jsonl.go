@@ -0,0 +1,25 @@
+package export
+
+import (
+	"encoding/json"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"io"
+)
+
+// jsonlExporter writes the per-answer results, one JSON object per line.
+type jsonlExporter struct{}
+
+func (j *jsonlExporter) Ext() string {
+	return "jsonl"
+}
+
+func (j *jsonlExporter) Export(w io.Writer, data Dataset) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return data.Results(func(result *entity.UserResult) error {
+		row := *result
+		row.AnsweredAt = data.In(result.AnsweredAt)
+		return encoder.Encode(&row)
+	})
+}
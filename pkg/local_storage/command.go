@@ -20,6 +20,35 @@ const (
 	QuizUpdateImage     TypeCommand = "update_image"
 	QuizUpdateQuestion  TypeCommand = "update_question"
 	QuizUpdateOldAnswer TypeCommand = "update_old_answer"
+	QuizForward         TypeCommand = "forward_quiz"
+	QuizImport          TypeCommand = "import_quiz"
+	QuizImportConfirm   TypeCommand = "import_quiz_confirm"
+	QuizPublishTargets  TypeCommand = "publish_targets"
+	QuizClone           TypeCommand = "clone_quiz"
+	QuizSeries          TypeCommand = "series_quiz"
+	QuizUpdateTags      TypeCommand = "update_tags"
+)
+
+const (
+	RatingExport          TypeCommand = "rating_export"
+	RatingExportPeriod    TypeCommand = "rating_export_period"
+	RatingExportQuestions TypeCommand = "rating_export_questions"
+)
+
+const (
+	AutopilotSchedule TypeCommand = "autopilot_schedule"
+	AutopilotTag      TypeCommand = "autopilot_tag"
+)
+
+const (
+	CronJobCreate   TypeCommand = "cron_job_create"
+	CronJobSchedule TypeCommand = "cron_job_schedule"
+	ChannelTimezone TypeCommand = "channel_timezone"
+)
+
+const (
+	ChannelFeedback  TypeCommand = "channel_feedback"
+	ChannelSignature TypeCommand = "channel_signature"
 )
 
 var MapTypes = map[TypeCommand]OperationType{
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// UndoAction restores the state that a destructive action replaced. Back is
+// the callback data of the menu shown after the undo.
+type UndoAction struct {
+	UserID  int64
+	Back    string
+	Restore func(ctx context.Context) error
+
+	expiresAt time.Time
+}
+
+// UndoStorage keeps undo actions for a limited time, an action can be taken
+// only once and only by the admin who made the change.
+type UndoStorage struct {
+	storage map[string]*UndoAction
+	ttl     time.Duration
+
+	mu sync.Mutex
+}
+
+func NewUndoStorage(ttl time.Duration) *UndoStorage {
+	return &UndoStorage{
+		storage: make(map[string]*UndoAction, 10),
+		ttl:     ttl,
+	}
+}
+
+func (u *UndoStorage) TTL() time.Duration {
+	return u.ttl
+}
+
+// Set saves the action and returns its token for the callback data.
+func (u *UndoStorage) Set(action *UndoAction) string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := time.Now()
+	for key, value := range u.storage {
+		if now.After(value.expiresAt) {
+			delete(u.storage, key)
+		}
+	}
+
+	action.expiresAt = now.Add(u.ttl)
+	u.storage[token] = action
+
+	return token
+}
+
+// Take removes the action and returns it if it has not expired yet.
+func (u *UndoStorage) Take(token string, userID int64) (*UndoAction, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	action, ok := u.storage[token]
+	if !ok || action.UserID != userID {
+		return nil, false
+	}
+	delete(u.storage, token)
+
+	if time.Now().After(action.expiresAt) {
+		return nil, false
+	}
+
+	return action, true
+}
@@ -24,7 +24,14 @@ func New(ctx context.Context, maxAttempts int, url string) (*Postgres, error) {
 		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		pool, err := pgxpool.New(ctx, url)
+		config, err := pgxpool.ParseConfig(url)
+		if err != nil {
+			return err
+		}
+		// timestamps are read and written in UTC whatever the server is set to
+		config.ConnConfig.RuntimeParams["timezone"] = "UTC"
+
+		pool, err := pgxpool.NewWithConfig(ctx, config)
 		if err != nil {
 			return err
 		}
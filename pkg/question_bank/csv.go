@@ -0,0 +1,34 @@
+package question_bank
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// DecodeCSV reads csv with "," or ";" separator, the separator is taken from
+// the header line. A UTF-8 BOM left by Excel is skipped.
+func DecodeCSV(r io.Reader) ([]Question, []RowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+
+	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader.FieldsPerRecord = -1
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		csvReader.Comma = ';'
+	}
+
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decodeTable(rows)
+}
@@ -0,0 +1,39 @@
+package question_bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func DecodeJSON(r io.Reader) ([]Question, []RowError, error) {
+	var raw []Question
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		questions []Question
+		rowErrors []RowError
+	)
+	for key, question := range raw {
+		if problems := question.validate(); len(problems) > 0 {
+			rowErrors = append(rowErrors, RowError{
+				Position: fmt.Sprintf("вопрос %d", key+1),
+				Message:  strings.Join(problems, "; "),
+			})
+			continue
+		}
+		questions = append(questions, question)
+	}
+
+	return questions, rowErrors, nil
+}
+
+func EncodeJSON(w io.Writer, questions []Question) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(questions)
+}
@@ -0,0 +1,216 @@
+package question_bank
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	maxAnswerLength  = 100
+	maxTextLength    = 4096
+	maxCaptionLength = 1024
+
+	// buttonsPerRowAuto is the table value of entity.ButtonsPerRowAuto
+	buttonsPerRowAuto = "auto"
+)
+
+var ErrUnknownFormat = errors.New("unknown file format, expected .xlsx, .csv or .json")
+
+// Question is one question of a bank file. The same model is used for import
+// and export, fields after Answers are filled on export and ignored on import.
+type Question struct {
+	Question  string                   `json:"question"`
+	Entities  []coverter.MessageEntity `json:"question_entities,omitempty"`
+	MediaType entity.MediaType         `json:"media_type,omitempty"`
+	FileID    string                   `json:"file_id,omitempty"`
+	// Tags, Difficulty (1-5), ButtonsPerRow and ShuffleAnswers are the
+	// question settings, empty ones fall back to the settings of the channel.
+	Tags           []string `json:"tags,omitempty"`
+	Difficulty     *int     `json:"difficulty,omitempty"`
+	ButtonsPerRow  *int     `json:"buttons_per_row,omitempty"`
+	ShuffleAnswers *bool    `json:"shuffle_answers,omitempty"`
+	Answers        []Answer `json:"answers"`
+
+	ID        int        `json:"id,omitempty"`
+	IsSend    bool       `json:"is_send,omitempty"`
+	Deadline  *time.Time `json:"deadline,omitempty"`
+	CreatedBy int64      `json:"created_by_user,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type Answer struct {
+	Answer  string `json:"answer"`
+	Cost    int    `json:"cost"`
+	Correct *bool  `json:"correct,omitempty"`
+}
+
+// RowError describes why a question of the file can't be imported.
+// Position is "строка N" for tables and "вопрос N" for JSON.
+type RowError struct {
+	Position string
+	Message  string
+}
+
+func (r RowError) String() string {
+	return r.Position + ": " + r.Message
+}
+
+// Decode parses a bank file by its extension. Questions with errors are not
+// returned, their problems are listed in the row errors instead.
+func Decode(fileName string, r io.Reader) ([]Question, []RowError, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx":
+		return DecodeXLSX(r)
+	case ".csv":
+		return DecodeCSV(r)
+	case ".json":
+		return DecodeJSON(r)
+	default:
+		return nil, nil, ErrUnknownFormat
+	}
+}
+
+func (q *Question) validate() []string {
+	var problems []string
+
+	if q.Question == "" && q.FileID == "" {
+		problems = append(problems, "нет ни текста вопроса, ни медиа")
+	}
+
+	if q.FileID != "" {
+		switch q.MediaType {
+		case entity.MediaPhoto, entity.MediaVideo, entity.MediaAnimation, entity.MediaDocument, entity.MediaAudio, entity.MediaVoice:
+		case "":
+			problems = append(problems, "не указан тип медиа")
+		default:
+			problems = append(problems, "неизвестный тип медиа "+string(q.MediaType))
+		}
+		if length := len(utf16.Encode([]rune(q.Question))); length > maxCaptionLength {
+			problems = append(problems, fmt.Sprintf("подпись к медиа длиннее %d символов", maxCaptionLength))
+		}
+	} else if q.MediaType != "" {
+		problems = append(problems, "указан тип медиа, но нет File ID")
+	}
+
+	if q.Difficulty != nil && (*q.Difficulty < 1 || *q.Difficulty > entity.MaxQuestionDifficulty) {
+		problems = append(problems, fmt.Sprintf("сложность должна быть от 1 до %d", entity.MaxQuestionDifficulty))
+	}
+	if q.ButtonsPerRow != nil && *q.ButtonsPerRow != entity.ButtonsPerRowAuto &&
+		(*q.ButtonsPerRow < 1 || *q.ButtonsPerRow > entity.MaxButtonsPerRow) {
+		problems = append(problems, fmt.Sprintf("кнопок в ряду должно быть от 1 до %d или %s",
+			entity.MaxButtonsPerRow, buttonsPerRowAuto))
+	}
+
+	if length := len(utf16.Encode([]rune(q.Question))); length > maxTextLength {
+		problems = append(problems, fmt.Sprintf("текст вопроса длиннее %d символов", maxTextLength))
+	}
+
+	for key, answer := range q.Answers {
+		if answer.Answer == "" {
+			problems = append(problems, fmt.Sprintf("ответ %d без текста", key+1))
+		}
+		if utf8.RuneCountInString(answer.Answer) > maxAnswerLength {
+			problems = append(problems, fmt.Sprintf("ответ %d длиннее %d символов", key+1, maxAnswerLength))
+		}
+	}
+
+	return problems
+}
+
+func (q *Question) ToQuiz(channelID int64, createdBy int64) entity.Quiz {
+	quiz := entity.Quiz{
+		Question: entity.Question{
+			QuestionName:     q.Question,
+			QuestionEntities: q.Entities,
+			TextFormat:       entity.TextFormatEntities,
+			MediaType:        q.MediaType,
+			ChannelID:        channelID,
+			CreatedByUser:    createdBy,
+			Tags:             entity.ParseTags(strings.Join(q.Tags, ",")),
+			Difficulty:       q.Difficulty,
+			ButtonsPerRow:    q.ButtonsPerRow,
+			ShuffleAnswers:   q.ShuffleAnswers,
+		},
+		Answer: make([]entity.Answer, len(q.Answers)),
+	}
+	if q.FileID != "" {
+		fileID := q.FileID
+		quiz.Question.FileID = &fileID
+	}
+
+	for key, answer := range q.Answers {
+		quiz.Answer[key] = entity.Answer{
+			Answer:         answer.Answer,
+			CostOfResponse: answer.Cost,
+			IsCorrect:      answer.Cost > 0,
+		}
+		if answer.Correct != nil {
+			quiz.Answer[key].IsCorrect = *answer.Correct
+		}
+	}
+
+	return quiz
+}
+
+// FromQuiz converts a stored quiz to the bank model. Legacy MarkdownV2
+// questions are exported as plain text, their markup has no entities.
+func FromQuiz(quiz entity.Quiz) Question {
+	question := Question{
+		Question:       quiz.Question.QuestionName,
+		Entities:       quiz.Question.QuestionEntities,
+		MediaType:      quiz.Question.MediaType,
+		Tags:           quiz.Question.Tags,
+		Difficulty:     quiz.Question.Difficulty,
+		ButtonsPerRow:  quiz.Question.ButtonsPerRow,
+		ShuffleAnswers: quiz.Question.ShuffleAnswers,
+		Answers:        make([]Answer, len(quiz.Answer)),
+		ID:             quiz.Question.ID,
+		IsSend:         quiz.Question.IsSend,
+		Deadline:       quiz.Question.Deadline,
+		CreatedBy:      quiz.Question.CreatedByUser,
+	}
+	if quiz.Question.TextFormat == entity.TextFormatMarkdownV2 {
+		question.Question, question.Entities = quiz.Question.PlainText(), nil
+	}
+	if quiz.Question.FileID != nil {
+		question.FileID = *quiz.Question.FileID
+	}
+	if !quiz.Question.CreatedAt.IsZero() {
+		createdAt := quiz.Question.CreatedAt
+		question.CreatedAt = &createdAt
+	}
+
+	for key, answer := range quiz.Answer {
+		correct := answer.IsCorrect
+		question.Answers[key] = Answer{
+			Answer:  answer.Answer,
+			Cost:    answer.CostOfResponse,
+			Correct: &correct,
+		}
+	}
+
+	return question
+}
+
+// InLocation moves the times of the questions to location, so an export shows
+// them in the timezone of the channel.
+func InLocation(questions []Question, location *time.Location) {
+	for key := range questions {
+		if t := questions[key].Deadline; t != nil {
+			deadline := t.In(location)
+			questions[key].Deadline = &deadline
+		}
+		if t := questions[key].CreatedAt; t != nil {
+			createdAt := t.In(location)
+			questions[key].CreatedAt = &createdAt
+		}
+	}
+}
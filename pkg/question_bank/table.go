@@ -0,0 +1,316 @@
+package question_bank
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns of xlsx and csv bank files. Columns are matched by header, so their
+// order is free and export-only columns are skipped on import.
+const (
+	ColNumber        = "Question #"
+	ColQuestion      = "Question"
+	ColEntities      = "Question entities"
+	ColMediaType     = "Media type"
+	ColFileID        = "File ID"
+	ColTags          = "Tags"
+	ColDifficulty    = "Difficulty"
+	ColButtonsPerRow = "Buttons per row"
+	ColShuffle       = "Shuffle answers"
+	ColAnswer        = "Answer"
+	ColCost          = "Cost"
+	ColCorrect       = "Correct"
+
+	ColQuestionID = "Question ID"
+	ColSent       = "Sent"
+	ColDeadline   = "Deadline"
+	ColCreatedBy  = "Created by"
+	ColCreatedAt  = "Created at"
+)
+
+var exportColumns = []string{
+	ColNumber, ColQuestion, ColEntities, ColMediaType, ColFileID, ColTags, ColDifficulty, ColButtonsPerRow, ColShuffle,
+	ColAnswer, ColCost, ColCorrect,
+	ColQuestionID, ColSent, ColDeadline, ColCreatedBy, ColCreatedAt,
+}
+
+var errNoHeader = errors.New("the first row must contain the Question and Answer columns")
+
+type tableRow struct {
+	header map[string]int
+	cells  []string
+}
+
+func (t tableRow) get(column string) string {
+	idx, ok := t.header[strings.ToLower(column)]
+	if !ok || idx >= len(t.cells) {
+		return ""
+	}
+	return strings.TrimSpace(t.cells[idx])
+}
+
+func (t tableRow) empty() bool {
+	for _, cell := range t.cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+type pendingQuestion struct {
+	question Question
+	number   string
+	row      int
+	problems []string
+}
+
+// decodeTable groups rows into questions. A row starts a new question when its
+// Question # differs from the previous row or, without numbers, when it has
+// question text. Other rows add answers to the current question.
+func decodeTable(rows [][]string) ([]Question, []RowError, error) {
+	if len(rows) == 0 {
+		return nil, nil, errNoHeader
+	}
+
+	header := make(map[string]int, len(rows[0]))
+	for idx, name := range rows[0] {
+		header[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = idx
+	}
+	_, hasQuestion := header[strings.ToLower(ColQuestion)]
+	_, hasAnswer := header[strings.ToLower(ColAnswer)]
+	if !hasQuestion || !hasAnswer {
+		return nil, nil, errNoHeader
+	}
+
+	var (
+		questions []Question
+		rowErrors []RowError
+		current   *pendingQuestion
+	)
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		problems := append(current.problems, current.question.validate()...)
+		if len(problems) > 0 {
+			rowErrors = append(rowErrors, RowError{
+				Position: fmt.Sprintf("строка %d", current.row),
+				Message:  strings.Join(problems, "; "),
+			})
+		} else {
+			questions = append(questions, current.question)
+		}
+		current = nil
+	}
+
+	for idx, cells := range rows[1:] {
+		rowNumber := idx + 2
+		row := tableRow{header: header, cells: cells}
+		if row.empty() {
+			continue
+		}
+
+		number, text := row.get(ColNumber), row.get(ColQuestion)
+		if current == nil || (number != "" && number != current.number) || (number == "" && text != "") {
+			flush()
+			current = &pendingQuestion{number: number, row: rowNumber}
+			current.question = Question{
+				Question:  text,
+				MediaType: entity.MediaType(strings.ToLower(row.get(ColMediaType))),
+				FileID:    row.get(ColFileID),
+			}
+			if raw := row.get(ColEntities); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &current.question.Entities); err != nil {
+					current.problems = append(current.problems, "некорректный JSON в колонке "+ColEntities)
+				}
+			}
+			current.problems = append(current.problems, parseSettings(row, &current.question)...)
+		}
+
+		answer, problems := parseAnswer(row)
+		for _, problem := range problems {
+			if rowNumber != current.row {
+				problem = fmt.Sprintf("строка %d: %s", rowNumber, problem)
+			}
+			current.problems = append(current.problems, problem)
+		}
+		if answer != nil {
+			current.question.Answers = append(current.question.Answers, *answer)
+		}
+	}
+	flush()
+
+	return questions, rowErrors, nil
+}
+
+// encodeTable is the reverse of decodeTable: the first row of a question holds
+// its fields, the following rows repeat only the number and hold answers.
+func encodeTable(questions []Question) [][]string {
+	rows := [][]string{exportColumns}
+
+	for key, question := range questions {
+		number := strconv.Itoa(key + 1)
+
+		first := map[string]string{
+			ColNumber:        number,
+			ColQuestion:      question.Question,
+			ColMediaType:     string(question.MediaType),
+			ColFileID:        question.FileID,
+			ColTags:          strings.Join(question.Tags, ", "),
+			ColDifficulty:    formatInt(question.Difficulty),
+			ColButtonsPerRow: formatButtonsPerRow(question.ButtonsPerRow),
+			ColQuestionID:    strconv.Itoa(question.ID),
+			ColSent:          strconv.FormatBool(question.IsSend),
+			ColDeadline:      formatTime(question.Deadline),
+			ColCreatedBy:     strconv.FormatInt(question.CreatedBy, 10),
+			ColCreatedAt:     formatTime(question.CreatedAt),
+		}
+		if question.ShuffleAnswers != nil {
+			first[ColShuffle] = strconv.FormatBool(*question.ShuffleAnswers)
+		}
+		if len(question.Entities) > 0 {
+			if raw, err := json.Marshal(question.Entities); err == nil {
+				first[ColEntities] = string(raw)
+			}
+		}
+
+		if len(question.Answers) == 0 {
+			rows = append(rows, tableCells(first))
+			continue
+		}
+		for idx, answer := range question.Answers {
+			cells := map[string]string{ColNumber: number}
+			if idx == 0 {
+				cells = first
+			}
+			cells[ColAnswer] = answer.Answer
+			cells[ColCost] = strconv.Itoa(answer.Cost)
+			if answer.Correct != nil {
+				cells[ColCorrect] = strconv.FormatBool(*answer.Correct)
+			}
+			rows = append(rows, tableCells(cells))
+		}
+	}
+
+	return rows
+}
+
+func tableCells(values map[string]string) []string {
+	cells := make([]string, len(exportColumns))
+	for idx, column := range exportColumns {
+		cells[idx] = values[column]
+	}
+	return cells
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatButtonsPerRow(value *int) string {
+	if value != nil && *value == entity.ButtonsPerRowAuto {
+		return buttonsPerRowAuto
+	}
+	return formatInt(value)
+}
+
+// parseSettings reads the question settings columns. Range checks are left to
+// Question.validate, so JSON and tables report them the same way.
+func parseSettings(row tableRow, question *Question) []string {
+	var problems []string
+
+	if raw := row.get(ColTags); raw != "" {
+		question.Tags = strings.Split(raw, ",")
+	}
+
+	if raw := row.get(ColDifficulty); raw != "" {
+		if difficulty, err := strconv.Atoi(raw); err != nil {
+			problems = append(problems, "сложность должна быть целым числом: "+raw)
+		} else {
+			question.Difficulty = &difficulty
+		}
+	}
+
+	if raw := row.get(ColButtonsPerRow); raw != "" {
+		perRow, err := strconv.Atoi(raw)
+		if strings.EqualFold(raw, buttonsPerRowAuto) {
+			perRow, err = entity.ButtonsPerRowAuto, nil
+		}
+		if err != nil {
+			problems = append(problems, "кнопок в ряду должно быть целым числом или "+buttonsPerRowAuto+": "+raw)
+		} else {
+			question.ButtonsPerRow = &perRow
+		}
+	}
+
+	if raw := row.get(ColShuffle); raw != "" {
+		if shuffle, ok := parseBool(raw); !ok {
+			problems = append(problems, "не удалось распознать признак перемешивания ответов: "+raw)
+		} else {
+			question.ShuffleAnswers = &shuffle
+		}
+	}
+
+	return problems
+}
+
+func parseAnswer(row tableRow) (*Answer, []string) {
+	text, rawCost, rawCorrect := row.get(ColAnswer), row.get(ColCost), row.get(ColCorrect)
+	if text == "" {
+		if rawCost != "" || rawCorrect != "" {
+			return nil, []string{"цена или признак верного ответа без текста ответа"}
+		}
+		return nil, nil
+	}
+
+	var problems []string
+	answer := &Answer{Answer: text}
+
+	if rawCost != "" {
+		cost, err := strconv.ParseFloat(strings.ReplaceAll(rawCost, ",", "."), 64)
+		if err != nil || cost != math.Trunc(cost) {
+			problems = append(problems, "цена ответа должна быть целым числом: "+rawCost)
+		} else {
+			answer.Cost = int(cost)
+		}
+	}
+
+	if rawCorrect != "" {
+		correct, ok := parseBool(rawCorrect)
+		if !ok {
+			problems = append(problems, "не удалось распознать признак верного ответа: "+rawCorrect)
+		} else {
+			answer.Correct = &correct
+		}
+	}
+
+	return answer, problems
+}
+
+func parseBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "y", "да", "+", "x", "✅":
+		return true, true
+	case "0", "false", "no", "n", "нет", "-":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,50 @@
+package question_bank
+
+import (
+	"github.com/xuri/excelize/v2"
+	"io"
+)
+
+const sheetName = "Questions"
+
+// DecodeXLSX reads the first sheet of the workbook.
+func DecodeXLSX(r io.Reader) ([]Question, []RowError, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decodeTable(rows)
+}
+
+// EncodeXLSX writes the questions to a single sheet, one answer per row.
+func EncodeXLSX(w io.Writer, questions []Question) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName(f.GetSheetName(0), sheetName); err != nil {
+		return err
+	}
+
+	for idx, row := range encodeTable(questions) {
+		cell, err := excelize.CoordinatesToCellName(1, idx+1)
+		if err != nil {
+			return err
+		}
+		values := make([]interface{}, len(row))
+		for key, value := range row {
+			values[key] = value
+		}
+		if err = f.SetSheetRow(sheetName, cell, &values); err != nil {
+			return err
+		}
+	}
+
+	return f.Write(w)
+}
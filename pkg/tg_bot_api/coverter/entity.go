@@ -0,0 +1,37 @@
+package coverter
+
+import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+// MessageEntity is tgbotapi.MessageEntity with the fields the library doesn't
+// decode, so the entities are stored and sent back as Telegram sent them.
+type MessageEntity struct {
+	tgbotapi.MessageEntity
+	// CustomEmojiID is set for custom_emoji only
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"`
+}
+
+// Entities wraps the entities decoded by the library. Custom emoji come
+// without their id and fall back to the plain emoji.
+func Entities(entities []tgbotapi.MessageEntity) []MessageEntity {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	result := make([]MessageEntity, 0, len(entities))
+	for _, e := range entities {
+		result = append(result, MessageEntity{MessageEntity: e})
+	}
+	return result
+}
+
+// SendableEntities drops custom emoji without an id, Telegram refuses them.
+func SendableEntities(entities []MessageEntity) []MessageEntity {
+	result := make([]MessageEntity, 0, len(entities))
+	for _, e := range entities {
+		if e.Type == "custom_emoji" && e.CustomEmojiID == "" {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package coverter
+
+import (
+	"fmt"
+	"html"
+)
+
+type htmlFormatter struct{}
+
+func (htmlFormatter) escape(text string, _ bool) string {
+	return html.EscapeString(text)
+}
+
+func (htmlFormatter) wrap(e MessageEntity, content string) string {
+	switch e.Type {
+	case "bold":
+		return "<b>" + content + "</b>"
+	case "italic":
+		return "<i>" + content + "</i>"
+	case "underline":
+		return "<u>" + content + "</u>"
+	case "strikethrough":
+		return "<s>" + content + "</s>"
+	case "spoiler":
+		return "<tg-spoiler>" + content + "</tg-spoiler>"
+	case "code":
+		return "<code>" + content + "</code>"
+	case "pre":
+		if e.Language != "" {
+			return fmt.Sprintf(`<pre><code class="language-%s">%s</code></pre>`, html.EscapeString(e.Language), content)
+		}
+		return "<pre>" + content + "</pre>"
+	case "text_link":
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(e.URL), content)
+	case "text_mention":
+		if e.User != nil {
+			return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, e.User.ID, content)
+		}
+	case "blockquote":
+		return "<blockquote>" + content + "</blockquote>"
+	case "expandable_blockquote":
+		return "<blockquote expandable>" + content + "</blockquote>"
+	case "custom_emoji":
+		if e.CustomEmojiID != "" {
+			return fmt.Sprintf(`<tg-emoji emoji-id="%s">%s</tg-emoji>`, html.EscapeString(e.CustomEmojiID), content)
+		}
+	}
+	return content
+}
+
+func ConvertToHTML(text string, messageEntities []MessageEntity) string {
+	return render(text, messageEntities, htmlFormatter{})
+}
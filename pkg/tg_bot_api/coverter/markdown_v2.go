@@ -1,61 +1,81 @@
 package coverter
 
 import (
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"unicode/utf16"
+	"fmt"
+	"strings"
 )
 
 var needEscape = make(map[rune]struct{})
 
 func init() {
-	for _, r := range []rune{'_', '*', '[', ']', '(', ')', '~', '`', '>', '#', '+', '-', '=', '|', '{', '}', '.', '!'} {
+	for _, r := range []rune{'_', '*', '[', ']', '(', ')', '~', '`', '>', '#', '+', '-', '=', '|', '{', '}', '.', '!', '\\'} {
 		needEscape[r] = struct{}{}
 	}
 }
 
-func ConvertToMarkdownV2(text string, messageEntities []tgbotapi.MessageEntity) string {
-	insertions := make(map[int]string)
-	for _, e := range messageEntities {
-		var before, after string
-		if e.IsBold() {
-			before = "*"
-			after = "*"
-		} else if e.IsItalic() {
-			before = "_"
-			after = "_"
-		} else if e.Type == "underline" {
-			before = "__"
-			after = "__"
-		} else if e.Type == "strikethrough" {
-			before = "~"
-			after = "~"
-		} else if e.IsCode() {
-			before = "`"
-			after = "`"
-		} else if e.IsPre() {
-			before = "```" + e.Language
-			after = "```"
-		} else if e.IsTextLink() {
-			before = "["
-			after = "](" + e.URL + ")"
-		}
-		if before != "" {
-			insertions[e.Offset] += before
-			insertions[e.Offset+e.Length] += after
+type markdownV2Formatter struct{}
+
+func (markdownV2Formatter) escape(text string, inCode bool) string {
+	var sb strings.Builder
+	for _, c := range text {
+		if inCode {
+			if c == '`' || c == '\\' {
+				sb.WriteRune('\\')
+			}
+		} else if _, has := needEscape[c]; has {
+			sb.WriteRune('\\')
 		}
+		sb.WriteRune(c)
 	}
+	return sb.String()
+}
 
-	input := []rune(text)
-	var output []rune
-	utf16pos := 0
-	for _, c := range input {
-		output = append(output, []rune(insertions[utf16pos])...)
-		if _, has := needEscape[c]; has {
-			output = append(output, '\\')
+func (markdownV2Formatter) wrap(e MessageEntity, content string) string {
+	switch e.Type {
+	case "bold":
+		return "*" + content + "*"
+	case "italic":
+		return "_" + content + "_"
+	case "underline":
+		return "__" + content + "__"
+	case "strikethrough":
+		return "~" + content + "~"
+	case "spoiler":
+		return "||" + content + "||"
+	case "code":
+		return "`" + content + "`"
+	case "pre":
+		return "```" + e.Language + "\n" + content + "\n```"
+	case "text_link":
+		return "[" + content + "](" + escapeLinkURL(e.URL) + ")"
+	case "text_mention":
+		if e.User != nil {
+			return fmt.Sprintf("[%s](tg://user?id=%d)", content, e.User.ID)
+		}
+	case "blockquote":
+		return quoteLines(content, ">")
+	case "expandable_blockquote":
+		return "**" + quoteLines(content, ">") + "||"
+	case "custom_emoji":
+		if e.CustomEmojiID != "" {
+			return "![" + content + "](tg://emoji?id=" + escapeLinkURL(e.CustomEmojiID) + ")"
 		}
-		output = append(output, c)
-		utf16pos += len(utf16.Encode([]rune{c}))
 	}
-	output = append(output, []rune(insertions[utf16pos])...)
-	return string(output)
+	return content
+}
+
+func escapeLinkURL(url string) string {
+	return strings.NewReplacer(`\`, `\\`, `)`, `\)`).Replace(url)
+}
+
+func quoteLines(content, prefix string) string {
+	lines := strings.Split(content, "\n")
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func ConvertToMarkdownV2(text string, messageEntities []MessageEntity) string {
+	return render(text, messageEntities, markdownV2Formatter{})
 }
@@ -0,0 +1,33 @@
+package coverter
+
+import "strings"
+
+type plainFormatter struct{}
+
+func (plainFormatter) escape(text string, _ bool) string {
+	return text
+}
+
+func (plainFormatter) wrap(_ MessageEntity, content string) string {
+	return content
+}
+
+func ConvertToPlain(text string, messageEntities []MessageEntity) string {
+	return render(text, messageEntities, plainFormatter{})
+}
+
+// UnescapeMarkdownV2 removes MarkdownV2 escaping from text stored before
+// questions were kept together with their entities.
+func UnescapeMarkdownV2(text string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range text {
+		if !escaped && r == '\\' {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+package coverter
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// formatter turns plain text and entities into one of the output formats.
+type formatter interface {
+	escape(text string, inCode bool) string
+	wrap(entity MessageEntity, content string) string
+}
+
+type node struct {
+	entity     *MessageEntity
+	start, end int
+	children   []*node
+}
+
+// render walks entities as a tree, so nested entities are closed in the right
+// order. Offsets and lengths are in UTF-16 code units as in the Bot API.
+func render(text string, entities []MessageEntity, f formatter) string {
+	text16 := utf16.Encode([]rune(text))
+	root := buildTree(text16, entities)
+	return renderNode(text16, root, f, false)
+}
+
+func buildTree(text16 []uint16, entities []MessageEntity) *node {
+	sorted := make([]MessageEntity, len(entities))
+	copy(sorted, entities)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+
+	root := &node{start: 0, end: len(text16)}
+	stack := []*node{root}
+	for i := range sorted {
+		start := clamp(sorted[i].Offset, 0, len(text16))
+		end := clamp(sorted[i].Offset+sorted[i].Length, start, len(text16))
+		if start == end {
+			continue
+		}
+
+		for len(stack) > 1 && stack[len(stack)-1].end <= start {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		// entities must not intersect partially, clip the broken ones to the parent
+		if end > parent.end {
+			end = parent.end
+		}
+
+		n := &node{entity: &sorted[i], start: start, end: end}
+		parent.children = append(parent.children, n)
+		stack = append(stack, n)
+	}
+
+	return root
+}
+
+func renderNode(text16 []uint16, n *node, f formatter, inCode bool) string {
+	if n.entity != nil && isCode(*n.entity) {
+		inCode = true
+	}
+
+	var sb strings.Builder
+	pos := n.start
+	for _, child := range n.children {
+		sb.WriteString(f.escape(string(utf16.Decode(text16[pos:child.start])), inCode))
+		sb.WriteString(renderNode(text16, child, f, inCode))
+		pos = child.end
+	}
+	sb.WriteString(f.escape(string(utf16.Decode(text16[pos:n.end])), inCode))
+
+	if n.entity == nil {
+		return sb.String()
+	}
+	return f.wrap(*n.entity, sb.String())
+}
+
+func isCode(entity MessageEntity) bool {
+	return entity.IsCode() || entity.IsPre()
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
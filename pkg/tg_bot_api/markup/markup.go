@@ -2,8 +2,12 @@ package markup
 
 import (
 	"fmt"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/button"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"strings"
+	"time"
 )
 
 var (
@@ -37,7 +41,19 @@ func QuizSettingV2(channelID int64) tgbotapi.InlineKeyboardMarkup {
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Открыть список вопросов", fmt.Sprintf("list_question_%d", channelID))),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Удалить вопрос", fmt.Sprintf("delete_question_%d", channelID))),
+			tgbotapi.NewInlineKeyboardButtonData("Удалить вопрос", fmt.Sprintf("delete_question_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("Корзина", fmt.Sprintf("trash_list_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Импорт вопросов", fmt.Sprintf("import_question_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("Экспорт вопросов", fmt.Sprintf("export_question_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Копировать вопросы в канал…", fmt.Sprintf("clone_select_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Серии вопросов", fmt.Sprintf("series_list_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("Автопилот", fmt.Sprintf("autopilot_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Регулярные задачи", fmt.Sprintf("jobs_list_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("Настройки канала", fmt.Sprintf("chset_%d", channelID))),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Скачать рейтинг", fmt.Sprintf("downloading_rating_%d", channelID))),
 		tgbotapi.NewInlineKeyboardRow(
@@ -56,9 +72,18 @@ func QuestionSetting(questionID int) tgbotapi.InlineKeyboardMarkup {
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Обновить ответы", fmt.Sprintf("update_answers_%d", questionID))),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Добавить изображение", fmt.Sprintf("add_image_%d", questionID))),
+			tgbotapi.NewInlineKeyboardButtonData("Добавить медиа", fmt.Sprintf("add_image_%d", questionID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Удалить медиа", fmt.Sprintf("delete_image_%d", questionID))),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Предварительный просмотр", fmt.Sprintf("quiz_check_%d", questionID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Теги и сложность", fmt.Sprintf("qmeta_%d", questionID)),
+			tgbotapi.NewInlineKeyboardButtonData("Кнопки ответов", fmt.Sprintf("qlayout_%d", questionID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("История изменений", fmt.Sprintf("qhistory_%d", questionID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Копировать в канал…", fmt.Sprintf("qclone_%d", questionID))),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData("Отправить вопрос в канал", fmt.Sprintf("send_question_%d", questionID))),
 		tgbotapi.NewInlineKeyboardRow(
@@ -70,3 +95,710 @@ func CancelCommandQuestion(questionID int) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("Отмена выполнения", fmt.Sprintf("cancel_update_%d", questionID))))
 }
+
+func QuestionMeta(question *entity.Question) tgbotapi.InlineKeyboardMarkup {
+	row := make([]tgbotapi.InlineKeyboardButton, 0, entity.MaxQuestionDifficulty)
+	for value := 1; value <= entity.MaxQuestionDifficulty; value++ {
+		text := fmt.Sprint(value)
+		if question.Difficulty != nil && *question.Difficulty == value {
+			text = "✅ " + text
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("qdiff_%d_%d", question.ID, value)))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		row,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Без сложности", fmt.Sprintf("qdiff_%d_0", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Изменить теги", fmt.Sprintf("qtags_%d", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("question_get_%d", question.ID))),
+	)
+}
+
+func QuestionMetaText(question *entity.Question) string {
+	difficulty := "не задана"
+	if question.Difficulty != nil {
+		difficulty = fmt.Sprintf("%d из %d", *question.Difficulty, entity.MaxQuestionDifficulty)
+	}
+	return fmt.Sprintf("Теги: %s\nСложность: %s\n\nПо тегам и сложности автопилот выбирает вопросы для публикации",
+		html.EscapeString(entity.TagsTitle(question.Tags)), difficulty)
+}
+
+func QuestionLayout(question *entity.Question) tgbotapi.InlineKeyboardMarkup {
+	checked := func(text string, ok bool) string {
+		if ok {
+			return "✅ " + text
+		}
+		return text
+	}
+
+	row := make([]tgbotapi.InlineKeyboardButton, 0, entity.MaxButtonsPerRow+1)
+	for value := 1; value <= entity.MaxButtonsPerRow; value++ {
+		text := checked(fmt.Sprint(value), question.ButtonsPerRow != nil && *question.ButtonsPerRow == value)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("qrow_%d_%d", question.ID, value)))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+		checked("Авто", question.ButtonsPerRow != nil && *question.ButtonsPerRow == entity.ButtonsPerRowAuto),
+		fmt.Sprintf("qrow_%d_%d", question.ID, entity.ButtonsPerRowAuto)))
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		row,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(checked("Кнопок в ряд как в канале", question.ButtonsPerRow == nil),
+				fmt.Sprintf("qrow_%d_0", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(checked("Перемешивать", question.ShuffleAnswers != nil && *question.ShuffleAnswers),
+				fmt.Sprintf("qshuffle_%d_1", question.ID)),
+			tgbotapi.NewInlineKeyboardButtonData(checked("Не перемешивать", question.ShuffleAnswers != nil && !*question.ShuffleAnswers),
+				fmt.Sprintf("qshuffle_%d_2", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(checked("Порядок ответов как в канале", question.ShuffleAnswers == nil),
+				fmt.Sprintf("qshuffle_%d_0", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("question_get_%d", question.ID))),
+	)
+}
+
+func QuestionLayoutText(question *entity.Question) string {
+	buttonsPerRow := "как в канале"
+	if question.ButtonsPerRow != nil {
+		buttonsPerRow = entity.ButtonsPerRowTitle(*question.ButtonsPerRow)
+	}
+	shuffle := "как в канале"
+	if question.ShuffleAnswers != nil && *question.ShuffleAnswers {
+		shuffle = "случайный"
+	} else if question.ShuffleAnswers != nil {
+		shuffle = "как в вопросе"
+	}
+
+	return fmt.Sprintf("Кнопок ответов в ряд: %s\nПорядок ответов: %s\n\n"+
+		"«Авто» ставит в ряд столько кнопок, сколько поместится по длине ответов. "+
+		"Случайный порядок выбирается при публикации, опубликованные посты его сохраняют", buttonsPerRow, shuffle)
+}
+
+func PublishPreflight(questionID int, allowForce bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if allowForce {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Опубликовать всё равно", fmt.Sprintf("send_anyway_%d", questionID))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("question_get_%d", questionID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func PublishTargets(channels []entity.Channel, publication *entity.Publication) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(channels)+3)
+	for _, channel := range channels {
+		mark := "▫️"
+		if publication.HasChannel(channel.TgID) {
+			mark = "✅"
+		}
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", mark, channel.ChannelName),
+				fmt.Sprintf("pubch_%d_%d", publication.QuestionID, channel.TgID))))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Очки: "+publication.Scoring.Title(), fmt.Sprintf("pubmode_%d", publication.QuestionID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Отправить (%d)", len(publication.ChannelIDs)),
+				fmt.Sprintf("pubsend_%d", publication.QuestionID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("question_get_%d", publication.QuestionID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func BackToChannel(channelID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", channelID))))
+}
+
+func ImportConfirm(channelID int64, count int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if count > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Импортировать вопросы: %d", count), fmt.Sprintf("import_confirm_%d", channelID))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Отмена", fmt.Sprintf("channel_get_%d", channelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func ExportQuestionFormat(channelID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Excel (.xlsx)", fmt.Sprintf("bank_xlsx_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("JSON", fmt.Sprintf("bank_json_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", channelID))),
+	)
+}
+
+func RatingFormat(channelID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Excel (.xlsx)", fmt.Sprintf("rating_xlsx_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("JSON Lines", fmt.Sprintf("rating_jsonl_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("CSV (,)", fmt.Sprintf("rating_csv_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("CSV (;)", fmt.Sprintf("rating_semicolon_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Изменить фильтр", fmt.Sprintf("downloading_rating_%d", channelID))),
+	)
+}
+
+func RatingFilter(channelID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вся история", fmt.Sprintf("rfilter_all_%d", channelID)),
+			tgbotapi.NewInlineKeyboardButtonData("Текущий сезон", fmt.Sprintf("rfilter_current_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Период по дате ответа", fmt.Sprintf("rfilter_answered_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Период по дате публикации", fmt.Sprintf("rfilter_published_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Выбрать вопросы", fmt.Sprintf("rfilter_questions_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Прошлые сезоны", fmt.Sprintf("rfilter_seasons_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", channelID))),
+	)
+}
+
+// RatingFormatText is the text shown with the RatingFormat markup.
+func RatingFormatText(filter *entity.ExportFilter) string {
+	return "Фильтр: " + filter.String() + "\n\nВыберите формат рейтинга. В Excel есть лидерборд и статистика по вопросам, " +
+		"CSV и JSON Lines содержат ответы пользователей построчно"
+}
+
+func RatingQuestionSelect(questions []entity.Question, filter *entity.ExportFilter, channelID int64) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(questions)+2)
+	for _, question := range questions {
+		mark := "▫️"
+		if filter.HasQuestion(question.ID) {
+			mark = "✅"
+		}
+
+		name := []rune(question.PlainText())
+		if len(name) > 30 {
+			name = name[:30]
+		}
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", mark, string(name)), fmt.Sprintf("rtoggle_%d", question.ID))))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Готово", fmt.Sprintf("rfilter_done_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("downloading_rating_%d", channelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func CloneQuestionSelect(questions []entity.Question, request *entity.CloneRequest) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(questions)+2)
+	for _, question := range questions {
+		mark := "▫️"
+		if request.HasQuestion(question.ID) {
+			mark = "✅"
+		}
+
+		name := []rune(question.PlainText())
+		if len(name) > 30 {
+			name = name[:30]
+		}
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", mark, string(name)), fmt.Sprintf("clone_toggle_%d", question.ID))))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Выбрать канал", fmt.Sprintf("clone_target_%d", request.SourceChannelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", request.SourceChannelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func CloneTargets(channels []entity.Channel, back string) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(channels)+1)
+	for _, channel := range channels {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(channel.ChannelName, fmt.Sprintf("clone_into_%d", channel.TgID))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", back)))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func SeriesList(list []entity.Series, channelID int64) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(list)+2)
+	for _, series := range list {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s — %s", series.Title, series.Status.Title()),
+				fmt.Sprintf("series_get_%d", series.ID))))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Создать серию", fmt.Sprintf("series_new_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", channelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func SeriesQuestionSelect(questions []entity.Question, draft *entity.Series) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(questions)+2)
+	for _, question := range questions {
+		mark := "▫️"
+		if position := draft.QuestionPosition(question.ID); position > 0 {
+			mark = fmt.Sprintf("%d.", position)
+		}
+
+		name := []rune(question.PlainText())
+		if len(name) > 30 {
+			name = name[:30]
+		}
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s", mark, string(name)), fmt.Sprintf("series_toggle_%d", question.ID))))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Выбрать интервал", fmt.Sprintf("series_interval_%d", draft.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("series_list_%d", draft.ChannelTgID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// SeriesIntervals offers how many minutes every question of the series is
+// open.
+func SeriesIntervals(minutes []int, channelID int64) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, value := range minutes {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d мин.", value), fmt.Sprintf("series_window_%d", value)))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		row,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("series_new_%d", channelID))),
+	)
+}
+
+func SeriesSetting(series *entity.Series) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	switch series.Status {
+	case entity.SeriesDraft:
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("▶️ Запустить", fmt.Sprintf("series_start_%d", series.ID))))
+	case entity.SeriesRunning:
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⏸ Пауза", fmt.Sprintf("series_pause_%d", series.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("⏭ Следующий вопрос", fmt.Sprintf("series_skip_%d", series.ID))))
+	case entity.SeriesPaused:
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("▶️ Продолжить", fmt.Sprintf("series_resume_%d", series.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("⏭ Следующий вопрос", fmt.Sprintf("series_skip_%d", series.ID))))
+	}
+
+	if series.Status != entity.SeriesFinished {
+		reveal := "Итоги после вопроса: выкл"
+		if series.RevealResults {
+			reveal = "Итоги после вопроса: вкл"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(reveal, fmt.Sprintf("series_reveal_%d", series.ID))))
+	}
+	if series.Status != entity.SeriesDraft {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Рейтинг серии", fmt.Sprintf("series_rating_%d", series.ID))))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Удалить серию", fmt.Sprintf("series_delete_%d", series.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("series_list_%d", series.ChannelTgID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func AutopilotSetting(autopilot *entity.Autopilot) tgbotapi.InlineKeyboardMarkup {
+	toggle := "▶️ Включить"
+	if autopilot.Enabled {
+		toggle = "⏸ Выключить"
+	}
+	difficulty := "Сложность: любая"
+	if autopilot.Difficulty != nil {
+		difficulty = fmt.Sprintf("Сложность: %d", *autopilot.Difficulty)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(toggle, fmt.Sprintf("apl_toggle_%d", autopilot.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Изменить расписание", fmt.Sprintf("apl_schedule_%d", autopilot.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Тег", fmt.Sprintf("apl_tag_%d", autopilot.ChannelTgID)),
+			tgbotapi.NewInlineKeyboardButtonData(difficulty, fmt.Sprintf("apl_diff_%d", autopilot.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Предупреждать при остатке: %d", autopilot.LowBankThreshold),
+				fmt.Sprintf("apl_low_%d", autopilot.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", autopilot.ChannelTgID))),
+	)
+}
+
+// AutopilotText shows the settings of the autopilot, times are in the
+// timezone of the channel.
+func AutopilotText(autopilot *entity.Autopilot, unsent int) string {
+	location := entity.LoadLocation(autopilot.Timezone)
+
+	var sb strings.Builder
+	sb.WriteString("<b>Автопилот</b> публикует неотправленный вопрос канала по расписанию cron\n\n")
+	if autopilot.Enabled {
+		sb.WriteString("Статус: включён\n")
+	} else {
+		sb.WriteString("Статус: выключен\n")
+	}
+	sb.WriteString(fmt.Sprintf("Расписание: <code>%s</code> (%s)\n", html.EscapeString(autopilot.Schedule), html.EscapeString(autopilot.Timezone)))
+	sb.WriteString(fmt.Sprintf("Вопросы: %s\n", html.EscapeString(autopilot.FilterTitle())))
+	sb.WriteString(fmt.Sprintf("Подходящих неотправленных вопросов: %d\n", unsent))
+	sb.WriteString(fmt.Sprintf("Предупреждать администраторов при остатке: %d\n", autopilot.LowBankThreshold))
+	if autopilot.Enabled && autopilot.NextRunAt != nil {
+		sb.WriteString(fmt.Sprintf("Следующая публикация: %s\n", autopilot.NextRunAt.In(location).Format("02.01.2006 15:04")))
+	}
+	if autopilot.LastRunAt != nil {
+		sb.WriteString(fmt.Sprintf("Последний запуск: %s\n", autopilot.LastRunAt.In(location).Format("02.01.2006 15:04")))
+	}
+	if autopilot.LastError != nil {
+		sb.WriteString("❌ " + html.EscapeString(*autopilot.LastError) + "\n")
+	}
+
+	return sb.String()
+}
+
+func CancelCommandAutopilot(channelID int64) tgbotapi.InlineKeyboardMarkup {
+	return CancelCommand(fmt.Sprintf("autopilot_%d", channelID))
+}
+
+// CancelCommand stops a text input and opens the screen of callbackData.
+func CancelCommand(callbackData string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Отмена выполнения", callbackData)))
+}
+
+const cronTimeLayout = "02.01.2006 15:04"
+
+const (
+	// CronListRuns is how many upcoming runs the list of jobs shows.
+	CronListRuns = 5
+	// CronJobRuns is how many upcoming runs the card of a job shows.
+	CronJobRuns = 3
+)
+
+func CronJobList(jobs []entity.CronJob, channelID int64) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(jobs)+3)
+	for _, job := range jobs {
+		mark := "▶️"
+		if job.Paused {
+			mark = "⏸"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %s — %s", mark, job.Kind.Title(), job.Schedule),
+				fmt.Sprintf("job_get_%d", job.ID))))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Добавить задачу", fmt.Sprintf("jobs_new_%d", channelID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", channelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func CronJobKinds(channelID int64) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(entity.CronJobKinds)+1)
+	for key, kind := range entity.CronJobKinds {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(kind.Title(), fmt.Sprintf("jobkind_%d_%d", channelID, key))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("jobs_list_%d", channelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func CronJobSetting(job *entity.CronJob) tgbotapi.InlineKeyboardMarkup {
+	pause := "⏸ Приостановить"
+	if job.Paused {
+		pause = "▶️ Возобновить"
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(pause, fmt.Sprintf("job_pause_%d", job.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Изменить расписание", fmt.Sprintf("job_schedule_%d", job.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Удалить задачу", fmt.Sprintf("job_delete_%d", job.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("jobs_list_%d", job.ChannelTgID))),
+	)
+}
+
+// CronJobListText lists the jobs of the channel and the runs coming next.
+func CronJobListText(timezone string, jobs []entity.CronJob, runs []entity.CronJobRun) string {
+	var sb strings.Builder
+	sb.WriteString("<b>Регулярные задачи</b> выполняются по расписанию cron\n")
+	sb.WriteString(fmt.Sprintf("Часовой пояс канала: %s, он меняется в настройках канала\n", html.EscapeString(timezone)))
+	if len(jobs) == 0 {
+		sb.WriteString("\nЗадач пока нет")
+		return sb.String()
+	}
+
+	sb.WriteString("\nБлижайшие запуски:\n")
+	if len(runs) == 0 {
+		sb.WriteString("нет, все задачи приостановлены\n")
+	}
+	for _, run := range runs {
+		sb.WriteString(fmt.Sprintf("%s — %s\n", run.At.Format(cronTimeLayout), run.Job.Kind.Title()))
+	}
+
+	return sb.String()
+}
+
+// CronJobText shows the job with its next runs and the result of the last one.
+func CronJobText(job *entity.CronJob, runs []entity.CronJobRun) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>%s</b>\n", job.Kind.Title()))
+	sb.WriteString(fmt.Sprintf("Расписание: <code>%s</code> (%s)\n", html.EscapeString(job.Schedule), html.EscapeString(job.Timezone)))
+	if job.Paused {
+		sb.WriteString("Статус: приостановлена\n")
+	} else {
+		sb.WriteString("Статус: активна\n")
+	}
+
+	if len(runs) > 0 {
+		sb.WriteString("\nСледующие запуски:\n")
+		for _, run := range runs {
+			sb.WriteString(run.At.Format(cronTimeLayout) + "\n")
+		}
+	}
+
+	if job.LastRunAt != nil {
+		location := entity.LoadLocation(job.Timezone)
+		mark := "✅"
+		if job.LastFailed {
+			mark = "❌"
+		}
+		sb.WriteString(fmt.Sprintf("\nПоследний запуск: %s\n", job.LastRunAt.In(location).Format(cronTimeLayout)))
+		if job.LastResult != nil {
+			sb.WriteString(fmt.Sprintf("%s %s\n", mark, html.EscapeString(*job.LastResult)))
+		}
+	}
+
+	return sb.String()
+}
+
+func ChannelSettings(settings *entity.ChannelSettings, timezone string) tgbotapi.InlineKeyboardMarkup {
+	shuffle := "Порядок ответов: как в вопросе"
+	if settings.ShuffleAnswers {
+		shuffle = "Порядок ответов: случайный"
+	}
+	points := "Показывать баллы: нет"
+	if settings.ShowPoints {
+		points = "Показывать баллы: да"
+	}
+	membership := "Только подписчики: нет"
+	if settings.RequireMembership {
+		membership = "Только подписчики: да"
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Кнопок в ряд: "+entity.ButtonsPerRowTitle(settings.ButtonsPerRow),
+				fmt.Sprintf("cset_row_%d", settings.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(shuffle, fmt.Sprintf("cset_shuffle_%d", settings.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(points, fmt.Sprintf("cset_points_%d", settings.ChannelTgID)),
+			tgbotapi.NewInlineKeyboardButtonData(membership, fmt.Sprintf("cset_member_%d", settings.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Смена ответа: "+entity.DurationTitle(settings.AnswerChange),
+				fmt.Sprintf("cset_change_%d", settings.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Срок ответа: "+entity.DurationTitle(settings.DefaultDeadline),
+				fmt.Sprintf("cset_deadline_%d", settings.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Текст после ответа", fmt.Sprintf("cset_feedback_%d", settings.ChannelTgID)),
+			tgbotapi.NewInlineKeyboardButtonData("Подпись постов", fmt.Sprintf("cset_sign_%d", settings.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Часовой пояс: "+timezone, fmt.Sprintf("cset_tz_%d", settings.ChannelTgID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", settings.ChannelTgID))),
+	)
+}
+
+// ChannelSettingsText shows how the channel publishes questions and takes
+// answers.
+func ChannelSettingsText(settings *entity.ChannelSettings, timezone string) string {
+	var sb strings.Builder
+	sb.WriteString("<b>Настройки канала</b>\n\n")
+	sb.WriteString("Кнопок ответов в ряд: " + entity.ButtonsPerRowTitle(settings.ButtonsPerRow) + "\n")
+	if settings.ShuffleAnswers {
+		sb.WriteString("Ответы перемешиваются при публикации\n")
+	}
+	if settings.RequireMembership {
+		sb.WriteString("Отвечать могут только подписчики канала\n")
+	}
+	sb.WriteString("Сменить ответ можно в течение: " + entity.DurationTitle(settings.AnswerChange) + "\n")
+	sb.WriteString("Срок ответа после публикации: " + entity.DurationTitle(settings.DefaultDeadline) + "\n")
+	sb.WriteString("\nПосле ответа: " + html.EscapeString(settings.Feedback(10)) + "\n")
+	if settings.Signature != nil {
+		sb.WriteString("Подпись постов: " + html.EscapeString(*settings.Signature) + "\n")
+	}
+	sb.WriteString("\nЧасовой пояс: " + html.EscapeString(timezone) + "\n")
+
+	return sb.String()
+}
+
+func CancelCommandChannelSettings(channelID int64) tgbotapi.InlineKeyboardMarkup {
+	return CancelCommand(fmt.Sprintf("chset_%d", channelID))
+}
+
+func SeasonList(seasons []entity.Season, channelID int64, location *time.Location) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(seasons)+2)
+	for _, season := range seasons {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Сезон %d, закрыт %s, участников: %d",
+				season.Number, season.ClosedAt.In(location).Format("02.01.2006"), season.Participants),
+				fmt.Sprintf("rseason_%d", season.ID))))
+	}
+	if len(seasons) > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Сравнить сезоны", fmt.Sprintf("season_compare_%d", channelID))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("downloading_rating_%d", channelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func SeasonClosed(channelID int64, seasonID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Скачать итоги сезона", fmt.Sprintf("rseason_%d", seasonID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", channelID))),
+	)
+}
+
+func Confirm(confirmData string, cancelData string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Да", confirmData),
+			tgbotapi.NewInlineKeyboardButtonData("Нет", cancelData)),
+	)
+}
+
+// WithUndo puts the undo button of a destructive action above the menu.
+func WithUndo(menu tgbotapi.InlineKeyboardMarkup, token string) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(menu.InlineKeyboard)+1)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Отменить", fmt.Sprintf("undo_action_%s", token))))
+	rows = append(rows, menu.InlineKeyboard...)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// WithSyncPost adds the button that updates the published posts of the question.
+func WithSyncPost(menu tgbotapi.InlineKeyboardMarkup, questionID int) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(menu.InlineKeyboard)+1)
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить в канале", fmt.Sprintf("sync_post_%d", questionID))))
+	rows = append(rows, menu.InlineKeyboard...)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func Back(callbackData string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", callbackData)))
+}
+
+func TrashList(questions []entity.Question, channelID int64, location *time.Location) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(questions)+1)
+	for _, question := range questions {
+		name := []rune(question.PlainText())
+		if len(name) > 20 {
+			name = name[:20]
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s - [%s]", string(name), question.DeletedAt.In(location).Format("02.01.2006")),
+				fmt.Sprintf("trash_get_%d", question.ID))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("channel_get_%d", channelID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func TrashQuestion(question *entity.Question) tgbotapi.InlineKeyboardMarkup {
+	points := "Не учитывать очки за вопрос"
+	if !question.CountPoints {
+		points = "Учитывать очки за вопрос"
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Восстановить", fmt.Sprintf("trash_restore_%d", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(points, fmt.Sprintf("trash_points_%d", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Удалить навсегда", fmt.Sprintf("trash_purge_%d", question.ID))),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("trash_list_%d", question.ChannelID))),
+	)
+}
+
+func QuestionHistory(versions []entity.QuestionVersion, questionID int, location *time.Location) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(versions)+1)
+	for _, version := range versions {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Версия %d - %s", version.Version, version.ChangedAt.In(location).Format("02.01.2006 15:04")),
+				fmt.Sprintf("qversion_%d_%d", questionID, version.Version))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("question_get_%d", questionID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func QuestionVersion(questionID int, version int, canRollback bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if canRollback {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Вернуть эту версию", fmt.Sprintf("qrollback_%d_%d", questionID, version))))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Вернуться назад", fmt.Sprintf("qhistory_%d", questionID))))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,220 @@
+package tg_bot_api
+
+import (
+	"encoding/json"
+	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
+	customErr "github.com/Enthreeka/tg-bot-quiz/pkg/bot_error"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+)
+
+// MediaFromMessage returns the file id and the media type of the attachment
+// of the message. ok is false when the message carries no supported media.
+func MediaFromMessage(msg *tgbotapi.Message) (fileID string, mediaType entity.MediaType, ok bool) {
+	if msg == nil {
+		return "", "", false
+	}
+
+	switch {
+	case len(msg.Photo) > 0:
+		return msg.Photo[len(msg.Photo)-1].FileID, entity.MediaPhoto, true
+	case msg.Animation != nil:
+		// animations also fill Document for compatibility, so check them first
+		return msg.Animation.FileID, entity.MediaAnimation, true
+	case msg.Video != nil:
+		return msg.Video.FileID, entity.MediaVideo, true
+	case msg.Audio != nil:
+		return msg.Audio.FileID, entity.MediaAudio, true
+	case msg.Voice != nil:
+		return msg.Voice.FileID, entity.MediaVoice, true
+	case msg.Document != nil:
+		return msg.Document.FileID, entity.MediaDocument, true
+	}
+
+	return "", "", false
+}
+
+// mediaField is the field of the media in the requests, the name of the send
+// method follows it: photo, sendPhoto.
+func mediaField(mediaType entity.MediaType) string {
+	switch mediaType {
+	case entity.MediaVideo:
+		return "video"
+	case entity.MediaAnimation:
+		return "animation"
+	case entity.MediaDocument:
+		return "document"
+	case entity.MediaAudio:
+		return "audio"
+	case entity.MediaVoice:
+		return "voice"
+	default:
+		return "photo"
+	}
+}
+
+// addEntities puts the entities to the request as Telegram sent them. The
+// configs of the library drop custom_emoji_id, so quiz requests are made by hand.
+func addEntities(params tgbotapi.Params, key string, entities []coverter.MessageEntity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	return params.AddInterface(key, entities)
+}
+
+// quizRequest returns the method and the params that post the quiz to the
+// chat, chatID or the username of a channel.
+func quizRequest(chatID int64, channelUsername string, quiz *entity.Quiz, layout *entity.PostLayout) (string, tgbotapi.Params, error) {
+	question := quiz.Question
+	text, entities, parseMode := quizText(&question, layout)
+
+	params := make(tgbotapi.Params)
+	if err := params.AddFirstValid("chat_id", chatID, channelUsername); err != nil {
+		return "", nil, err
+	}
+	params.AddNonEmpty("parse_mode", parseMode)
+	if buttonMarkup := buttonQualifier(quiz.Answer, layout); buttonMarkup != nil {
+		if err := params.AddInterface("reply_markup", buttonMarkup); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if question.FileID == nil {
+		params["text"] = text
+		params.AddBool("disable_web_page_preview", true)
+		return "sendMessage", params, addEntities(params, "entities", entities)
+	}
+
+	field := mediaField(question.MediaType)
+	params[field] = *question.FileID
+	params.AddNonEmpty("caption", text)
+	return "send" + strings.ToUpper(field[:1]) + field[1:], params, addEntities(params, "caption_entities", entities)
+}
+
+// sendQuiz makes a request of quizRequest and returns the sent message.
+func (t *TelegramMsg) sendQuiz(method string, params tgbotapi.Params) (tgbotapi.Message, error) {
+	var message tgbotapi.Message
+
+	resp, err := t.bot.MakeRequest(method, params)
+	if err != nil {
+		return message, err
+	}
+
+	err = json.Unmarshal(resp.Result, &message)
+	return message, err
+}
+
+// EditQuizPost brings a published post in line with the quiz: the text or the
+// caption, the media and the buttons. Telegram can't add media to a text post
+// or remove it from a media post, such posts get ErrPostNotEditable.
+func (t *TelegramMsg) EditQuizPost(post *entity.QuestionPost, quiz *entity.Quiz) error {
+	question := quiz.Question
+	text, entities, parseMode := quizText(&question, &post.Layout)
+	keyboard := quizKeyboard(quiz.Answer, &post.Layout)
+	base := tgbotapi.BaseEdit{ChatID: post.ChatID, MessageID: post.MessageID, ReplyMarkup: &keyboard}
+
+	var err error
+	switch {
+	case (post.FileID == nil) != (question.FileID == nil):
+		return customErr.ErrPostNotEditable
+	case question.FileID == nil:
+		err = t.editQuizText(base, "editMessageText", func(params tgbotapi.Params) error {
+			params["text"] = text
+			params.AddBool("disable_web_page_preview", true)
+			params.AddNonEmpty("parse_mode", parseMode)
+			return addEntities(params, "entities", entities)
+		})
+	case *post.FileID == *question.FileID:
+		err = t.editQuizText(base, "editMessageCaption", func(params tgbotapi.Params) error {
+			params.AddNonEmpty("caption", text)
+			params.AddNonEmpty("parse_mode", parseMode)
+			return addEntities(params, "caption_entities", entities)
+		})
+	default:
+		err = t.editQuizMedia(base, &question, text, entities, parseMode)
+	}
+
+	if err != nil && !isNotModified(err) {
+		t.log.Error("failed to edit post %d in %d: %v", post.MessageID, post.ChatID, err)
+		return err
+	}
+
+	return nil
+}
+
+// editQuizText edits the text or the caption of a post, fill adds the fields
+// of the method.
+func (t *TelegramMsg) editQuizText(base tgbotapi.BaseEdit, method string, fill func(params tgbotapi.Params) error) error {
+	params := make(tgbotapi.Params)
+	params.AddNonZero64("chat_id", base.ChatID)
+	params.AddNonZero("message_id", base.MessageID)
+	if err := params.AddInterface("reply_markup", base.ReplyMarkup); err != nil {
+		return err
+	}
+	if err := fill(params); err != nil {
+		return err
+	}
+
+	_, err := t.bot.MakeRequest(method, params)
+	return err
+}
+
+// editQuizMedia calls editMessageMedia directly: the library drops animations
+// from this request.
+func (t *TelegramMsg) editQuizMedia(base tgbotapi.BaseEdit, question *entity.Question, text string,
+	entities []coverter.MessageEntity, parseMode string) error {
+	// voice messages can't replace the media of a post
+	if question.MediaType == entity.MediaVoice {
+		return customErr.ErrPostNotEditable
+	}
+
+	media := map[string]interface{}{
+		"type":    mediaField(question.MediaType),
+		"media":   *question.FileID,
+		"caption": text,
+	}
+	if len(entities) > 0 {
+		media["caption_entities"] = entities
+	}
+	if parseMode != "" {
+		media["parse_mode"] = parseMode
+	}
+
+	params := make(tgbotapi.Params)
+	params.AddNonZero64("chat_id", base.ChatID)
+	params.AddNonZero("message_id", base.MessageID)
+	if err := params.AddInterface("media", media); err != nil {
+		return err
+	}
+	if err := params.AddInterface("reply_markup", base.ReplyMarkup); err != nil {
+		return err
+	}
+
+	_, err := t.bot.MakeRequest("editMessageMedia", params)
+	return err
+}
+
+// quizText returns the text of the question with the entities or the parse
+// mode to send it with. The signature of the layout goes after the text.
+func quizText(question *entity.Question, layout *entity.PostLayout) (string, []coverter.MessageEntity, string) {
+	var signature string
+	if layout != nil && layout.Signature != nil && *layout.Signature != "" {
+		signature = *layout.Signature
+	}
+
+	if question.TextFormat == entity.TextFormatMarkdownV2 {
+		text := question.QuestionName
+		if signature != "" {
+			text += "\n\n" + coverter.ConvertToMarkdownV2(signature, nil)
+		}
+		return text, nil, tgbotapi.ModeMarkdownV2
+	}
+
+	// the entities count from the start of the text, so the signature doesn't move them
+	text := question.QuestionName
+	if signature != "" {
+		text += "\n\n" + signature
+	}
+	return text, coverter.SendableEntities(question.QuestionEntities), ""
+}
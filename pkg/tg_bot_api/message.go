@@ -1,19 +1,32 @@
 package tg_bot_api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/Enthreeka/tg-bot-quiz/internal/entity"
 	"github.com/Enthreeka/tg-bot-quiz/pkg/logger"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 )
 
 type Message interface {
 	SendNewMessage(chatID int64, markup *tgbotapi.InlineKeyboardMarkup, text string) (int, error)
 	SendEditMessage(chatID int64, messageID int, markup *tgbotapi.InlineKeyboardMarkup, text string) (int, error)
-	SendDocument(chatID int64, fileName string, fileIDBytes *[]byte, text string) (int, error)
+	SendDocument(chatID int64, fileName string, file io.Reader, text string) (int, error)
 	SendMessageToChannel(username string, quiz *entity.Quiz) error
-	SendMessageToUser(chatID int64, quiz *entity.Quiz) (int, error)
+	SendMessageToUser(chatID int64, quiz *entity.Quiz, layout *entity.PostLayout) (int, error)
+	EditQuizButtons(post *entity.QuestionPost, answers []entity.Answer) error
+	EditQuizPost(post *entity.QuestionPost, quiz *entity.Quiz) error
+	GetBotChatMember(chatID int64) (tgbotapi.ChatMember, error)
+	IsChatMember(chatID int64, userID int64) (bool, error)
+	GetFile(fileID string) (tgbotapi.File, error)
+	DownloadFile(ctx context.Context, fileID string) ([]byte, error)
 }
 
 type TelegramMsg struct {
@@ -61,10 +74,103 @@ func (t *TelegramMsg) SendEditMessage(chatID int64, messageID int, markup *tgbot
 	return sendMsg.MessageID, nil
 }
 
-func (t *TelegramMsg) SendDocument(chatID int64, fileName string, fileIDBytes *[]byte, text string) (int, error) {
-	msg := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
-		Name:  fileName,
-		Bytes: *fileIDBytes,
+func (t *TelegramMsg) GetBotChatMember(chatID int64) (tgbotapi.ChatMember, error) {
+	return t.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: t.bot.Self.ID,
+		},
+	})
+}
+
+// IsChatMember reports whether the user is in the chat now.
+func (t *TelegramMsg) IsChatMember(chatID int64, userID int64) (bool, error) {
+	member, err := t.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+			ChatID: chatID,
+			UserID: userID,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	switch member.Status {
+	case "creator", "administrator", "member":
+		return true, nil
+	case "restricted":
+		return member.IsMember, nil
+	}
+	return false, nil
+}
+
+func (t *TelegramMsg) GetFile(fileID string) (tgbotapi.File, error) {
+	return t.bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
+}
+
+const (
+	// maxBankFileSize matches the Bot API limit for files a bot can download.
+	maxBankFileSize = 20 << 20
+	downloadTimeout = time.Minute
+)
+
+var downloadClient = &http.Client{Timeout: downloadTimeout}
+
+// DownloadFile returns the content of a file sent to the bot. The file url
+// contains the bot token, so it never gets into logs or returned errors.
+func (t *TelegramMsg) DownloadFile(ctx context.Context, fileID string) ([]byte, error) {
+	fileURL, err := t.bot.GetFileDirectURL(fileID)
+	if err != nil {
+		err = withoutURL(err)
+		t.log.Error("failed to get file url: %v", err)
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		err = withoutURL(err)
+		t.log.Error("failed to create download request: %v", err)
+		return nil, err
+	}
+
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		err = withoutURL(err)
+		t.log.Error("failed to download file: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBankFileSize+1))
+	if err != nil {
+		err = withoutURL(err)
+		t.log.Error("failed to read file: %v", err)
+		return nil, err
+	}
+	if len(data) > maxBankFileSize {
+		return nil, fmt.Errorf("download file: file is larger than %d MB", maxBankFileSize>>20)
+	}
+
+	return data, nil
+}
+
+// withoutURL drops the request url, which holds the bot token, from an http error.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+func (t *TelegramMsg) SendDocument(chatID int64, fileName string, file io.Reader, text string) (int, error) {
+	msg := tgbotapi.NewDocument(chatID, tgbotapi.FileReader{
+		Name:   fileName,
+		Reader: file,
 	})
 	msg.ParseMode = tgbotapi.ModeHTML
 	msg.Caption = text
@@ -79,85 +185,65 @@ func (t *TelegramMsg) SendDocument(chatID int64, fileName string, fileIDBytes *[
 }
 
 func (t *TelegramMsg) SendMessageToChannel(username string, quiz *entity.Quiz) error {
-	if quiz.Question.FileID != nil {
-		publicationPhoto := tgbotapi.NewInputMediaPhoto(tgbotapi.FileID(*quiz.Question.FileID))
-		msg := tgbotapi.NewPhotoToChannel(username, publicationPhoto.Media)
-		msg.ParseMode = tgbotapi.ModeMarkdownV2
-		buttonMarkup := buttonQualifier(quiz.Answer)
-		if buttonMarkup != nil {
-			msg.ReplyMarkup = &buttonMarkup
-		}
-		if quiz.Question.QuestionName != "" {
-			msg.Caption = quiz.Question.QuestionName
-		}
-
-		if _, err := t.bot.Send(msg); err != nil {
-			t.log.Error("failed to send message: %v", err)
-			return err
-		}
-		return nil
-	}
-
-	msg := tgbotapi.NewMessageToChannel(username, "")
-	msg.DisableWebPagePreview = true
-	msg.ParseMode = tgbotapi.ModeMarkdownV2
-	buttonMarkup := buttonQualifier(quiz.Answer)
-	if buttonMarkup != nil {
-		msg.ReplyMarkup = &buttonMarkup
-	}
-	if quiz.Question.QuestionName != "" {
-		msg.Text = quiz.Question.QuestionName
+	method, params, err := quizRequest(0, username, quiz, nil)
+	if err != nil {
+		return err
 	}
 
-	if _, err := t.bot.Send(msg); err != nil {
-		t.log.Error("failed to send message", err)
+	if _, err = t.sendQuiz(method, params); err != nil {
+		t.log.Error("failed to send message: %v", err)
 		return err
 	}
 
 	return nil
 }
 
-func (t *TelegramMsg) SendMessageToUser(chatID int64, quiz *entity.Quiz) (int, error) {
-	if quiz.Question.FileID != nil {
-		publicationPhotoPhoto := tgbotapi.NewInputMediaPhoto(tgbotapi.FileID(*quiz.Question.FileID))
-		msg := tgbotapi.NewPhoto(chatID, publicationPhotoPhoto.Media)
-		buttonMarkup := buttonQualifier(quiz.Answer)
-		if buttonMarkup != nil {
-			msg.ReplyMarkup = &buttonMarkup
-		}
-		if quiz.Question.QuestionName != "" {
-			msg.Caption = quiz.Question.QuestionName
-		}
-		msg.ParseMode = tgbotapi.ModeMarkdownV2
-		sendMsg, err := t.bot.Send(msg)
-		if err != nil {
-			t.log.Error("failed to send message: %v", err)
-			return 0, err
-		}
-		return sendMsg.MessageID, nil
-	}
-
-	msg := tgbotapi.NewMessage(chatID, "")
-	msg.DisableWebPagePreview = true
-	msg.ParseMode = tgbotapi.ModeMarkdownV2
-	buttonMarkup := buttonQualifier(quiz.Answer)
-	if buttonMarkup != nil {
-		msg.ReplyMarkup = &buttonMarkup
-	}
-	if quiz.Question.QuestionName != "" {
-		msg.Text = quiz.Question.QuestionName
+// SendMessageToUser sends the question with its answers laid out by layout,
+// nil is one answer per row in the order of the question.
+func (t *TelegramMsg) SendMessageToUser(chatID int64, quiz *entity.Quiz, layout *entity.PostLayout) (int, error) {
+	method, params, err := quizRequest(chatID, "", quiz, layout)
+	if err != nil {
+		return 0, err
 	}
 
-	sendMsg, err := t.bot.Send(msg)
+	sendMsg, err := t.sendQuiz(method, params)
 	if err != nil {
-		t.log.Error("failed to send message", err)
+		t.log.Error("failed to send message: %v", err)
 		return 0, err
 	}
 
 	return sendMsg.MessageID, nil
 }
 
-func buttonQualifier(answers []entity.Answer) *tgbotapi.InlineKeyboardMarkup {
+// EditQuizButtons replaces the answer buttons of a published question, the
+// layout of the post stays.
+func (t *TelegramMsg) EditQuizButtons(post *entity.QuestionPost, answers []entity.Answer) error {
+	if _, err := t.bot.Send(tgbotapi.NewEditMessageReplyMarkup(post.ChatID, post.MessageID, quizKeyboard(answers, &post.Layout))); err != nil {
+		if isNotModified(err) {
+			return nil
+		}
+		t.log.Error("failed to edit message buttons: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// quizKeyboard is buttonQualifier for edits, where no buttons is an empty
+// keyboard rather than no keyboard.
+func quizKeyboard(answers []entity.Answer, layout *entity.PostLayout) tgbotapi.InlineKeyboardMarkup {
+	if markup := buttonQualifier(answers, layout); markup != nil {
+		return *markup
+	}
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+}
+
+// isNotModified reports that an edit changed nothing, the post is already up to date.
+func isNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
+}
+
+func buttonQualifier(answers []entity.Answer, layout *entity.PostLayout) *tgbotapi.InlineKeyboardMarkup {
 	if len(answers) == 0 {
 		return nil
 	}
@@ -165,7 +251,8 @@ func buttonQualifier(answers []entity.Answer) *tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 	var row []tgbotapi.InlineKeyboardButton
 
-	buttonsPerRow := 1
+	answers = layout.Arrange(answers)
+	buttonsPerRow := layout.PerRow(answers)
 	for i, el := range answers {
 		btn := tgbotapi.NewInlineKeyboardButtonData(el.Answer, fmt.Sprintf("quiz_answer_%d", el.ID))
 
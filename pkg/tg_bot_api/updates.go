@@ -0,0 +1,116 @@
+package tg_bot_api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Enthreeka/tg-bot-quiz/pkg/tg_bot_api/coverter"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"time"
+)
+
+// Update is an update with the entities of its message as Telegram sent them.
+// The library drops custom_emoji_id and the entities of poll questions.
+type Update struct {
+	tgbotapi.Update
+	message *rawMessage
+}
+
+type rawMessage struct {
+	Entities        []coverter.MessageEntity `json:"entities"`
+	CaptionEntities []coverter.MessageEntity `json:"caption_entities"`
+	Poll            *struct {
+		QuestionEntities []coverter.MessageEntity `json:"question_entities"`
+	} `json:"poll"`
+}
+
+// GetUpdatesChan long polls the updates like the library does until ctx is
+// done, then the channel is closed. The library loop can't be used, it decodes
+// the updates into its own types and the raw entities are lost.
+func (t *TelegramMsg) GetUpdatesChan(ctx context.Context, config tgbotapi.UpdateConfig) <-chan Update {
+	ch := make(chan Update, t.bot.Buffer)
+
+	go func() {
+		defer close(ch)
+		for ctx.Err() == nil {
+			updates, err := t.getUpdates(config)
+			if err != nil {
+				t.log.Error("failed to get updates, retrying in 3 seconds: %v", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(3 * time.Second):
+				}
+				continue
+			}
+
+			for _, update := range updates {
+				if update.UpdateID < config.Offset {
+					continue
+				}
+				config.Offset = update.UpdateID + 1
+				select {
+				case ch <- update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (t *TelegramMsg) getUpdates(config tgbotapi.UpdateConfig) ([]Update, error) {
+	resp, err := t.bot.Request(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var raws []json.RawMessage
+	if err = json.Unmarshal(resp.Result, &raws); err != nil {
+		return nil, err
+	}
+
+	updates := make([]Update, 0, len(raws))
+	for _, raw := range raws {
+		var update struct {
+			Message *rawMessage `json:"message"`
+		}
+		if err = json.Unmarshal(raw, &update); err != nil {
+			return nil, err
+		}
+
+		result := Update{message: update.Message}
+		if err = json.Unmarshal(raw, &result.Update); err != nil {
+			return nil, err
+		}
+		updates = append(updates, result)
+	}
+
+	return updates, nil
+}
+
+// TextEntities returns the entities of the text or of the caption of the
+// message with custom emoji ids.
+func (u *Update) TextEntities() []coverter.MessageEntity {
+	msg := u.Message
+	switch {
+	case msg == nil:
+		return nil
+	case u.message != nil && msg.Caption != "":
+		return u.message.CaptionEntities
+	case u.message != nil:
+		return u.message.Entities
+	case msg.Caption != "":
+		return coverter.Entities(msg.CaptionEntities)
+	default:
+		return coverter.Entities(msg.Entities)
+	}
+}
+
+// PollEntities returns the entities of the question of the poll in the message.
+func (u *Update) PollEntities() []coverter.MessageEntity {
+	if u.message != nil && u.message.Poll != nil {
+		return u.message.Poll.QuestionEntities
+	}
+	return nil
+}